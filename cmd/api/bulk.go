@@ -0,0 +1,155 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/Torkel-Aannestad/OMDB-api/internal/database"
+	"github.com/Torkel-Aannestad/OMDB-api/internal/validator"
+)
+
+const ndjsonType = "application/x-ndjson"
+
+// bulkLine is a record of a bulk request body and the line it was read from.
+// For a JSON array the line is the position of the record in the array.
+type bulkLine struct {
+	line int
+	js   json.RawMessage
+}
+
+// readBulkMode reads the ?mode= value of a bulk request and reports whether
+// the records should be written best-effort. The default is all-or-nothing.
+func (app *application) readBulkMode(qs url.Values, v *validator.Validator) bool {
+	mode := app.readString(qs, "mode", "all-or-nothing")
+	v.Check(validator.PermittedValue(mode, "all-or-nothing", "best-effort"), "mode", "must be all-or-nothing or best-effort")
+	return mode == "best-effort"
+}
+
+// readBulk reads the records of a bulk request body, which is either a JSON
+// array or, with the application/x-ndjson content type, one JSON record per
+// line. Blank lines are skipped. The records are decoded later, one by one, so
+// that a bad record is reported on its line.
+func (app *application) readBulk(w http.ResponseWriter, r *http.Request) ([]bulkLine, error) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+
+	var lines []bulkLine
+	if mediaType == ndjsonType {
+		maxBytes := 1_048_576
+		r.Body = http.MaxBytesReader(w, r.Body, int64(maxBytes))
+
+		scanner := bufio.NewScanner(r.Body)
+		scanner.Buffer(make([]byte, 0, 64*1024), maxBytes)
+		for n := 1; scanner.Scan(); n++ {
+			js := bytes.TrimSpace(scanner.Bytes())
+			if len(js) == 0 {
+				continue
+			}
+			lines = append(lines, bulkLine{line: n, js: bytes.Clone(js)})
+		}
+
+		err := scanner.Err()
+		if err != nil {
+			var maxBytesError *http.MaxBytesError
+			if errors.As(err, &maxBytesError) {
+				return nil, fmt.Errorf("body must not be larger than %d bytes", maxBytesError.Limit)
+			}
+			return nil, err
+		}
+	} else {
+		var records []json.RawMessage
+		err := app.readJSON(w, r, &records)
+		if err != nil {
+			return nil, err
+		}
+		for i, js := range records {
+			lines = append(lines, bulkLine{line: i + 1, js: js})
+		}
+	}
+
+	if len(lines) == 0 {
+		return nil, errors.New("body must contain at least one record")
+	}
+	if len(lines) > database.MaxBulkRecords {
+		return nil, fmt.Errorf("body must not contain more than %d records", database.MaxBulkRecords)
+	}
+
+	return lines, nil
+}
+
+// decodeBulkRecord decodes one record of a bulk request into dst, with the
+// same rules as readJSON.
+func decodeBulkRecord(js json.RawMessage, dst any) error {
+	dec := json.NewDecoder(bytes.NewReader(js))
+	dec.DisallowUnknownFields()
+
+	err := dec.Decode(dst)
+	if err != nil {
+		var syntaxError *json.SyntaxError
+		var unmarshalTypeError *json.UnmarshalTypeError
+
+		switch {
+		case errors.As(err, &syntaxError):
+			return fmt.Errorf("contains badly-formed JSON (at character %d)", syntaxError.Offset)
+
+		case errors.Is(err, io.ErrUnexpectedEOF):
+			return errors.New("contains badly-formed JSON")
+
+		case errors.As(err, &unmarshalTypeError):
+			if unmarshalTypeError.Field != "" {
+				return fmt.Errorf("contains incorrect JSON type for field %q", unmarshalTypeError.Field)
+			}
+			return fmt.Errorf("contains incorrect JSON type (at character %d)", unmarshalTypeError.Offset)
+
+		case strings.HasPrefix(err.Error(), "json: unknown field "):
+			fieldName := strings.TrimPrefix(err.Error(), "json: unknown field ")
+			return fmt.Errorf("contains unknown key %s", fieldName)
+
+		default:
+			return err
+		}
+	}
+
+	if dec.More() {
+		return errors.New("must only contain a single JSON value")
+	}
+
+	return nil
+}
+
+// writeBulk writes the valid records of a bulk request with write and sends
+// the result. errs are the records that failed to decode or validate. In
+// all-or-nothing mode any error fails the request with 422 and nothing is
+// written.
+func (app *application) writeBulk(w http.ResponseWriter, r *http.Request, received int, errs []database.BulkError, bestEffort bool, write func() (*database.BulkResult, error)) {
+	if len(errs) > 0 && !bestEffort {
+		app.failedBulkValidationResponse(w, r, errs)
+		return
+	}
+
+	result, err := write()
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	result.Errors = append(errs, result.Errors...)
+	database.SortBulkErrors(result.Errors)
+	if len(result.Errors) > 0 && !bestEffort {
+		app.failedBulkValidationResponse(w, r, result.Errors)
+		return
+	}
+	result.Received = received
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"result": result}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// etag returns the entity tag of the full representation of a record, which
+// is its version.
+func etag(version int32) string {
+	return fmt.Sprintf(`"%d"`, version)
+}
+
+// variantETag returns the weak entity tag of a sparse or localized
+// representation of a record, built from the version and the inputs that make
+// the representation differ from the full one.
+func variantETag(version int32, inputs ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(inputs, "\x00")))
+	return fmt.Sprintf(`W/"%d-%x"`, version, sum[:8])
+}
+
+// matchETag reports whether the comma separated entity tags of an
+// If-None-Match header include tag. It uses the weak comparison, which ignores
+// the W/ prefix.
+func matchETag(header string, tag string) bool {
+	tag = strings.TrimPrefix(tag, "W/")
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// recordETag returns the entity tag of a record for the fields selected with
+// ?fields=, which is the strong tag of the version for the full record.
+func (app *application) recordETag(r *http.Request, version int32) string {
+	fields := app.contextGetFields(r)
+	if fields == nil {
+		return etag(version)
+	}
+	return variantETag(version, append([]string{"fields"}, fields...)...)
+}
+
+// notModified sets the ETag and Last-Modified headers of a record and answers
+// with 304 Not Modified when the copy the client has cached is current. It
+// reports whether the response was sent. If-None-Match takes precedence over
+// If-Modified-Since. A zero modifiedAt sends no Last-Modified header, for
+// representations the modification time of the record does not cover.
+func (app *application) notModified(w http.ResponseWriter, r *http.Request, tag string, modifiedAt time.Time) bool {
+	w.Header().Set("ETag", tag)
+	if !modifiedAt.IsZero() {
+		w.Header().Set("Last-Modified", modifiedAt.UTC().Format(http.TimeFormat))
+	}
+
+	current := false
+	if header := r.Header.Get("If-None-Match"); header != "" {
+		current = matchETag(header, tag)
+	} else if header := r.Header.Get("If-Modified-Since"); header != "" && !modifiedAt.IsZero() {
+		since, err := http.ParseTime(header)
+		current = err == nil && !modifiedAt.Truncate(time.Second).After(since)
+	}
+
+	if current {
+		w.WriteHeader(http.StatusNotModified)
+	}
+	return current
+}
+
+// ifMatch checks the If-Match header of a PATCH or DELETE against the version
+// of the record, and answers with 412 Precondition Failed when the client
+// changes a stale copy. It reports whether the request may go ahead. The
+// sparse and localized variants of a version are all built from the same
+// record, so the weak tag of a variant matches as well as the strong tag.
+func (app *application) ifMatch(w http.ResponseWriter, r *http.Request, version int32) bool {
+	header := r.Header.Get("If-Match")
+	if header == "" || matchVersion(header, version) {
+		return true
+	}
+
+	app.preconditionFailedResponse(w, r)
+	return false
+}
+
+// matchVersion reports whether the comma separated entity tags of an If-Match
+// header include a tag of version, either the strong tag or the weak tag of a
+// variant.
+func matchVersion(header string, version int32) bool {
+	want := strconv.FormatInt(int64(version), 10)
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return true
+		}
+
+		weak := strings.HasPrefix(candidate, "W/")
+		candidate = strings.TrimPrefix(candidate, "W/")
+		if len(candidate) < 2 || candidate[0] != '"' || candidate[len(candidate)-1] != '"' {
+			continue
+		}
+		candidate = candidate[1 : len(candidate)-1]
+
+		if weak {
+			var found bool
+			candidate, _, found = strings.Cut(candidate, "-")
+			if !found {
+				continue
+			}
+		}
+		if candidate == want {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,57 @@
+package main
+
+import "testing"
+
+func TestMatchVersion(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		want   bool
+	}{
+		{name: "strong tag", header: `"3"`, want: true},
+		{name: "weak variant tag", header: variantETag(3, "fields", "id"), want: true},
+		{name: "any", header: `*`, want: true},
+		{name: "list", header: `"2", W/"3-1f0c2a9e4b7d6a05"`, want: true},
+		{name: "stale strong tag", header: `"2"`},
+		{name: "stale variant tag", header: variantETag(2, "fields", "id")},
+		{name: "weak tag without variant", header: `W/"3"`},
+		{name: "unquoted", header: `3`},
+		{name: "strong tag with suffix", header: `"3-1f0c2a9e4b7d6a05"`},
+		{name: "empty quotes", header: `""`},
+		{name: "lone quote", header: `"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := matchVersion(tt.header, 3); got != tt.want {
+				t.Errorf("got %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchETag(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		tag    string
+		want   bool
+	}{
+		{name: "strong tag", header: `"3"`, tag: `"3"`, want: true},
+		{name: "weak header", header: `W/"3"`, tag: `"3"`, want: true},
+		{name: "weak tag", header: `"3-ab"`, tag: `W/"3-ab"`, want: true},
+		{name: "list", header: `"1", "3"`, tag: `"3"`, want: true},
+		{name: "any", header: `*`, tag: `"3"`, want: true},
+		{name: "other variant", header: `W/"3-ab"`, tag: `W/"3-cd"`},
+		{name: "full record for variant", header: `"3"`, tag: `W/"3-ab"`},
+		{name: "stale", header: `"2"`, tag: `"3"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := matchETag(tt.header, tt.tag); got != tt.want {
+				t.Errorf("got %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
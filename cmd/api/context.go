@@ -9,7 +9,10 @@ import (
 
 type contextKey string
 
-const userContextKey = contextKey("user")
+const (
+	userContextKey   = contextKey("user")
+	fieldsContextKey = contextKey("fields")
+)
 
 func (app *application) contextSetUser(r *http.Request, user *database.User) *http.Request {
 	ctx := context.WithValue(r.Context(), userContextKey, user)
@@ -23,3 +26,15 @@ func (app *application) contextGetUser(r *http.Request) *database.User {
 	}
 	return user
 }
+
+func (app *application) contextSetFields(r *http.Request, fields database.Fields) *http.Request {
+	ctx := context.WithValue(r.Context(), fieldsContextKey, fields)
+	return r.WithContext(ctx)
+}
+
+// contextGetFields returns the fields selected with ?fields=, or nil when the
+// client did not select any.
+func (app *application) contextGetFields(r *http.Request) database.Fields {
+	fields, _ := r.Context().Value(fieldsContextKey).(database.Fields)
+	return fields
+}
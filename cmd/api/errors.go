@@ -1,8 +1,12 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
+
+	"github.com/Torkel-Aannestad/OMDB-api/internal/database"
+	"github.com/Torkel-Aannestad/OMDB-api/internal/patch"
 )
 
 // var (
@@ -52,11 +56,43 @@ func (app *application) failedValidationResponse(w http.ResponseWriter, r *http.
 	app.errorResponse(w, r, http.StatusUnprocessableEntity, errors)
 }
 
+// failedBulkValidationResponse reports the errors of each record of a bulk
+// request by line.
+func (app *application) failedBulkValidationResponse(w http.ResponseWriter, r *http.Request, errs []database.BulkError) {
+	app.errorResponse(w, r, http.StatusUnprocessableEntity, errs)
+}
+
 func (app *application) editConflictResponse(w http.ResponseWriter, r *http.Request) {
 	message := "unable to update the record due to an edit conflict, please try again"
 	app.errorResponse(w, r, http.StatusConflict, message)
 }
 
+func (app *application) idempotencyKeyInProgressResponse(w http.ResponseWriter, r *http.Request) {
+	message := "a request with this Idempotency-Key is still being processed, please try again later"
+	app.errorResponse(w, r, http.StatusConflict, message)
+}
+
+func (app *application) idempotencyKeyReusedResponse(w http.ResponseWriter, r *http.Request) {
+	message := "this Idempotency-Key was already used for a different request"
+	app.errorResponse(w, r, http.StatusUnprocessableEntity, message)
+}
+
+// patchErrorResponse reports a merge patch or JSON patch that could not be
+// applied. A failed test operation means the record has changed, which is an
+// edit conflict.
+func (app *application) patchErrorResponse(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, patch.ErrTestFailed) {
+		app.editConflictResponse(w, r)
+		return
+	}
+	app.badRequestResponse(w, r, err)
+}
+
+func (app *application) preconditionFailedResponse(w http.ResponseWriter, r *http.Request) {
+	message := "the record has been modified since it was retrieved, please fetch it again"
+	app.errorResponse(w, r, http.StatusPreconditionFailed, message)
+}
+
 func (app *application) rateLimitExceededResponse(w http.ResponseWriter, r *http.Request) {
 	message := "rate limit exceeded"
 	app.errorResponse(w, r, http.StatusTooManyRequests, message)
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/Torkel-Aannestad/OMDB-api/internal/database"
+	"github.com/Torkel-Aannestad/OMDB-api/internal/validator"
+)
+
+// movieExpansions maps the values accepted by ?expand= on the movie endpoints
+// to the permission needed to read the expanded resource.
+var movieExpansions = map[string]string{
+	"credits":    "casts:read",
+	"categories": "category-items:read",
+	"keywords":   "category-items:read",
+	"trailers":   "trailers:read",
+	"links":      "movie-links:read",
+	"images":     "images:read",
+}
+
+// personExpansions maps the values accepted by ?expand= on the people
+// endpoints to the permission needed to read the expanded resource.
+var personExpansions = map[string]string{
+	"credits": "casts:read",
+	"links":   "people-links:read",
+	"images":  "images:read",
+}
+
+// movieResponse is a movie with the expanded resources. The expanded fields
+// are any so that a resource without rows is returned as an empty list, while
+// resources that are not expanded are left out.
+type movieResponse struct {
+	*database.Movie
+	Credits    any `json:"credits,omitempty"`
+	Categories any `json:"categories,omitempty"`
+	Keywords   any `json:"keywords,omitempty"`
+	Trailers   any `json:"trailers,omitempty"`
+	Links      any `json:"links,omitempty"`
+	Images     any `json:"images,omitempty"`
+}
+
+// personResponse is a person with the expanded resources, see movieResponse.
+type personResponse struct {
+	*database.Person
+	Credits any `json:"credits,omitempty"`
+	Links   any `json:"links,omitempty"`
+	Images  any `json:"images,omitempty"`
+}
+
+// readExpand reads the comma separated ?expand= values and checks them against
+// the expansions of the endpoint.
+func (app *application) readExpand(qs url.Values, expansions map[string]string, v *validator.Validator) []string {
+	expand := app.readCSV(qs, "expand", []string{})
+
+	permitted := make([]string, 0, len(expansions))
+	for expansion := range expansions {
+		permitted = append(permitted, expansion)
+	}
+	slices.Sort(permitted)
+
+	for i := range expand {
+		expand[i] = strings.TrimSpace(expand[i])
+		v.Check(validator.PermittedValue(expand[i], permitted...), "expand", "must be one of the following values: "+strings.Join(permitted, ", "))
+	}
+	v.Check(validator.Unique(expand), "expand", "must not contain duplicate values")
+
+	return expand
+}
+
+// expandPermitted reports whether the user has the read permission of every
+// expanded resource.
+func (app *application) expandPermitted(r *http.Request, expand []string, expansions map[string]string) (bool, error) {
+	if len(expand) == 0 {
+		return true, nil
+	}
+
+	user := app.contextGetUser(r)
+
+	permissions, err := app.models.Permissions.GetAllForUser(user.ID)
+	if err != nil {
+		return false, err
+	}
+
+	for _, expansion := range expand {
+		if !permissions.Include(expansions[expansion]) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// expanded returns the rows loaded for id, or an empty list when there are none.
+func expanded[T any](rows map[int64][]T, id int64) []T {
+	if values, found := rows[id]; found {
+		return values
+	}
+	return []T{}
+}
+
+// expandMovies loads the expanded resources of all the movies with one query
+// per resource.
+func (app *application) expandMovies(movies []*database.Movie, expand []string) ([]*movieResponse, error) {
+	responses := make([]*movieResponse, 0, len(movies))
+	movieIDs := make([]int64, 0, len(movies))
+	for _, movie := range movies {
+		responses = append(responses, &movieResponse{Movie: movie})
+		movieIDs = append(movieIDs, movie.ID)
+	}
+
+	if len(movies) == 0 {
+		return responses, nil
+	}
+
+	for _, expansion := range expand {
+		switch expansion {
+		case "credits":
+			credits, err := app.models.Casts.GetCreditsForMovies(movieIDs)
+			if err != nil {
+				return nil, err
+			}
+			for _, response := range responses {
+				response.Credits = expanded(credits, response.ID)
+			}
+		case "categories", "keywords":
+			tableName := "movie_categories"
+			if expansion == "keywords" {
+				tableName = "movie_keywords"
+			}
+			categories, err := app.models.CategoryItems.GetCategoriesForMovies(movieIDs, tableName)
+			if err != nil {
+				return nil, err
+			}
+			for _, response := range responses {
+				if expansion == "keywords" {
+					response.Keywords = expanded(categories, response.ID)
+				} else {
+					response.Categories = expanded(categories, response.ID)
+				}
+			}
+		case "trailers":
+			trailers, err := app.models.Trailer.GetForMovies(movieIDs)
+			if err != nil {
+				return nil, err
+			}
+			for _, response := range responses {
+				response.Trailers = expanded(trailers, response.ID)
+			}
+		case "links":
+			movieLinks, err := app.models.MovieLinks.GetForMovies(movieIDs)
+			if err != nil {
+				return nil, err
+			}
+			for _, response := range responses {
+				response.Links = expanded(movieLinks, response.ID)
+			}
+		case "images":
+			images, err := app.models.Images.GetImagesForObjects(movieIDs, "Movie")
+			if err != nil {
+				return nil, err
+			}
+			for _, response := range responses {
+				response.Images = expanded(images, response.ID)
+			}
+		}
+	}
+
+	return responses, nil
+}
+
+// expandPeople loads the expanded resources of all the people with one query
+// per resource.
+func (app *application) expandPeople(people []*database.Person, expand []string) ([]*personResponse, error) {
+	responses := make([]*personResponse, 0, len(people))
+	personIDs := make([]int64, 0, len(people))
+	for _, person := range people {
+		responses = append(responses, &personResponse{Person: person})
+		personIDs = append(personIDs, person.ID)
+	}
+
+	if len(people) == 0 {
+		return responses, nil
+	}
+
+	for _, expansion := range expand {
+		switch expansion {
+		case "credits":
+			filmographies, err := app.models.Casts.GetFilmographyForPeople(personIDs)
+			if err != nil {
+				return nil, err
+			}
+			for _, response := range responses {
+				response.Credits = expanded(filmographies, response.ID)
+			}
+		case "links":
+			peopleLinks, err := app.models.PeopleLinks.GetForPeople(personIDs)
+			if err != nil {
+				return nil, err
+			}
+			for _, response := range responses {
+				response.Links = expanded(peopleLinks, response.ID)
+			}
+		case "images":
+			images, err := app.models.Images.GetImagesForObjects(personIDs, "Person")
+			if err != nil {
+				return nil, err
+			}
+			for _, response := range responses {
+				response.Images = expanded(images, response.ID)
+			}
+		}
+	}
+
+	return responses, nil
+}
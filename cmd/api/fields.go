@@ -0,0 +1,212 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/Torkel-Aannestad/OMDB-api/internal/validator"
+)
+
+// sparseFields reads the comma separated ?fields= values, checks them against
+// the JSON fields of resource and trims the object, or list of objects, under
+// key in the response to the selected fields. Expanded resources are kept. The
+// fields are put in the request context so that the handler can leave the
+// other columns out of the query.
+func (app *application) sparseFields(key string, resource any, next http.HandlerFunc) http.HandlerFunc {
+	permitted := jsonFields(reflect.TypeOf(resource))
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		qs := r.URL.Query()
+		if !qs.Has("fields") {
+			next(w, r)
+			return
+		}
+
+		v := validator.New()
+		fields := app.readCSV(qs, "fields", []string{})
+		for i := range fields {
+			fields[i] = strings.TrimSpace(fields[i])
+			v.Check(validator.PermittedValue(fields[i], permitted...), "fields", "must be one of the following values: "+strings.Join(permitted, ", "))
+		}
+		v.Check(len(fields) > 0, "fields", "must contain at least one field")
+		v.Check(validator.Unique(fields), "fields", "must not contain duplicate values")
+		if !v.Valid() {
+			app.failedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		keep := slices.Clone(fields)
+		for _, expansion := range app.readCSV(qs, "expand", []string{}) {
+			keep = append(keep, strings.TrimSpace(expansion))
+		}
+
+		bw := &bufferedResponseWriter{ResponseWriter: w, status: http.StatusOK}
+		next(bw, app.contextSetFields(r, fields))
+
+		body := bw.body.Bytes()
+		if bw.status == http.StatusOK && bw.body.Len() > 0 {
+			var err error
+			body, err = trimEnvelope(body, key, keep)
+			if err != nil {
+				app.serverErrorResponse(w, r, err)
+				return
+			}
+		}
+
+		w.WriteHeader(bw.status)
+		w.Write(body)
+	}
+}
+
+// noSparseFields rejects the fields query parameter on endpoints returning
+// nested structures, which have no single resource to select the fields of.
+func (app *application) noSparseFields(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Has("fields") {
+			v := validator.New()
+			v.AddError("fields", "is not supported by this endpoint")
+			app.failedValidationResponse(w, r, v.Errors)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// bufferedResponseWriter holds back the response of a handler so that it can
+// be rewritten before it is sent.
+type bufferedResponseWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (bw *bufferedResponseWriter) WriteHeader(status int) {
+	bw.status = status
+}
+
+func (bw *bufferedResponseWriter) Write(b []byte) (int, error) {
+	return bw.body.Write(b)
+}
+
+// jsonFields returns the names of the JSON fields of a struct type, including
+// the fields of embedded structs.
+func jsonFields(t reflect.Type) []string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	var fields []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		switch {
+		case name == "-":
+			continue
+		case name == "" && field.Anonymous:
+			fields = append(fields, jsonFields(field.Type)...)
+		case name == "":
+			fields = append(fields, field.Name)
+		default:
+			fields = append(fields, name)
+		}
+	}
+	return fields
+}
+
+// trimEnvelope trims the value under key in a JSON envelope to the fields.
+func trimEnvelope(js []byte, key string, fields []string) ([]byte, error) {
+	var env map[string]json.RawMessage
+	err := json.Unmarshal(js, &env)
+	if err != nil {
+		return nil, err
+	}
+
+	value, ok := env[key]
+	if !ok {
+		return js, nil
+	}
+
+	env[key], err = trimFields(value, fields)
+	if err != nil {
+		return nil, err
+	}
+
+	js, err = json.Marshal(env)
+	if err != nil {
+		return nil, err
+	}
+	return append(js, '\n'), nil
+}
+
+// trimFields returns the JSON object, or each object of the JSON array, with
+// only the fields. The fields keep their order.
+func trimFields(js json.RawMessage, fields []string) (json.RawMessage, error) {
+	js = bytes.TrimSpace(js)
+	if len(js) == 0 {
+		return js, nil
+	}
+
+	switch js[0] {
+	case '[':
+		var items []json.RawMessage
+		err := json.Unmarshal(js, &items)
+		if err != nil {
+			return nil, err
+		}
+		for i := range items {
+			items[i], err = trimFields(items[i], fields)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return json.Marshal(items)
+	case '{':
+		dec := json.NewDecoder(bytes.NewReader(js))
+		_, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+
+		var buf bytes.Buffer
+		buf.WriteByte('{')
+		for dec.More() {
+			token, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			name := token.(string)
+
+			var value json.RawMessage
+			err = dec.Decode(&value)
+			if err != nil {
+				return nil, err
+			}
+
+			if !slices.Contains(fields, name) {
+				continue
+			}
+			if buf.Len() > 1 {
+				buf.WriteByte(',')
+			}
+			nameJS, err := json.Marshal(name)
+			if err != nil {
+				return nil, err
+			}
+			buf.Write(nameJS)
+			buf.WriteByte(':')
+			buf.Write(value)
+		}
+		buf.WriteByte('}')
+		return buf.Bytes(), nil
+	default:
+		return js, nil
+	}
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestJSONFields(t *testing.T) {
+	type Base struct {
+		ID      int64 `json:"id"`
+		Version int32 `json:"-"`
+	}
+	type Named struct {
+		Name string `json:"name"`
+	}
+
+	tests := []struct {
+		name     string
+		resource any
+		want     []string
+	}{
+		{
+			name: "tags",
+			resource: struct {
+				ID    int64  `json:"id"`
+				Title string `json:"title,omitempty"`
+			}{},
+			want: []string{"id", "title"},
+		},
+		{
+			name: "untagged field",
+			resource: struct {
+				ID    int64 `json:"id"`
+				Title string
+			}{},
+			want: []string{"id", "Title"},
+		},
+		{
+			name: "option without name",
+			resource: struct {
+				Title string `json:",omitempty"`
+			}{},
+			want: []string{"Title"},
+		},
+		{
+			name: "skipped and unexported fields",
+			resource: struct {
+				ID       int64  `json:"id"`
+				Password string `json:"-"`
+				internal string
+			}{},
+			want: []string{"id"},
+		},
+		{
+			name: "embedded structs",
+			resource: struct {
+				Base
+				*Named
+				Title string `json:"title"`
+			}{},
+			want: []string{"id", "name", "title"},
+		},
+		{
+			name: "tagged embedded struct",
+			resource: struct {
+				Base `json:"base"`
+			}{},
+			want: []string{"base"},
+		},
+		{
+			name: "pointer",
+			resource: &struct {
+				CreatedAt time.Time `json:"created_at"`
+			}{},
+			want: []string{"created_at"},
+		},
+		{name: "no fields", resource: struct{}{}, want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := jsonFields(reflect.TypeOf(tt.resource))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTrimFields(t *testing.T) {
+	tests := []struct {
+		name    string
+		js      string
+		fields  []string
+		want    string
+		wantErr bool
+	}{
+		{name: "object", js: `{"id":1,"title":"Alien","runtime":117}`, fields: []string{"id", "runtime"}, want: `{"id":1,"runtime":117}`},
+		{name: "keeps the order of the object", js: `{"id":1,"title":"Alien"}`, fields: []string{"title", "id"}, want: `{"id":1,"title":"Alien"}`},
+		{name: "nested values are kept whole", js: `{"id":1,"cast":[{"id":2,"name":"x"}]}`, fields: []string{"cast"}, want: `{"cast":[{"id":2,"name":"x"}]}`},
+		{name: "no matching fields", js: `{"id":1}`, fields: []string{"title"}, want: `{}`},
+		{name: "no fields", js: `{"id":1}`, fields: nil, want: `{}`},
+		{name: "empty object", js: `{}`, fields: []string{"id"}, want: `{}`},
+		{name: "whitespace", js: " {\n \"id\" : 1 ,\n \"title\": \"Alien\"\n} ", fields: []string{"id"}, want: `{"id":1}`},
+		{name: "escaped name", js: `{"ti\u0074le":"Alien"}`, fields: []string{"title"}, want: `{"title":"Alien"}`},
+		{name: "array", js: `[{"id":1,"title":"a"},{"id":2,"title":"b"}]`, fields: []string{"title"}, want: `[{"title":"a"},{"title":"b"}]`},
+		{name: "empty array", js: `[]`, fields: []string{"id"}, want: `[]`},
+		{name: "array of values", js: `[1,"a",null]`, fields: []string{"id"}, want: `[1,"a",null]`},
+		{name: "null", js: `null`, fields: []string{"id"}, want: `null`},
+		{name: "empty", js: ``, fields: []string{"id"}, want: ``},
+		{name: "malformed object", js: `{"id":1,`, fields: []string{"id"}, wantErr: true},
+		{name: "malformed array", js: `[{"id":1}`, fields: []string{"id"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := trimFields([]byte(tt.js), tt.fields)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("got %s, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTrimEnvelope(t *testing.T) {
+	tests := []struct {
+		name    string
+		js      string
+		key     string
+		fields  []string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:   "object",
+			js:     `{"movie":{"id":1,"name":"Alien","runtime":117}}` + "\n",
+			key:    "movie",
+			fields: []string{"name"},
+			want:   `{"movie":{"name":"Alien"}}` + "\n",
+		},
+		{
+			name:   "list with metadata",
+			js:     `{"metadata":{"page_size":2},"movies":[{"id":1,"name":"a"},{"id":2,"name":"b"}]}`,
+			key:    "movies",
+			fields: []string{"id"},
+			want:   `{"metadata":{"page_size":2},"movies":[{"id":1},{"id":2}]}` + "\n",
+		},
+		{
+			name:   "empty list",
+			js:     `{"metadata":{},"movies":[]}`,
+			key:    "movies",
+			fields: []string{"id"},
+			want:   `{"metadata":{},"movies":[]}` + "\n",
+		},
+		{
+			name:   "null",
+			js:     `{"movie":null}`,
+			key:    "movie",
+			fields: []string{"id"},
+			want:   `{"movie":null}` + "\n",
+		},
+		{
+			name:   "missing key",
+			js:     `{"error":"the requested resource could not be found"}`,
+			key:    "movie",
+			fields: []string{"id"},
+			want:   `{"error":"the requested resource could not be found"}`,
+		},
+		{name: "not an object", js: `[{"id":1}]`, key: "movie", fields: []string{"id"}, wantErr: true},
+		{name: "malformed", js: `{"movie":`, key: "movie", fields: []string{"id"}, wantErr: true},
+		{name: "empty", js: ``, key: "movie", fields: []string{"id"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := trimEnvelope([]byte(tt.js), tt.key, tt.fields)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("got %s, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
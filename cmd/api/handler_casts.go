@@ -63,6 +63,73 @@ func (app *application) createCastHandler(w http.ResponseWriter, r *http.Request
 	}
 
 }
+
+func (app *application) bulkCastsHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+	bestEffort := app.readBulkMode(r.URL.Query(), v)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	lines, err := app.readBulk(w, r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	errs := []database.BulkError{}
+	records := []database.BulkRecord[database.Cast]{}
+	ids := make(map[int64]bool)
+
+	for _, line := range lines {
+		var input struct {
+			ID       int64   `json:"id"`
+			MovieID  int64   `json:"movie_id"`
+			PersonID int64   `json:"person_id"`
+			JobID    int64   `json:"job_id"`
+			Role     *string `json:"role"`
+			Position int32   `json:"position"`
+		}
+
+		err := decodeBulkRecord(line.js, &input)
+		if err != nil {
+			errs = append(errs, database.BulkError{Line: line.line, Errors: map[string]string{"record": err.Error()}})
+			continue
+		}
+
+		cast := database.Cast{
+			ID:       input.ID,
+			MovieID:  input.MovieID,
+			PersonID: input.PersonID,
+			JobID:    input.JobID,
+			Position: input.Position,
+		}
+
+		v := validator.New()
+		if input.Role != nil {
+			cast.Role = *input.Role
+		} else {
+			v.AddError("role", "must be provided")
+		}
+		v.Check(cast.ID >= 0, "id", "must be a positive number")
+		v.Check(cast.ID == 0 || !ids[cast.ID], "id", "must not be repeated in the request")
+		ids[cast.ID] = true
+
+		database.ValidateCast(v, &cast)
+		if !v.Valid() {
+			errs = append(errs, database.BulkError{Line: line.line, Errors: v.Errors})
+			continue
+		}
+
+		records = append(records, database.BulkRecord[database.Cast]{Line: line.line, Record: &cast})
+	}
+
+	app.writeBulk(w, r, len(lines), errs, bestEffort, func() (*database.BulkResult, error) {
+		return app.models.Casts.Bulk(records, bestEffort)
+	})
+}
+
 func (app *application) getCastsByMovieIdHandler(w http.ResponseWriter, r *http.Request) {
 	id, err := app.readIDParam(r)
 	if err != nil {
@@ -88,6 +155,80 @@ func (app *application) getCastsByMovieIdHandler(w http.ResponseWriter, r *http.
 	}
 }
 
+func (app *application) getMovieCreditsHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	departments, err := app.models.Casts.GetCredits(id)
+	if err != nil {
+		if errors.Is(err, database.ErrRecordNotFound) {
+			app.notFoundResponse(w, r)
+
+		} else {
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"movie_id": id, "departments": departments}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
+
+func (app *application) getPersonFilmographyHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var input struct {
+		Kind string
+		database.Filters
+	}
+
+	qs := r.URL.Query()
+
+	v := validator.New()
+
+	input.Kind = app.readString(qs, "kind", "")
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+	input.Filters.Sort = app.readString(qs, "sort", "-date")
+	input.Filters.SortSafelist = []string{"date", "vote_average", "-date", "-vote_average"}
+
+	if input.Kind != "" {
+		database.ValidateKind(v, &input.Kind)
+	}
+	database.ValidateFilters(v, input.Filters)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	jobs, metadata, err := app.models.Casts.GetFilmography(id, input.Kind, input.Filters)
+	if err != nil {
+		if errors.Is(err, database.ErrRecordNotFound) {
+			app.notFoundResponse(w, r)
+
+		} else {
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"person_id": id, "jobs": jobs, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
+
 func (app *application) getCastsByPersonIdHandler(w http.ResponseWriter, r *http.Request) {
 	id, err := app.readIDParam(r)
 	if err != nil {
@@ -129,10 +270,13 @@ func (app *application) updateCastHandler(w http.ResponseWriter, r *http.Request
 		Version  int32   `json:"version"`
 	}
 
-	err = app.readJSON(w, r, &input)
-	if err != nil {
-		app.badRequestResponse(w, r, err)
-		return
+	patchType := patchMediaType(r)
+	if patchType == "" {
+		err = app.readJSON(w, r, &input)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
 	}
 
 	cast, err := app.models.Casts.Get(id)
@@ -146,6 +290,18 @@ func (app *application) updateCastHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	if !app.ifMatch(w, r, cast.Version) {
+		return
+	}
+
+	if patchType != "" {
+		err = app.readPatch(w, r, patchType, cast, "movie_id", "person_id", "job_id", "role", "position")
+		if err != nil {
+			app.patchErrorResponse(w, r, err)
+			return
+		}
+	}
+
 	if input.MovieID != nil {
 		cast.MovieID = *input.MovieID
 	}
@@ -179,7 +335,10 @@ func (app *application) updateCastHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	app.writeJSON(w, http.StatusOK, envelope{"cast": cast}, nil)
+	headers := make(http.Header)
+	headers.Set("ETag", etag(cast.Version))
+
+	app.writeJSON(w, http.StatusOK, envelope{"cast": cast}, headers)
 
 }
 
@@ -190,6 +349,22 @@ func (app *application) deleteCastHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	if r.Header.Get("If-Match") != "" {
+		cast, err := app.models.Casts.Get(id)
+		if err != nil {
+			if errors.Is(err, database.ErrRecordNotFound) {
+				app.notFoundResponse(w, r)
+			} else {
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		if !app.ifMatch(w, r, cast.Version) {
+			return
+		}
+	}
+
 	err = app.models.Casts.Delete(id)
 	if err != nil {
 		if errors.Is(err, database.ErrRecordNotFound) {
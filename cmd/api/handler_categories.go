@@ -1,6 +1,7 @@
 package main
 
 import (
+	"database/sql"
 	"errors"
 	"fmt"
 
@@ -75,6 +76,10 @@ func (app *application) getCategoryHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	if app.notModified(w, r, app.recordETag(r, category.Version), category.ModifiedAt) {
+		return
+	}
+
 	err = app.writeJSON(w, http.StatusOK, envelope{"category": category}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
@@ -89,16 +94,19 @@ func (app *application) updateCategoryHandler(w http.ResponseWriter, r *http.Req
 		return
 	}
 
+	// The parent is changed with POST /v1/categories/:id/move, which checks
+	// for cycles and moves the subtree to the new root.
 	var input struct {
-		Name     *string             `json:"name"`
-		ParentID *database.NullInt64 `json:"parent_id"`
-		RootID   *database.NullInt64 `json:"root_id"`
+		Name *string `json:"name"`
 	}
 
-	err = app.readJSON(w, r, &input)
-	if err != nil {
-		app.badRequestResponse(w, r, err)
-		return
+	patchType := patchMediaType(r)
+	if patchType == "" {
+		err = app.readJSON(w, r, &input)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
 	}
 
 	category, err := app.models.Categories.Get(id)
@@ -112,14 +120,20 @@ func (app *application) updateCategoryHandler(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	if input.Name != nil {
-		category.Name = *input.Name
+	if !app.ifMatch(w, r, category.Version) {
+		return
 	}
-	if input.ParentID != nil {
-		category.ParentID = *input.ParentID
+
+	if patchType != "" {
+		err = app.readPatch(w, r, patchType, category, "name")
+		if err != nil {
+			app.patchErrorResponse(w, r, err)
+			return
+		}
 	}
-	if input.RootID != nil {
-		category.RootID = *input.RootID
+
+	if input.Name != nil {
+		category.Name = *input.Name
 	}
 
 	v := validator.New()
@@ -139,7 +153,10 @@ func (app *application) updateCategoryHandler(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	app.writeJSON(w, http.StatusOK, envelope{"category": category}, nil)
+	headers := make(http.Header)
+	headers.Set("ETag", app.recordETag(r, category.Version))
+
+	app.writeJSON(w, http.StatusOK, envelope{"category": category}, headers)
 
 }
 
@@ -150,6 +167,22 @@ func (app *application) deleteCategoryHandler(w http.ResponseWriter, r *http.Req
 		return
 	}
 
+	if r.Header.Get("If-Match") != "" {
+		category, err := app.models.Categories.Get(id)
+		if err != nil {
+			if errors.Is(err, database.ErrRecordNotFound) {
+				app.notFoundResponse(w, r)
+			} else {
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		if !app.ifMatch(w, r, category.Version) {
+			return
+		}
+	}
+
 	err = app.models.Categories.Delete(id)
 	if err != nil {
 		if errors.Is(err, database.ErrRecordNotFound) {
@@ -164,3 +197,278 @@ func (app *application) deleteCategoryHandler(w http.ResponseWriter, r *http.Req
 		app.serverErrorResponse(w, r, err)
 	}
 }
+
+func (app *application) listCategoriesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Has("ids") {
+		app.getCategoriesByIDsHandler(w, r)
+		return
+	}
+
+	var input struct {
+		Name     string
+		ParentID database.NullInt64
+		database.Filters
+	}
+
+	qs := r.URL.Query()
+
+	v := validator.New()
+
+	input.Name = app.readString(qs, "name", "")
+	if qs.Has("parent_id") {
+		input.ParentID.Int64 = int64(app.readInt(qs, "parent_id", 0, v))
+		input.ParentID.Valid = true
+	}
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+	input.Filters.Sort = app.readString(qs, "sort", "name")
+	input.Filters.SortSafelist = []string{"id", "name", "-id", "-name"}
+
+	database.ValidateFilters(v, input.Filters)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	categories, metadata, err := app.models.Categories.GetAll(input.Name, input.ParentID, input.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"categories": categories, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// getCategoriesByIDsHandler serves GET /v1/categories?ids=, see getMoviesByIDsHandler.
+func (app *application) getCategoriesByIDsHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+	ids := app.readIDs(r.URL.Query(), "ids", v)
+	if v.Valid() {
+		database.ValidateBatchIDs(v, ids)
+	}
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	categories, missing, err := app.models.Categories.GetByIDs(ids)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"categories": categories, "missing_ids": missing}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) getCategoryChildrenHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var input struct {
+		database.Filters
+	}
+
+	qs := r.URL.Query()
+
+	v := validator.New()
+
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+	input.Filters.Sort = app.readString(qs, "sort", "name")
+	input.Filters.SortSafelist = []string{"id", "name", "-id", "-name"}
+
+	database.ValidateFilters(v, input.Filters)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	_, err = app.models.Categories.Get(id)
+	if err != nil {
+		if errors.Is(err, database.ErrRecordNotFound) {
+			app.notFoundResponse(w, r)
+
+		} else {
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	parentID := database.NullInt64{NullInt64: sql.NullInt64{Int64: id, Valid: true}}
+
+	categories, metadata, err := app.models.Categories.GetAll("", parentID, input.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"categories": categories, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) getCategoryAncestorsHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	categories, err := app.models.Categories.GetAncestors(id)
+	if err != nil {
+		if errors.Is(err, database.ErrRecordNotFound) {
+			app.notFoundResponse(w, r)
+
+		} else {
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"categories": categories}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) getCategoryDescendantsHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	qs := r.URL.Query()
+
+	v := validator.New()
+
+	depth := app.readInt(qs, "depth", 3, v)
+	v.Check(depth > 0, "depth", "must be greater than zero")
+	v.Check(depth <= 10, "depth", "must be a maximum of 10")
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	category, err := app.models.Categories.GetDescendants(id, depth)
+	if err != nil {
+		if errors.Is(err, database.ErrRecordNotFound) {
+			app.notFoundResponse(w, r)
+
+		} else {
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"category": category}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) moveCategoryHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var input struct {
+		ParentID *int64 `json:"parent_id"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	// a category without parent_id is moved to the top as a root category
+	var parentID database.NullInt64
+	if input.ParentID != nil {
+		parentID.Int64 = *input.ParentID
+		parentID.Valid = true
+	}
+
+	v := validator.New()
+
+	category, err := app.models.Categories.Move(id, parentID)
+	if err != nil {
+		switch {
+		case errors.Is(err, database.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		case errors.Is(err, database.ErrCategoryCycle):
+			v.AddError("parent_id", "must not be the category or one of its descendants")
+			app.failedValidationResponse(w, r, v.Errors)
+		case errors.Is(err, database.ErrCategoryParentNotFound):
+			v.AddError("parent_id", "category does not exist")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"category": category}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) mergeCategoryHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var input struct {
+		TargetID int64 `json:"target_id"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	v.Check(input.TargetID > 0, "target_id", "must be provided")
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	merge, err := app.models.Categories.Merge(id, input.TargetID)
+	if err != nil {
+		switch {
+		case errors.Is(err, database.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		case errors.Is(err, database.ErrCategoryCycle):
+			v.AddError("target_id", "must not be the category or one of its descendants")
+			app.failedValidationResponse(w, r, v.Errors)
+		case errors.Is(err, database.ErrCategoryParentNotFound):
+			v.AddError("target_id", "category does not exist")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"merge": merge}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"net/http"
 
 	"github.com/Torkel-Aannestad/OMDB-api/internal/database"
+	"github.com/Torkel-Aannestad/OMDB-api/internal/validator"
 )
 
 func (app *application) createMovieKeywordsHandler(w http.ResponseWriter, r *http.Request) {
@@ -67,6 +68,63 @@ func (app *application) createMovieCategoriesHandler(w http.ResponseWriter, r *h
 	}
 }
 
+func (app *application) bulkMovieKeywordsHandler(w http.ResponseWriter, r *http.Request) {
+	app.bulkCategoryItems(w, r, "movie_keywords")
+}
+
+func (app *application) bulkMovieCategoriesHandler(w http.ResponseWriter, r *http.Request) {
+	app.bulkCategoryItems(w, r, "movie_categories")
+}
+
+func (app *application) bulkCategoryItems(w http.ResponseWriter, r *http.Request, tableName string) {
+	v := validator.New()
+	bestEffort := app.readBulkMode(r.URL.Query(), v)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	lines, err := app.readBulk(w, r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	errs := []database.BulkError{}
+	records := []database.BulkRecord[database.CategoryItem]{}
+
+	for _, line := range lines {
+		var input struct {
+			MovieId    int64 `json:"movie_id"`
+			CategoryId int64 `json:"category_id"`
+		}
+
+		err := decodeBulkRecord(line.js, &input)
+		if err != nil {
+			errs = append(errs, database.BulkError{Line: line.line, Errors: map[string]string{"record": err.Error()}})
+			continue
+		}
+
+		categoryItem := database.CategoryItem{
+			MovieId:    input.MovieId,
+			CategoryId: input.CategoryId,
+		}
+
+		v := validator.New()
+		database.ValidateCategoryItem(v, &categoryItem)
+		if !v.Valid() {
+			errs = append(errs, database.BulkError{Line: line.line, Errors: v.Errors})
+			continue
+		}
+
+		records = append(records, database.BulkRecord[database.CategoryItem]{Line: line.line, Record: &categoryItem})
+	}
+
+	app.writeBulk(w, r, len(lines), errs, bestEffort, func() (*database.BulkResult, error) {
+		return app.models.CategoryItems.Bulk(records, tableName, bestEffort)
+	})
+}
+
 func (app *application) getMovieKeywordsHandler(w http.ResponseWriter, r *http.Request) {
 	movieId, err := app.readIDParam(r)
 	if err != nil {
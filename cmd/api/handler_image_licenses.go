@@ -0,0 +1,185 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"net/http"
+
+	"github.com/Torkel-Aannestad/OMDB-api/internal/database"
+	"github.com/Torkel-Aannestad/OMDB-api/internal/validator"
+)
+
+func (app *application) createImageLicenseHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		ImageID   int64  `json:"image_id"`
+		Source    string `json:"source"`
+		LicenseID int64  `json:"license_id"`
+		Author    string `json:"author"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	imageLicense := database.ImageLicense{
+		ImageID:   input.ImageID,
+		Source:    input.Source,
+		LicenseID: input.LicenseID,
+		Author:    input.Author,
+	}
+
+	v := validator.New()
+	database.ValidateImageLicense(v, &imageLicense)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	_, err = app.models.Images.Get(imageLicense.ImageID)
+	if err != nil {
+		if errors.Is(err, database.ErrRecordNotFound) {
+			v.AddError("image_id", "image does not exist")
+			app.failedValidationResponse(w, r, v.Errors)
+		} else {
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.models.ImageLicenses.Insert(&imageLicense)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	header := make(http.Header)
+	header.Set("Location", fmt.Sprintf("/v1/image-licenses/%d", imageLicense.ImageID))
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"image_license": imageLicense}, header)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
+
+func (app *application) getImageLicenseHandler(w http.ResponseWriter, r *http.Request) {
+	imageID, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	imageLicense, err := app.models.ImageLicenses.Get(imageID)
+	if err != nil {
+		if errors.Is(err, database.ErrRecordNotFound) {
+			app.notFoundResponse(w, r)
+
+		} else {
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"image_license": imageLicense}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
+
+func (app *application) updateImageLicenseHandler(w http.ResponseWriter, r *http.Request) {
+	imageID, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var input struct {
+		Source    *string `json:"source"`
+		LicenseID *int64  `json:"license_id"`
+		Author    *string `json:"author"`
+		Version   *int32  `json:"version"`
+	}
+
+	patchType := patchMediaType(r)
+	if patchType == "" {
+		err = app.readJSON(w, r, &input)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+	}
+
+	imageLicense, err := app.models.ImageLicenses.Get(imageID)
+	if err != nil {
+		if errors.Is(err, database.ErrRecordNotFound) {
+			app.notFoundResponse(w, r)
+
+		} else {
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if patchType != "" {
+		err = app.readPatch(w, r, patchType, imageLicense, "source", "license_id", "author")
+		if err != nil {
+			app.patchErrorResponse(w, r, err)
+			return
+		}
+	}
+
+	if input.Source != nil {
+		imageLicense.Source = *input.Source
+	}
+	if input.LicenseID != nil {
+		imageLicense.LicenseID = *input.LicenseID
+	}
+	if input.Author != nil {
+		imageLicense.Author = *input.Author
+	}
+
+	v := validator.New()
+	database.ValidateImageLicense(v, imageLicense)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.ImageLicenses.Update(imageLicense)
+	if err != nil {
+		if errors.Is(err, database.ErrEditConflict) {
+			app.editConflictResponse(w, r)
+		} else {
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	app.writeJSON(w, http.StatusOK, envelope{"image_license": imageLicense}, nil)
+}
+
+func (app *application) deleteImageLicenseHandler(w http.ResponseWriter, r *http.Request) {
+	imageID, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	err = app.models.ImageLicenses.Delete(imageID)
+	if err != nil {
+		if errors.Is(err, database.ErrRecordNotFound) {
+			app.notFoundResponse(w, r)
+		} else {
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "image license successfuly deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
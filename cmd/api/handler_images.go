@@ -69,6 +69,10 @@ func (app *application) getImageHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	if app.notModified(w, r, app.recordETag(r, image.Version), image.ModifiedAt) {
+		return
+	}
+
 	err = app.writeJSON(w, http.StatusOK, envelope{"image": image}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
@@ -77,6 +81,11 @@ func (app *application) getImageHandler(w http.ResponseWriter, r *http.Request)
 }
 
 func (app *application) getImagesObjektIdHandler(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Has("ids") {
+		app.getImagesByIDsHandler(w, r)
+		return
+	}
+
 	var input struct {
 		ObjectID   int64
 		ObjectType string
@@ -118,6 +127,30 @@ func (app *application) getImagesObjektIdHandler(w http.ResponseWriter, r *http.
 	}
 }
 
+// getImagesByIDsHandler serves GET /v1/images?ids=, see getMoviesByIDsHandler.
+func (app *application) getImagesByIDsHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+	ids := app.readIDs(r.URL.Query(), "ids", v)
+	if v.Valid() {
+		database.ValidateBatchIDs(v, ids)
+	}
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	images, missing, err := app.models.Images.GetByIDs(ids)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"images": images, "missing_ids": missing}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
 func (app *application) updateImageHandler(w http.ResponseWriter, r *http.Request) {
 	id, err := app.readIDParam(r)
 	if err != nil {
@@ -130,10 +163,13 @@ func (app *application) updateImageHandler(w http.ResponseWriter, r *http.Reques
 		ObjectType *string `json:"object_type"`
 	}
 
-	err = app.readJSON(w, r, &input)
-	if err != nil {
-		app.badRequestResponse(w, r, err)
-		return
+	patchType := patchMediaType(r)
+	if patchType == "" {
+		err = app.readJSON(w, r, &input)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
 	}
 
 	image, err := app.models.Images.Get(id)
@@ -147,6 +183,18 @@ func (app *application) updateImageHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	if !app.ifMatch(w, r, image.Version) {
+		return
+	}
+
+	if patchType != "" {
+		err = app.readPatch(w, r, patchType, image, "object_id", "object_type")
+		if err != nil {
+			app.patchErrorResponse(w, r, err)
+			return
+		}
+	}
+
 	if input.ObjectID != nil {
 		image.ObjectID = *input.ObjectID
 	}
@@ -171,7 +219,10 @@ func (app *application) updateImageHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	app.writeJSON(w, http.StatusOK, envelope{"image": image}, nil)
+	headers := make(http.Header)
+	headers.Set("ETag", app.recordETag(r, image.Version))
+
+	app.writeJSON(w, http.StatusOK, envelope{"image": image}, headers)
 
 }
 
@@ -182,6 +233,22 @@ func (app *application) deleteImageHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	if r.Header.Get("If-Match") != "" {
+		image, err := app.models.Images.Get(id)
+		if err != nil {
+			if errors.Is(err, database.ErrRecordNotFound) {
+				app.notFoundResponse(w, r)
+			} else {
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		if !app.ifMatch(w, r, image.Version) {
+			return
+		}
+	}
+
 	err = app.models.Images.Delete(id)
 	if err != nil {
 		if errors.Is(err, database.ErrRecordNotFound) {
@@ -12,7 +12,8 @@ import (
 
 func (app *application) createJobHandler(w http.ResponseWriter, r *http.Request) {
 	var input struct {
-		Name string `json:"name"`
+		Name         string              `json:"name"`
+		DepartmentID *database.NullInt64 `json:"department_id,omitempty"`
 	}
 
 	err := app.readJSON(w, r, &input)
@@ -25,6 +26,10 @@ func (app *application) createJobHandler(w http.ResponseWriter, r *http.Request)
 		Name: input.Name,
 	}
 
+	if input.DepartmentID != nil {
+		job.DepartmentID = *input.DepartmentID
+	}
+
 	v := validator.New()
 	database.ValidateJob(v, &job)
 	if !v.Valid() {
@@ -32,6 +37,10 @@ func (app *application) createJobHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	if !app.departmentExists(w, r, job.DepartmentID) {
+		return
+	}
+
 	err = app.models.Jobs.Insert(&job)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
@@ -67,6 +76,10 @@ func (app *application) getJobHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if app.notModified(w, r, app.recordETag(r, job.Version), job.ModifiedAt) {
+		return
+	}
+
 	err = app.writeJSON(w, http.StatusOK, envelope{"jobs": job}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
@@ -74,6 +87,30 @@ func (app *application) getJobHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// getJobsByIDsHandler serves GET /v1/jobs?ids=, see getMoviesByIDsHandler.
+func (app *application) getJobsByIDsHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+	ids := app.readIDs(r.URL.Query(), "ids", v)
+	if v.Valid() {
+		database.ValidateBatchIDs(v, ids)
+	}
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	jobs, missing, err := app.models.Jobs.GetByIDs(ids)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"jobs": jobs, "missing_ids": missing}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
 func (app *application) updateJobHandler(w http.ResponseWriter, r *http.Request) {
 	id, err := app.readIDParam(r)
 	if err != nil {
@@ -82,13 +119,17 @@ func (app *application) updateJobHandler(w http.ResponseWriter, r *http.Request)
 	}
 
 	var input struct {
-		Name *string `json:"name"`
+		Name         *string             `json:"name"`
+		DepartmentID *database.NullInt64 `json:"department_id"`
 	}
 
-	err = app.readJSON(w, r, &input)
-	if err != nil {
-		app.badRequestResponse(w, r, err)
-		return
+	patchType := patchMediaType(r)
+	if patchType == "" {
+		err = app.readJSON(w, r, &input)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
 	}
 
 	job, err := app.models.Jobs.Get(id)
@@ -102,9 +143,24 @@ func (app *application) updateJobHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	if !app.ifMatch(w, r, job.Version) {
+		return
+	}
+
+	if patchType != "" {
+		err = app.readPatch(w, r, patchType, job, "name", "department_id")
+		if err != nil {
+			app.patchErrorResponse(w, r, err)
+			return
+		}
+	}
+
 	if input.Name != nil {
 		job.Name = *input.Name
 	}
+	if input.DepartmentID != nil {
+		job.DepartmentID = *input.DepartmentID
+	}
 
 	v := validator.New()
 	database.ValidateJob(v, job)
@@ -113,6 +169,10 @@ func (app *application) updateJobHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	if !app.departmentExists(w, r, job.DepartmentID) {
+		return
+	}
+
 	err = app.models.Jobs.Update(job)
 	if err != nil {
 		if errors.Is(err, database.ErrEditConflict) {
@@ -123,7 +183,10 @@ func (app *application) updateJobHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	app.writeJSON(w, http.StatusOK, envelope{"job": job}, nil)
+	headers := make(http.Header)
+	headers.Set("ETag", app.recordETag(r, job.Version))
+
+	app.writeJSON(w, http.StatusOK, envelope{"job": job}, headers)
 
 }
 
@@ -134,6 +197,22 @@ func (app *application) deleteJobHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	if r.Header.Get("If-Match") != "" {
+		job, err := app.models.Jobs.Get(id)
+		if err != nil {
+			if errors.Is(err, database.ErrRecordNotFound) {
+				app.notFoundResponse(w, r)
+			} else {
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		if !app.ifMatch(w, r, job.Version) {
+			return
+		}
+	}
+
 	err = app.models.Jobs.Delete(id)
 	if err != nil {
 		if errors.Is(err, database.ErrRecordNotFound) {
@@ -148,3 +227,24 @@ func (app *application) deleteJobHandler(w http.ResponseWriter, r *http.Request)
 		app.serverErrorResponse(w, r, err)
 	}
 }
+
+// departmentExists checks that the department of a job is an existing job and
+// sends a failed validation response when it is not.
+func (app *application) departmentExists(w http.ResponseWriter, r *http.Request, departmentID database.NullInt64) bool {
+	if !departmentID.Valid {
+		return true
+	}
+
+	_, err := app.models.Jobs.Get(departmentID.Int64)
+	if err != nil {
+		if errors.Is(err, database.ErrRecordNotFound) {
+			v := validator.New()
+			v.AddError("department_id", "department does not exist")
+			app.failedValidationResponse(w, r, v.Errors)
+		} else {
+			app.serverErrorResponse(w, r, err)
+		}
+		return false
+	}
+	return true
+}
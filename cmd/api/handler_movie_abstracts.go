@@ -0,0 +1,210 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"net/http"
+
+	"github.com/Torkel-Aannestad/OMDB-api/internal/database"
+	"github.com/Torkel-Aannestad/OMDB-api/internal/validator"
+)
+
+func (app *application) createMovieAbstractHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		MovieID  int64  `json:"movie_id"`
+		Language string `json:"language"`
+		Abstract string `json:"abstract"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	movieAbstract := database.MovieAbstract{
+		MovieID:  input.MovieID,
+		Language: strings.ToLower(input.Language),
+		Abstract: input.Abstract,
+	}
+
+	v := validator.New()
+	database.ValidateMovieAbstract(v, &movieAbstract)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.MovieAbstracts.Insert(&movieAbstract)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	header := make(http.Header)
+	header.Set("Location", fmt.Sprintf("/v1/movie-abstracts/%d", movieAbstract.MovieID))
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"movie_abstract": movieAbstract}, header)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
+
+func (app *application) getMovieAbstractsHandler(w http.ResponseWriter, r *http.Request) {
+	movieID, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	movieAbstracts, err := app.models.MovieAbstracts.GetByMovieID(movieID)
+	if err != nil {
+		if errors.Is(err, database.ErrRecordNotFound) {
+			app.notFoundResponse(w, r)
+
+		} else {
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"movie_abstracts": movieAbstracts}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
+
+func (app *application) updateMovieAbstractHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		MovieID  int64   `json:"movie_id"`
+		Language string  `json:"language"`
+		Abstract *string `json:"abstract"`
+		Version  *int32  `json:"version"`
+	}
+
+	// A patch document only holds the new values, so the abstract is picked by
+	// the query string instead.
+	patchType := patchMediaType(r)
+	if patchType == "" {
+		err := app.readJSON(w, r, &input)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+	} else {
+		qs := r.URL.Query()
+		v := validator.New()
+		input.MovieID = int64(app.readInt(qs, "movie_id", 0, v))
+		input.Language = app.readString(qs, "language", "")
+		if !v.Valid() {
+			app.failedValidationResponse(w, r, v.Errors)
+			return
+		}
+	}
+
+	movieAbstract, err := app.models.MovieAbstracts.Get(input.MovieID, strings.ToLower(input.Language))
+	if err != nil {
+		if errors.Is(err, database.ErrRecordNotFound) {
+			app.notFoundResponse(w, r)
+
+		} else {
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if patchType != "" {
+		err = app.readPatch(w, r, patchType, movieAbstract, "abstract")
+		if err != nil {
+			app.patchErrorResponse(w, r, err)
+			return
+		}
+	}
+
+	if input.Abstract != nil {
+		movieAbstract.Abstract = *input.Abstract
+	}
+
+	v := validator.New()
+	database.ValidateMovieAbstract(v, movieAbstract)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.MovieAbstracts.Update(movieAbstract)
+	if err != nil {
+		if errors.Is(err, database.ErrEditConflict) {
+			app.editConflictResponse(w, r)
+		} else {
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	app.writeJSON(w, http.StatusOK, envelope{"movie_abstract": movieAbstract}, nil)
+}
+
+func (app *application) deleteMovieAbstractHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		MovieID  int64  `json:"movie_id"`
+		Language string `json:"language"`
+	}
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	err = app.models.MovieAbstracts.Delete(input.MovieID, strings.ToLower(input.Language))
+	if err != nil {
+		if errors.Is(err, database.ErrRecordNotFound) {
+			app.notFoundResponse(w, r)
+		} else {
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "movie abstract successfuly deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// localizeAbstracts replaces the english abstract on each movie with the first
+// preferred language that has an abstract. Movies without a matching abstract
+// keep the english abstract.
+func (app *application) localizeAbstracts(movies []*database.Movie, languages []string) error {
+	if len(movies) == 0 || len(languages) == 0 {
+		return nil
+	}
+
+	movieIDs := make([]int64, 0, len(movies))
+	for _, movie := range movies {
+		movieIDs = append(movieIDs, movie.ID)
+	}
+
+	abstracts, err := app.models.MovieAbstracts.GetForMovies(movieIDs, languages)
+	if err != nil {
+		return err
+	}
+
+	for _, movie := range movies {
+		for _, language := range languages {
+			if language == database.DefaultAbstractLanguage {
+				break
+			}
+			if abstract, found := abstracts[movie.ID][language]; found {
+				movie.Abstract = abstract
+				movie.AbstractLanguage = language
+				break
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"net/http"
+
+	"github.com/Torkel-Aannestad/OMDB-api/internal/database"
+	"github.com/Torkel-Aannestad/OMDB-api/internal/validator"
+)
+
+func (app *application) createMovieAliasHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		MovieID     int64  `json:"movie_id"`
+		Name        string `json:"name"`
+		Language    string `json:"language"`
+		CountryCode string `json:"country_code"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	movieAlias := database.MovieAlias{
+		MovieID:     input.MovieID,
+		Name:        strings.TrimSpace(input.Name),
+		Language:    strings.ToLower(input.Language),
+		CountryCode: strings.ToUpper(input.CountryCode),
+	}
+
+	v := validator.New()
+	database.ValidateMovieAlias(v, &movieAlias)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.MovieAliases.Insert(&movieAlias)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	header := make(http.Header)
+	header.Set("Location", fmt.Sprintf("/v1/movie-aliases/%d", movieAlias.MovieID))
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"movie_alias": movieAlias}, header)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
+
+func (app *application) getMovieAliasesHandler(w http.ResponseWriter, r *http.Request) {
+	movieID, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	movieAliases, err := app.models.MovieAliases.GetByMovieID(movieID)
+	if err != nil {
+		if errors.Is(err, database.ErrRecordNotFound) {
+			app.notFoundResponse(w, r)
+
+		} else {
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"movie_aliases": movieAliases}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
+
+func (app *application) updateMovieAliasHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var input struct {
+		Name        *string `json:"name"`
+		Language    *string `json:"language"`
+		CountryCode *string `json:"country_code"`
+		Version     *int32  `json:"version"`
+	}
+
+	patchType := patchMediaType(r)
+	if patchType == "" {
+		err = app.readJSON(w, r, &input)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+	}
+
+	movieAlias, err := app.models.MovieAliases.Get(id)
+	if err != nil {
+		if errors.Is(err, database.ErrRecordNotFound) {
+			app.notFoundResponse(w, r)
+
+		} else {
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if patchType != "" {
+		err = app.readPatch(w, r, patchType, movieAlias, "name", "language", "country_code")
+		if err != nil {
+			app.patchErrorResponse(w, r, err)
+			return
+		}
+		movieAlias.Name = strings.TrimSpace(movieAlias.Name)
+		movieAlias.Language = strings.ToLower(movieAlias.Language)
+		movieAlias.CountryCode = strings.ToUpper(movieAlias.CountryCode)
+	}
+
+	if input.Name != nil {
+		movieAlias.Name = strings.TrimSpace(*input.Name)
+	}
+	if input.Language != nil {
+		movieAlias.Language = strings.ToLower(*input.Language)
+	}
+	if input.CountryCode != nil {
+		movieAlias.CountryCode = strings.ToUpper(*input.CountryCode)
+	}
+
+	v := validator.New()
+	database.ValidateMovieAlias(v, movieAlias)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.MovieAliases.Update(movieAlias)
+	if err != nil {
+		if errors.Is(err, database.ErrEditConflict) {
+			app.editConflictResponse(w, r)
+		} else {
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	app.writeJSON(w, http.StatusOK, envelope{"movie_alias": movieAlias}, nil)
+}
+
+func (app *application) deleteMovieAliasHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	err = app.models.MovieAliases.Delete(id)
+	if err != nil {
+		if errors.Is(err, database.ErrRecordNotFound) {
+			app.notFoundResponse(w, r)
+		} else {
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "movie alias successfuly deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// localizeTitles sets the display title on each movie to an alias in the first
+// preferred language that has one. When a region is given an alias for that
+// country is preferred over an alias used in every country. Movies without a
+// matching alias keep the original name as title.
+func (app *application) localizeTitles(movies []*database.Movie, languages []string, region string) error {
+	if len(movies) == 0 || len(languages) == 0 {
+		return nil
+	}
+
+	movieIDs := make([]int64, 0, len(movies))
+	for _, movie := range movies {
+		movieIDs = append(movieIDs, movie.ID)
+	}
+
+	movieAliases, err := app.models.MovieAliases.GetForMovies(movieIDs, languages)
+	if err != nil {
+		return err
+	}
+
+	// titles holds the best alias per movie and language, ranked by how well the
+	// country matches the region.
+	type rankedTitle struct {
+		name string
+		rank int
+	}
+	titles := make(map[int64]map[string]rankedTitle)
+
+	for _, movieAlias := range movieAliases {
+		rank := 1
+		switch {
+		case region != "" && movieAlias.CountryCode == region:
+			rank = 3
+		case movieAlias.CountryCode == "":
+			rank = 2
+		}
+
+		if titles[movieAlias.MovieID] == nil {
+			titles[movieAlias.MovieID] = make(map[string]rankedTitle)
+		}
+		if current, found := titles[movieAlias.MovieID][movieAlias.Language]; !found || rank > current.rank {
+			titles[movieAlias.MovieID][movieAlias.Language] = rankedTitle{name: movieAlias.Name, rank: rank}
+		}
+	}
+
+	for _, movie := range movies {
+		for _, language := range languages {
+			if title, found := titles[movie.ID][language]; found {
+				movie.Title = title.name
+				break
+			}
+		}
+	}
+
+	return nil
+}
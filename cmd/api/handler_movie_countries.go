@@ -0,0 +1,103 @@
+package main
+
+import (
+	"errors"
+	"strings"
+
+	"net/http"
+
+	"github.com/Torkel-Aannestad/OMDB-api/internal/database"
+	"github.com/Torkel-Aannestad/OMDB-api/internal/validator"
+)
+
+func (app *application) createMovieCountryHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		MovieID     int64  `json:"movie_id"`
+		CountryCode string `json:"country_code"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	movieCountry := database.MovieCountry{
+		MovieID:     input.MovieID,
+		CountryCode: strings.ToUpper(input.CountryCode),
+	}
+
+	v := validator.New()
+	database.ValidateMovieCountry(v, &movieCountry)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.MovieCountries.Insert(&movieCountry)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"movie_countries": movieCountry}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
+
+func (app *application) getMovieCountriesHandler(w http.ResponseWriter, r *http.Request) {
+	movieID, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	movieCountries, err := app.models.MovieCountries.Get(movieID)
+	if err != nil {
+		if errors.Is(err, database.ErrRecordNotFound) {
+			app.notFoundResponse(w, r)
+
+		} else {
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"movie_countries": movieCountries}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
+
+func (app *application) deleteMovieCountryHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		MovieID     int64  `json:"movie_id"`
+		CountryCode string `json:"country_code"`
+	}
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	movieCountry := database.MovieCountry{
+		MovieID:     input.MovieID,
+		CountryCode: strings.ToUpper(input.CountryCode),
+	}
+
+	err = app.models.MovieCountries.Delete(movieCountry.MovieID, movieCountry.CountryCode)
+	if err != nil {
+		if errors.Is(err, database.ErrRecordNotFound) {
+			app.notFoundResponse(w, r)
+		} else {
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "movie_country successfuly deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
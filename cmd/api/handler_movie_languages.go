@@ -0,0 +1,103 @@
+package main
+
+import (
+	"errors"
+	"strings"
+
+	"net/http"
+
+	"github.com/Torkel-Aannestad/OMDB-api/internal/database"
+	"github.com/Torkel-Aannestad/OMDB-api/internal/validator"
+)
+
+func (app *application) createMovieLanguageHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		MovieID      int64  `json:"movie_id"`
+		LanguageCode string `json:"language_code"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	movieLanguage := database.MovieLanguage{
+		MovieID:      input.MovieID,
+		LanguageCode: strings.ToLower(input.LanguageCode),
+	}
+
+	v := validator.New()
+	database.ValidateMovieLanguage(v, &movieLanguage)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.MovieLanguages.Insert(&movieLanguage)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"movie_languages": movieLanguage}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
+
+func (app *application) getMovieLanguagesHandler(w http.ResponseWriter, r *http.Request) {
+	movieID, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	movieLanguages, err := app.models.MovieLanguages.Get(movieID)
+	if err != nil {
+		if errors.Is(err, database.ErrRecordNotFound) {
+			app.notFoundResponse(w, r)
+
+		} else {
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"movie_languages": movieLanguages}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
+
+func (app *application) deleteMovieLanguageHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		MovieID      int64  `json:"movie_id"`
+		LanguageCode string `json:"language_code"`
+	}
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	movieLanguage := database.MovieLanguage{
+		MovieID:      input.MovieID,
+		LanguageCode: strings.ToLower(input.LanguageCode),
+	}
+
+	err = app.models.MovieLanguages.Delete(movieLanguage.MovieID, movieLanguage.LanguageCode)
+	if err != nil {
+		if errors.Is(err, database.ErrRecordNotFound) {
+			app.notFoundResponse(w, r)
+		} else {
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "movie_language successfuly deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
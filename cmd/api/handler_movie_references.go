@@ -0,0 +1,216 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"net/http"
+
+	"github.com/Torkel-Aannestad/OMDB-api/internal/database"
+	"github.com/Torkel-Aannestad/OMDB-api/internal/validator"
+)
+
+func (app *application) createMovieReferenceHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		MovieID      int64  `json:"movie_id"`
+		ReferencedID int64  `json:"referenced_id"`
+		Type         string `json:"type"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	movieReference := database.MovieReference{
+		MovieID:      input.MovieID,
+		ReferencedID: input.ReferencedID,
+		Type:         input.Type,
+	}
+
+	v := validator.New()
+	database.ValidateMovieReference(v, &movieReference)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.MovieReferences.Insert(&movieReference)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	header := make(http.Header)
+	header.Set("Location", fmt.Sprintf("/v1/movie-references/%d", movieReference.MovieID))
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"movie_reference": movieReference}, header)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
+
+func (app *application) getMovieReferencesHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	movieReferences, err := app.models.MovieReferences.GetByMovieID(id)
+	if err != nil {
+		if errors.Is(err, database.ErrRecordNotFound) {
+			app.notFoundResponse(w, r)
+
+		} else {
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"movie_references": movieReferences}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
+
+func (app *application) getMovieReferenceGraphHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var input struct {
+		Depth     int
+		Direction string
+	}
+
+	qs := r.URL.Query()
+	v := validator.New()
+
+	input.Depth = app.readInt(qs, "depth", 2, v)
+	input.Direction = app.readString(qs, "direction", "both")
+
+	v.Check(input.Depth > 0, "depth", "must be greater than zero")
+	v.Check(input.Depth <= 5, "depth", "must be a maximum of 5")
+	v.Check(validator.PermittedValue(input.Direction, "both", "outgoing", "incoming"), "direction", "must be one of the following values: both, outgoing, incoming")
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	graph, err := app.models.MovieReferences.GetGraph(id, input.Depth, input.Direction)
+	if err != nil {
+		if errors.Is(err, database.ErrRecordNotFound) {
+			app.notFoundResponse(w, r)
+
+		} else {
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"references": graph}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
+
+func (app *application) updateMovieReferenceHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var input struct {
+		MovieID      *int64  `json:"movie_id"`
+		ReferencedID *int64  `json:"referenced_id"`
+		Type         *string `json:"type"`
+		Version      *int32  `json:"version"`
+	}
+
+	patchType := patchMediaType(r)
+	if patchType == "" {
+		err = app.readJSON(w, r, &input)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+	}
+
+	movieReference, err := app.models.MovieReferences.Get(id)
+	if err != nil {
+		if errors.Is(err, database.ErrRecordNotFound) {
+			app.notFoundResponse(w, r)
+
+		} else {
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if patchType != "" {
+		err = app.readPatch(w, r, patchType, movieReference, "movie_id", "referenced_id", "type")
+		if err != nil {
+			app.patchErrorResponse(w, r, err)
+			return
+		}
+	}
+
+	if input.MovieID != nil {
+		movieReference.MovieID = *input.MovieID
+	}
+	if input.ReferencedID != nil {
+		movieReference.ReferencedID = *input.ReferencedID
+	}
+	if input.Type != nil {
+		movieReference.Type = *input.Type
+	}
+
+	v := validator.New()
+	database.ValidateMovieReference(v, movieReference)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.MovieReferences.Update(movieReference)
+	if err != nil {
+		if errors.Is(err, database.ErrEditConflict) {
+			app.editConflictResponse(w, r)
+		} else {
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	app.writeJSON(w, http.StatusOK, envelope{"movie_reference": movieReference}, nil)
+}
+
+func (app *application) deleteMovieReferenceHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	err = app.models.MovieReferences.Delete(id)
+	if err != nil {
+		if errors.Is(err, database.ErrRecordNotFound) {
+			app.notFoundResponse(w, r)
+		} else {
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "movie reference successfuly deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
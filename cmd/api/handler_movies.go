@@ -3,6 +3,7 @@ package main
 import (
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"net/http"
@@ -34,18 +35,23 @@ func (app *application) createMovieHandler(w http.ResponseWriter, r *http.Reques
 	}
 
 	movie := database.Movie{
-		Name:        input.Name,
-		ParentID:    input.ParentID,
-		SeriesID:    input.SeriesID,
-		Date:        input.Date,
-		Kind:        input.Kind,
-		Runtime:     input.Runtime,
-		Budget:      0,
-		Revenue:     0,
-		Homepage:    "",
-		VoteAvarage: input.VoteAverage,
-		VoteCount:   input.VotesCount,
-		Abstract:    "",
+		Name:             input.Name,
+		Title:            input.Name,
+		ParentID:         input.ParentID,
+		SeriesID:         input.SeriesID,
+		Date:             input.Date,
+		Kind:             input.Kind,
+		Runtime:          input.Runtime,
+		Budget:           0,
+		Revenue:          0,
+		Homepage:         "",
+		VoteAvarage:      input.VoteAverage,
+		VoteCount:        input.VotesCount,
+		Abstract:         "",
+		AbstractLanguage: database.DefaultAbstractLanguage,
+		Countries:        []string{},
+		Languages:        []string{},
+		Aliases:          []string{},
 	}
 
 	if input.Budget != nil {
@@ -91,7 +97,31 @@ func (app *application) getMovieHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	movie, err := app.models.Movies.Get(id)
+	v := validator.New()
+	languages := app.readLanguages(r, v)
+	region := strings.ToUpper(app.readString(r.URL.Query(), "region", ""))
+	if region != "" {
+		database.ValidateCountryCode(v, "region", region)
+	}
+	expand := app.readExpand(r.URL.Query(), movieExpansions, v)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	permitted, err := app.expandPermitted(r, expand, movieExpansions)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if !permitted {
+		app.notPermittedResponse(w, r)
+		return
+	}
+
+	fields := app.contextGetFields(r)
+
+	movie, err := app.models.Movies.GetFields(id, fields)
 	if err != nil {
 		if errors.Is(err, database.ErrRecordNotFound) {
 			app.notFoundResponse(w, r)
@@ -102,7 +132,37 @@ func (app *application) getMovieHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	err = app.writeJSON(w, http.StatusOK, envelope{"movie": movie}, nil)
+	w.Header().Add("Vary", "Accept-Language")
+
+	// The validators only cover the movie, so expanded responses are not
+	// cached. A localized movie has its own tag for the preferred languages and
+	// region.
+	if len(expand) == 0 {
+		tag := app.recordETag(r, movie.Version)
+		if len(languages) > 0 {
+			tag = variantETag(movie.Version, "fields", strings.Join(fields, ","), "languages", strings.Join(languages, ","), "region", region)
+		}
+		if app.notModified(w, r, tag, movie.ModifiedAt) {
+			return
+		}
+	}
+
+	err = app.localizeMovies([]*database.Movie{movie}, fields, languages, region)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	responses, err := app.expandMovies([]*database.Movie{movie}, expand)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	header := make(http.Header)
+	header.Set("Content-Language", movie.AbstractLanguage)
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"movie": responses[0]}, header)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
@@ -110,9 +170,13 @@ func (app *application) getMovieHandler(w http.ResponseWriter, r *http.Request)
 }
 
 func (app *application) listMoviesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Has("ids") {
+		app.getMoviesByIDsHandler(w, r)
+		return
+	}
+
 	var input struct {
-		Name string
-		Kind string
+		database.MovieFilters
 		database.Filters
 	}
 
@@ -122,10 +186,51 @@ func (app *application) listMoviesHandler(w http.ResponseWriter, r *http.Request
 
 	input.Name = app.readString(qs, "name", "")
 	input.Kind = app.readString(qs, "kind", "")
+	input.Country = strings.ToUpper(app.readString(qs, "country", ""))
+	input.Language = strings.ToLower(app.readString(qs, "language", ""))
+	input.DateFrom = app.readOptionalDate(qs, "date_from", v)
+	input.DateTo = app.readOptionalDate(qs, "date_to", v)
+	input.RuntimeMin = app.readOptionalInt64(qs, "runtime_min", v)
+	input.RuntimeMax = app.readOptionalInt64(qs, "runtime_max", v)
+	input.VoteAvgMin = app.readOptionalFloat(qs, "vote_average_min", v)
+	input.VoteAvgMax = app.readOptionalFloat(qs, "vote_average_max", v)
+	input.VotesCountMin = app.readOptionalInt64(qs, "votes_count_min", v)
+	input.VotesCountMax = app.readOptionalInt64(qs, "votes_count_max", v)
+	input.BudgetMin = app.readOptionalFloat(qs, "budget_min", v)
+	input.BudgetMax = app.readOptionalFloat(qs, "budget_max", v)
+	input.RevenueMin = app.readOptionalFloat(qs, "revenue_min", v)
+	input.RevenueMax = app.readOptionalFloat(qs, "revenue_max", v)
+	input.ParentID = app.readOptionalInt64(qs, "parent_id", v)
+	input.SeriesID = app.readOptionalInt64(qs, "series_id", v)
+	input.CategoryIDs = app.readIDs(qs, "category_ids", v)
+	input.KeywordIDs = app.readIDs(qs, "keyword_ids", v)
+	input.PersonIDs = app.readIDs(qs, "person_ids", v)
+	languages := app.readLanguages(r, v)
+	region := strings.ToUpper(app.readString(qs, "region", ""))
 	input.Filters.Page = app.readInt(qs, "page", 1, v)
 	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+	input.Filters.After = app.readString(qs, "after", "")
+	input.Filters.Before = app.readString(qs, "before", "")
+	input.Filters.ExactCount = app.readCount(qs, v)
 	input.Filters.Sort = app.readString(qs, "sort", "id")
-	input.Filters.SortSafelist = []string{"id", "name", "date", "runtime", "-id", "-name", "-date", "-runtime"}
+	input.Filters.SortSafelist = []string{
+		"id", "name", "date", "runtime", "vote_average", "votes_count", "budget", "revenue",
+		"-id", "-name", "-date", "-runtime", "-vote_average", "-votes_count", "-budget", "-revenue",
+	}
+
+	if q := app.readString(qs, "q", ""); q != "" {
+		sort := database.ParseMovieQuery(v, q, &input.MovieFilters)
+		if sort != "" {
+			switch {
+			case qs.Has("sort"):
+				v.AddError("q.sort", "must not be used together with the sort query parameter")
+			case !validator.PermittedValue(sort, input.Filters.SortSafelist...):
+				v.AddError("q.sort", "invalid sort value")
+			default:
+				input.Filters.Sort = sort
+			}
+		}
+	}
 
 	database.ValidateFilters(v, input.Filters)
 	if !v.Valid() {
@@ -133,19 +238,137 @@ func (app *application) listMoviesHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	database.ValidateKind(v, &input.Kind)
+	database.ValidateMovieFilters(v, input.MovieFilters)
+	if region != "" {
+		database.ValidateCountryCode(v, "region", region)
+	}
+	expand := app.readExpand(qs, movieExpansions, v)
+	facets := app.readCSV(qs, "facets", []string{})
+	for i := range facets {
+		facets[i] = strings.TrimSpace(facets[i])
+	}
+	database.ValidateFacets(v, facets)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	permitted, err := app.expandPermitted(r, expand, movieExpansions)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if !permitted {
+		app.notPermittedResponse(w, r)
+		return
+	}
+
+	fields := app.contextGetFields(r)
+
+	movies, metadata, err := app.models.Movies.GetAll(input.MovieFilters, input.Filters, fields)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.localizeMovies(movies, fields, languages, region)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.setPageLinks(r, &metadata)
+
+	responses, err := app.expandMovies(movies, expand)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	data := envelope{"movies": responses, "metadata": metadata}
+
+	// Suggest similar titles when the name matched nothing, like /v1/search.
+	f := input.Filters
+	if len(movies) == 0 && input.Name != "" && f.Page == 1 && f.After == "" && f.Before == "" {
+		didYouMean, err := app.models.Search.DidYouMean(input.Name, []string{"movie"})
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+		data["did_you_mean"] = didYouMean
+	}
+
+	if len(facets) > 0 {
+		counts, err := app.models.Movies.GetFacets(input.MovieFilters, facets)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+		data["facets"] = counts
+	}
+
+	w.Header().Add("Vary", "Accept-Language")
+
+	err = app.writeJSON(w, http.StatusOK, data, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// getMoviesByIDsHandler serves GET /v1/movies?ids=. The movies are returned in
+// the order of the ids, and ids without a movie are listed in missing_ids.
+func (app *application) getMoviesByIDsHandler(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+
+	v := validator.New()
+	ids := app.readIDs(qs, "ids", v)
+	languages := app.readLanguages(r, v)
+	region := strings.ToUpper(app.readString(qs, "region", ""))
+	if region != "" {
+		database.ValidateCountryCode(v, "region", region)
+	}
+	expand := app.readExpand(qs, movieExpansions, v)
+	if v.Valid() {
+		database.ValidateBatchIDs(v, ids)
+	}
 	if !v.Valid() {
 		app.failedValidationResponse(w, r, v.Errors)
 		return
 	}
 
-	movies, metadata, err := app.models.Movies.GetAll(input.Name, input.Kind, input.Filters)
+	permitted, err := app.expandPermitted(r, expand, movieExpansions)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if !permitted {
+		app.notPermittedResponse(w, r)
+		return
+	}
+
+	fields := app.contextGetFields(r)
+
+	movies, missing, err := app.models.Movies.GetByIDs(ids, fields)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.localizeMovies(movies, fields, languages, region)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
 
-	err = app.writeJSON(w, http.StatusOK, envelope{"movies": movies, "metadata": metadata}, nil)
+	responses, err := app.expandMovies(movies, expand)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	w.Header().Add("Vary", "Accept-Language")
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"movies": responses, "missing_ids": missing}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
@@ -174,10 +397,13 @@ func (app *application) updateMovieHandler(w http.ResponseWriter, r *http.Reques
 		Version     *int32              `json:"version"`
 	}
 
-	err = app.readJSON(w, r, &input)
-	if err != nil {
-		app.badRequestResponse(w, r, err)
-		return
+	patchType := patchMediaType(r)
+	if patchType == "" {
+		err = app.readJSON(w, r, &input)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
 	}
 
 	movie, err := app.models.Movies.Get(id)
@@ -191,8 +417,22 @@ func (app *application) updateMovieHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	if !app.ifMatch(w, r, movie.Version) {
+		return
+	}
+
+	if patchType != "" {
+		err = app.readPatch(w, r, patchType, movie, "name", "parent_id", "date", "series_id", "kind", "runtime", "budget", "revenue", "homepage", "vote_average", "vote_count", "abstract")
+		if err != nil {
+			app.patchErrorResponse(w, r, err)
+			return
+		}
+		movie.Title = movie.Name
+	}
+
 	if input.Name != nil {
 		movie.Name = *input.Name
+		movie.Title = *input.Name
 	}
 	if input.ParentID != nil {
 		movie.ParentID = *input.ParentID
@@ -239,7 +479,10 @@ func (app *application) updateMovieHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	app.writeJSON(w, http.StatusOK, envelope{"movie": movie}, nil)
+	headers := make(http.Header)
+	headers.Set("ETag", app.recordETag(r, movie.Version))
+
+	app.writeJSON(w, http.StatusOK, envelope{"movie": movie}, headers)
 
 }
 
@@ -250,6 +493,22 @@ func (app *application) deleteMovieHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	if r.Header.Get("If-Match") != "" {
+		movie, err := app.models.Movies.Get(id)
+		if err != nil {
+			if errors.Is(err, database.ErrRecordNotFound) {
+				app.notFoundResponse(w, r)
+			} else {
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		if !app.ifMatch(w, r, movie.Version) {
+			return
+		}
+	}
+
 	err = app.models.Movies.Delete(id)
 	if err != nil {
 		if errors.Is(err, database.ErrRecordNotFound) {
@@ -264,3 +523,23 @@ func (app *application) deleteMovieHandler(w http.ResponseWriter, r *http.Reques
 		app.serverErrorResponse(w, r, err)
 	}
 }
+
+// localizeMovies localizes the abstracts and titles of the movies. Fields that
+// were not selected with ?fields= are skipped.
+func (app *application) localizeMovies(movies []*database.Movie, fields database.Fields, languages []string, region string) error {
+	if fields.Include("abstract") || fields.Include("abstract_language") {
+		err := app.localizeAbstracts(movies, languages)
+		if err != nil {
+			return err
+		}
+	}
+
+	if fields.Include("title") {
+		err := app.localizeTitles(movies, languages, region)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -71,7 +71,24 @@ func (app *application) getPeopleHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	person, err := app.models.People.Get(id)
+	v := validator.New()
+	expand := app.readExpand(r.URL.Query(), personExpansions, v)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	permitted, err := app.expandPermitted(r, expand, personExpansions)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if !permitted {
+		app.notPermittedResponse(w, r)
+		return
+	}
+
+	person, err := app.models.People.GetFields(id, app.contextGetFields(r))
 	if err != nil {
 		if errors.Is(err, database.ErrRecordNotFound) {
 			app.notFoundResponse(w, r)
@@ -82,7 +99,19 @@ func (app *application) getPeopleHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	err = app.writeJSON(w, http.StatusOK, envelope{"person": person}, nil)
+	// The validators only cover the person row, so expanded responses are not
+	// cached.
+	if len(expand) == 0 && app.notModified(w, r, app.recordETag(r, person.Version), person.ModifiedAt) {
+		return
+	}
+
+	responses, err := app.expandPeople([]*database.Person{person}, expand)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"person": responses[0]}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
@@ -90,6 +119,11 @@ func (app *application) getPeopleHandler(w http.ResponseWriter, r *http.Request)
 }
 
 func (app *application) listPeopleHandler(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Has("ids") {
+		app.getPeopleByIDsHandler(w, r)
+		return
+	}
+
 	var input struct {
 		Name string
 		database.Filters
@@ -102,8 +136,12 @@ func (app *application) listPeopleHandler(w http.ResponseWriter, r *http.Request
 	input.Name = app.readString(qs, "name", "")
 	input.Filters.Page = app.readInt(qs, "page", 1, v)
 	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+	input.Filters.After = app.readString(qs, "after", "")
+	input.Filters.Before = app.readString(qs, "before", "")
+	input.Filters.ExactCount = app.readCount(qs, v)
 	input.Filters.Sort = app.readString(qs, "sort", "id")
 	input.Filters.SortSafelist = []string{"id", "name", "birthday", "-id", "-name", "-birthday"}
+	expand := app.readExpand(qs, personExpansions, v)
 
 	database.ValidateFilters(v, input.Filters)
 	if !v.Valid() {
@@ -111,13 +149,74 @@ func (app *application) listPeopleHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	people, metadata, err := app.models.People.GetAll(input.Name, input.Filters)
+	permitted, err := app.expandPermitted(r, expand, personExpansions)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if !permitted {
+		app.notPermittedResponse(w, r)
+		return
+	}
+
+	people, metadata, err := app.models.People.GetAll(input.Name, input.Filters, app.contextGetFields(r))
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.setPageLinks(r, &metadata)
+
+	responses, err := app.expandPeople(people, expand)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
 
-	err = app.writeJSON(w, http.StatusOK, envelope{"people": people, "metadata": metadata}, nil)
+	err = app.writeJSON(w, http.StatusOK, envelope{"people": responses, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// getPeopleByIDsHandler serves GET /v1/people?ids=, see getMoviesByIDsHandler.
+func (app *application) getPeopleByIDsHandler(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+
+	v := validator.New()
+	ids := app.readIDs(qs, "ids", v)
+	expand := app.readExpand(qs, personExpansions, v)
+	if v.Valid() {
+		database.ValidateBatchIDs(v, ids)
+	}
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	permitted, err := app.expandPermitted(r, expand, personExpansions)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if !permitted {
+		app.notPermittedResponse(w, r)
+		return
+	}
+
+	people, missing, err := app.models.People.GetByIDs(ids, app.contextGetFields(r))
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	responses, err := app.expandPeople(people, expand)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"people": responses, "missing_ids": missing}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
@@ -139,10 +238,13 @@ func (app *application) updatePeopleHandler(w http.ResponseWriter, r *http.Reque
 		Version  *int32     `json:"version"`
 	}
 
-	err = app.readJSON(w, r, &input)
-	if err != nil {
-		app.badRequestResponse(w, r, err)
-		return
+	patchType := patchMediaType(r)
+	if patchType == "" {
+		err = app.readJSON(w, r, &input)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
 	}
 
 	person, err := app.models.People.Get(id)
@@ -155,6 +257,25 @@ func (app *application) updatePeopleHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
+	if !app.ifMatch(w, r, person.Version) {
+		return
+	}
+
+	if patchType != "" {
+		err = app.readPatch(w, r, patchType, person, "name", "birthday", "deathday", "gender", "aliases")
+		if err != nil {
+			app.patchErrorResponse(w, r, err)
+			return
+		}
+		// An unknown deathday is stored as 1888-01-01, like on create.
+		if person.Deathday.IsZero() {
+			person.Deathday = time.Date(1888, 1, 1, 00, 00, 00, 00, time.UTC)
+		}
+		if person.Aliases == nil {
+			person.Aliases = []string{}
+		}
+	}
+
 	if input.Name != nil {
 		person.Name = *input.Name
 	}
@@ -188,7 +309,10 @@ func (app *application) updatePeopleHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	app.writeJSON(w, http.StatusOK, envelope{"people": person}, nil)
+	headers := make(http.Header)
+	headers.Set("ETag", app.recordETag(r, person.Version))
+
+	app.writeJSON(w, http.StatusOK, envelope{"people": person}, headers)
 
 }
 
@@ -199,6 +323,22 @@ func (app *application) deletePeopleHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
+	if r.Header.Get("If-Match") != "" {
+		person, err := app.models.People.Get(id)
+		if err != nil {
+			if errors.Is(err, database.ErrRecordNotFound) {
+				app.notFoundResponse(w, r)
+			} else {
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		if !app.ifMatch(w, r, person.Version) {
+			return
+		}
+	}
+
 	err = app.models.People.Delete(id)
 	if err != nil {
 		if errors.Is(err, database.ErrRecordNotFound) {
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/Torkel-Aannestad/OMDB-api/internal/database"
+	"github.com/Torkel-Aannestad/OMDB-api/internal/validator"
+)
+
+// searchPermissions maps the search types to the permission needed to read
+// results of the type.
+var searchPermissions = map[string]string{
+	"movie":    "movies:read",
+	"person":   "people:read",
+	"category": "categories:read",
+	"keyword":  "categories:read",
+}
+
+func (app *application) searchHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Q     string
+		Types []string
+		database.Filters
+	}
+
+	qs := r.URL.Query()
+
+	v := validator.New()
+
+	input.Q = app.readString(qs, "q", "")
+	input.Types = app.readCSV(qs, "types", []string{})
+	for i := range input.Types {
+		input.Types[i] = strings.TrimSpace(input.Types[i])
+	}
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+	input.Filters.Sort = "-score"
+	input.Filters.SortSafelist = []string{"-score"}
+
+	permissions, err := app.models.Permissions.GetAllForUser(app.contextGetUser(r).ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if len(input.Types) == 0 {
+		input.Types = permittedSearchTypes(permissions, database.SearchTypes)
+		if len(input.Types) == 0 {
+			app.notPermittedResponse(w, r)
+			return
+		}
+	}
+
+	database.ValidateFilters(v, input.Filters)
+	database.ValidateSearch(v, input.Q, input.Types)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	if len(permittedSearchTypes(permissions, input.Types)) != len(input.Types) {
+		app.notPermittedResponse(w, r)
+		return
+	}
+
+	results, metadata, err := app.models.Search.Search(input.Q, input.Types, input.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	data := envelope{"results": results, "metadata": metadata}
+
+	// Suggest similar names when nothing matched at all.
+	if len(results) == 0 && input.Filters.Page == 1 {
+		didYouMean, err := app.models.Search.DidYouMean(input.Q, input.Types)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+		data["did_you_mean"] = didYouMean
+	}
+
+	err = app.writeJSON(w, http.StatusOK, data, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) autocompleteHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Q     string
+		Types []string
+		Limit int
+	}
+
+	qs := r.URL.Query()
+
+	v := validator.New()
+
+	input.Q = app.readString(qs, "q", "")
+	input.Types = app.readCSV(qs, "types", []string{})
+	for i := range input.Types {
+		input.Types[i] = strings.TrimSpace(input.Types[i])
+	}
+	input.Limit = app.readInt(qs, "limit", 10, v)
+
+	permissions, err := app.models.Permissions.GetAllForUser(app.contextGetUser(r).ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if len(input.Types) == 0 {
+		input.Types = permittedSearchTypes(permissions, database.AutocompleteTypes)
+		if len(input.Types) == 0 {
+			app.notPermittedResponse(w, r)
+			return
+		}
+	}
+
+	database.ValidateAutocomplete(v, input.Q, input.Types, input.Limit)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	if len(permittedSearchTypes(permissions, input.Types)) != len(input.Types) {
+		app.notPermittedResponse(w, r)
+		return
+	}
+
+	suggestions, err := app.models.Search.Autocomplete(input.Q, input.Types, input.Limit)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"suggestions": suggestions}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// permittedSearchTypes returns the types the user has the read permission of.
+// Searches without types cover every type the user may read.
+func permittedSearchTypes(permissions database.Permissions, types []string) []string {
+	permitted := []string{}
+	for _, searchType := range types {
+		if permissions.Include(searchPermissions[searchType]) {
+			permitted = append(permitted, searchType)
+		}
+	}
+	return permitted
+}
+
+func (app *application) searchPlotsHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Q         string
+		SimilarTo int64
+		database.Filters
+	}
+
+	qs := r.URL.Query()
+
+	v := validator.New()
+
+	input.Q = strings.TrimSpace(app.readString(qs, "q", ""))
+	input.SimilarTo = int64(app.readInt(qs, "similar_to", 0, v))
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+	input.Filters.Sort = "-score"
+	input.Filters.SortSafelist = []string{"-score"}
+
+	// The english abstracts are always searched, as most movies only have one
+	// in english.
+	languages := app.readLanguages(r, v)
+	if !slices.Contains(languages, database.DefaultAbstractLanguage) {
+		languages = append(languages, database.DefaultAbstractLanguage)
+	}
+
+	database.ValidateFilters(v, input.Filters)
+	database.ValidatePlotSearch(v, input.Q, input.SimilarTo, languages)
+	if input.SimilarTo != 0 {
+		v.Check(input.Filters.Page == 1, "page", "must not be used together with similar_to")
+	}
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	if input.SimilarTo != 0 {
+		results, err := app.models.Search.SimilarPlots(input.SimilarTo, input.Filters.PageSize)
+		if err != nil {
+			switch {
+			case errors.Is(err, database.ErrRecordNotFound):
+				app.notFoundResponse(w, r)
+			case errors.Is(err, database.ErrNoAbstract):
+				v.AddError("similar_to", "movie must have an english abstract")
+				app.failedValidationResponse(w, r, v.Errors)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		err = app.writeJSON(w, http.StatusOK, envelope{"results": results}, nil)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	results, metadata, err := app.models.Search.SearchPlots(input.Q, languages, input.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	w.Header().Add("Vary", "Accept-Language")
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"results": results, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -64,7 +64,7 @@ func (app *application) registerUserHandler(w http.ResponseWriter, r *http.Reque
 		}
 	}
 
-	err = app.models.Permissions.AddForUser(user.ID, "movies:read", "people:read", "casts:read", "jobs:read", "categories:read", "category-items:read", "movie-links:read", "people-links:read", "trailers:read", "images:write", "movies:write", "people:write", "casts:write", "jobs:write", "categories:write", "category-items:write", "movie-links:write", "people-links:write", "trailers:write", "images:write")
+	err = app.models.Permissions.AddForUser(user.ID, "movies:read", "people:read", "casts:read", "jobs:read", "categories:read", "category-items:read", "movie-links:read", "people-links:read", "trailers:read", "images:write", "movies:write", "people:write", "casts:write", "jobs:write", "categories:write", "category-items:write", "movie-links:write", "people-links:write", "trailers:write", "images:write", "movie-countries:read", "movie-countries:write", "movie-languages:read", "movie-languages:write", "movie-references:read", "movie-references:write", "movie-abstracts:read", "movie-abstracts:write", "movie-aliases:read", "movie-aliases:write")
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
@@ -212,7 +212,7 @@ func (app *application) addUserPermissionsHandler(w http.ResponseWriter, r *http
 		return
 	}
 
-	err = app.models.Permissions.AddForUser(user.ID, "movies:read", "people:read", "casts:read", "jobs:read", "categories:read", "category-items:read", "movie-links:read", "people-links:read", "trailers:read", "images:write", "movies:write", "people:write", "casts:write", "jobs:write", "categories:write", "category-items:write", "movie-links:write", "people-links:write", "trailers:write", "images:write")
+	err = app.models.Permissions.AddForUser(user.ID, "movies:read", "people:read", "casts:read", "jobs:read", "categories:read", "category-items:read", "movie-links:read", "people-links:read", "trailers:read", "images:write", "movies:write", "people:write", "casts:write", "jobs:write", "categories:write", "category-items:write", "movie-links:write", "people-links:write", "trailers:write", "images:write", "movie-countries:read", "movie-countries:write", "movie-languages:read", "movie-languages:write", "movie-references:read", "movie-references:write", "movie-abstracts:read", "movie-abstracts:write", "movie-aliases:read", "movie-aliases:write")
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
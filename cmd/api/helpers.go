@@ -5,11 +5,16 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"math"
 	"net/http"
 	"net/url"
+	"slices"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/Torkel-Aannestad/OMDB-api/internal/database"
 	"github.com/Torkel-Aannestad/OMDB-api/internal/validator"
 	"github.com/julienschmidt/httprouter"
 )
@@ -139,14 +144,151 @@ func (app *application) readInt(qs url.Values, key string, defaultValue int, v *
 	return i
 }
 
-// func (app *application) readCSV(qs url.Values, key string, defaultValue []string) []string {
-// 	csv := qs.Get(key)
-// 	if csv == "" {
-// 		return defaultValue
-// 	}
+// readLanguages returns the languages the client prefers, most preferred first.
+// The lang query parameter takes precedence over the Accept-Language header.
+// Only the primary subtag is kept, so "nb-NO" is read as "nb".
+func (app *application) readLanguages(r *http.Request, v *validator.Validator) []string {
+	lang := strings.ToLower(r.URL.Query().Get("lang"))
+	if lang != "" {
+		v.Check(validator.Matches(lang, validator.LanguageCodeRX), "lang", "must be a two letter ISO 639-1 code")
+		return []string{lang}
+	}
+
+	type weightedLanguage struct {
+		code   string
+		weight float64
+	}
+
+	weighted := []weightedLanguage{}
+	for _, part := range strings.Split(r.Header.Get("Accept-Language"), ",") {
+		code, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		code, _, _ = strings.Cut(strings.ToLower(code), "-")
+		if !validator.Matches(code, validator.LanguageCodeRX) {
+			continue
+		}
+
+		weight := 1.0
+		if q, found := strings.CutPrefix(strings.TrimSpace(params), "q="); found {
+			parsed, err := strconv.ParseFloat(q, 64)
+			if err != nil {
+				continue
+			}
+			weight = parsed
+		}
+		if weight <= 0 {
+			continue
+		}
+
+		weighted = append(weighted, weightedLanguage{code: code, weight: weight})
+	}
+
+	sort.SliceStable(weighted, func(i, j int) bool {
+		return weighted[i].weight > weighted[j].weight
+	})
+
+	languages := []string{}
+	for _, language := range weighted {
+		if !slices.Contains(languages, language.code) {
+			languages = append(languages, language.code)
+		}
+	}
 
-// 	return strings.Split(csv, ",")
-// }
+	return languages
+}
+
+func (app *application) readCSV(qs url.Values, key string, defaultValue []string) []string {
+	csv := qs.Get(key)
+	if csv == "" {
+		return defaultValue
+	}
+
+	return strings.Split(csv, ",")
+}
+
+// readOptionalInt64 returns nil when the key is not in the query string, so
+// that filters can tell an absent value from zero.
+func (app *application) readOptionalInt64(qs url.Values, key string, v *validator.Validator) *int64 {
+	s := qs.Get(key)
+	if s == "" {
+		return nil
+	}
+
+	i, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		v.AddError(key, "must be an integer value")
+		return nil
+	}
+	return &i
+}
+
+func (app *application) readOptionalFloat(qs url.Values, key string, v *validator.Validator) *float64 {
+	s := qs.Get(key)
+	if s == "" {
+		return nil
+	}
+
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+		v.AddError(key, "must be a number")
+		return nil
+	}
+	return &f
+}
+
+func (app *application) readOptionalDate(qs url.Values, key string, v *validator.Validator) *time.Time {
+	s := qs.Get(key)
+	if s == "" {
+		return nil
+	}
+
+	t, err := time.Parse(time.DateOnly, s)
+	if err != nil {
+		v.AddError(key, "must be a date in the format YYYY-MM-DD")
+		return nil
+	}
+	return &t
+}
+
+func (app *application) readIDs(qs url.Values, key string, v *validator.Validator) []int64 {
+	ids := []int64{}
+	for _, s := range app.readCSV(qs, key, []string{}) {
+		id, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+		if err != nil {
+			v.AddError(key, "must be a comma separated list of integer ids")
+			return nil
+		}
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// readCount reports whether the client asked for an exact total with
+// count=exact. The default, count=estimate, returns the planner's estimate.
+func (app *application) readCount(qs url.Values, v *validator.Validator) bool {
+	count := app.readString(qs, "count", "estimate")
+	v.Check(validator.PermittedValue(count, "exact", "estimate"), "count", "must be exact or estimate")
+	return count == "exact"
+}
+
+// setPageLinks adds links to the next and previous page to the metadata of a
+// list, keeping the other query parameters of the request.
+func (app *application) setPageLinks(r *http.Request, metadata *database.Metadata) {
+	link := func(key, cursor string) string {
+		qs := r.URL.Query()
+		qs.Del("page")
+		qs.Del("after")
+		qs.Del("before")
+		qs.Set(key, cursor)
+		return r.URL.Path + "?" + qs.Encode()
+	}
+
+	if metadata.NextCursor != "" {
+		metadata.Next = link("after", metadata.NextCursor)
+	}
+	if metadata.PrevCursor != "" {
+		metadata.Prev = link("before", metadata.PrevCursor)
+	}
+}
 
 func (app *application) backgroundJob(fn func()) {
 	app.wg.Add(1)
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/Torkel-Aannestad/OMDB-api/internal/database"
+	"github.com/Torkel-Aannestad/OMDB-api/internal/validator"
+)
+
+// replayedHeaders are the response headers stored with the response to a
+// request with an Idempotency-Key.
+var replayedHeaders = []string{"Content-Type", "Location", "ETag"}
+
+// idempotent lets clients retry a POST safely by sending an Idempotency-Key
+// header. The first request with a key runs the handler and stores its
+// response, and a retry with the same key gets the stored response back with
+// an Idempotent-Replayed header. A key sent again with a different request
+// fails with 422, and while the first request is still running with 409.
+// Server errors are not stored, so that the request can be retried. Keys are
+// scoped to the user and kept for the configured TTL.
+func (app *application) idempotent(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get("Idempotency-Key")
+		if key == "" {
+			next(w, r)
+			return
+		}
+
+		v := validator.New()
+		database.ValidateIdempotencyKey(v, key)
+		if !v.Valid() {
+			app.failedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		// Read one byte past the 1MB limit of readJSON, so that the handler
+		// still rejects a body that is too large.
+		maxBytes := 1_048_576
+		body, err := io.ReadAll(io.LimitReader(r.Body, int64(maxBytes)+1))
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		idempotencyKey := &database.IdempotencyKey{
+			UserID:      app.contextGetUser(r).ID,
+			Key:         key,
+			Fingerprint: fingerprint(r, body),
+		}
+
+		reserved, err := app.models.IdempotencyKeys.Reserve(idempotencyKey, app.config.idempotency.ttl)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+		if !reserved {
+			app.replayResponse(w, r, idempotencyKey)
+			return
+		}
+
+		// Release the key if the handler panics, the panic is recovered and
+		// reported by the panicRecovery middleware.
+		completed := false
+		defer func() {
+			if !completed {
+				app.releaseIdempotencyKey(r, idempotencyKey)
+			}
+		}()
+
+		bw := &bufferedResponseWriter{ResponseWriter: w, status: http.StatusOK}
+		next(bw, r)
+		completed = true
+
+		if bw.status >= http.StatusInternalServerError {
+			app.releaseIdempotencyKey(r, idempotencyKey)
+		} else {
+			idempotencyKey.Status = bw.status
+			idempotencyKey.Header = database.ResponseHeader{}
+			for _, name := range replayedHeaders {
+				if values := w.Header().Values(name); len(values) > 0 {
+					idempotencyKey.Header[name] = values
+				}
+			}
+			idempotencyKey.Body = bw.body.Bytes()
+
+			err = app.models.IdempotencyKeys.Complete(idempotencyKey)
+			if err != nil {
+				app.logError(r, err)
+			}
+		}
+
+		w.WriteHeader(bw.status)
+		w.Write(bw.body.Bytes())
+	}
+}
+
+// replayResponse answers a request whose Idempotency-Key is already in use
+// with the stored response.
+func (app *application) replayResponse(w http.ResponseWriter, r *http.Request, idempotencyKey *database.IdempotencyKey) {
+	stored, err := app.models.IdempotencyKeys.Get(idempotencyKey.UserID, idempotencyKey.Key)
+	if err != nil {
+		// The key was released by a failed request after Reserve.
+		if errors.Is(err, database.ErrRecordNotFound) {
+			app.idempotencyKeyInProgressResponse(w, r)
+		} else {
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	switch {
+	case !bytes.Equal(stored.Fingerprint, idempotencyKey.Fingerprint):
+		app.idempotencyKeyReusedResponse(w, r)
+	case stored.Status == 0:
+		app.idempotencyKeyInProgressResponse(w, r)
+	default:
+		for name, values := range stored.Header {
+			w.Header()[name] = values
+		}
+		w.Header().Set("Idempotent-Replayed", "true")
+		w.WriteHeader(stored.Status)
+		w.Write(stored.Body)
+	}
+}
+
+func (app *application) releaseIdempotencyKey(r *http.Request, idempotencyKey *database.IdempotencyKey) {
+	err := app.models.IdempotencyKeys.Release(idempotencyKey)
+	if err != nil {
+		app.logError(r, err)
+	}
+}
+
+// fingerprint identifies a request by its method, path with query string and
+// body.
+func fingerprint(r *http.Request, body []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte(r.Method + " " + r.URL.RequestURI() + "\n"))
+	h.Write(body)
+	return h.Sum(nil)
+}
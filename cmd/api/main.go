@@ -38,6 +38,9 @@ type Config struct {
 		burst   int
 		enabled bool
 	}
+	idempotency struct {
+		ttl time.Duration
+	}
 	smtp struct {
 		host     string
 		port     int
@@ -82,6 +85,9 @@ func main() {
 	flag.IntVar(&cfg.authLimiter.burst, "auth-limiter-burst", 10, "Rate limiter maximum burst for auth endpoints")
 	flag.BoolVar(&cfg.authLimiter.enabled, "auth-limiter-enabled", true, "Enable auth rate limiter")
 
+	//Idempotency keys
+	flag.DurationVar(&cfg.idempotency.ttl, "idempotency-ttl", 24*time.Hour, "How long the response to a POST with an Idempotency-Key is kept for retries")
+
 	//Mailer sandbox.smtp.mailtrap.io live.smtp.mailtrap.io
 	flag.StringVar(&cfg.smtp.host, "smtp-host", "sandbox.smtp.mailtrap.io", "host")
 	flag.IntVar(&cfg.smtp.port, "smtp-port", 587, "port")
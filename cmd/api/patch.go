@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/Torkel-Aannestad/OMDB-api/internal/patch"
+)
+
+const (
+	mergePatchType = "application/merge-patch+json"
+	jsonPatchType  = "application/json-patch+json"
+)
+
+// patchMediaType returns the media type of a PATCH body that holds a merge
+// patch or a JSON patch, or "" when the body is plain JSON.
+func patchMediaType(r *http.Request) string {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		return ""
+	}
+
+	switch mediaType {
+	case mergePatchType, jsonPatchType:
+		return mediaType
+	default:
+		return ""
+	}
+}
+
+// readPatch applies the merge patch or JSON patch in the request body to
+// record, a pointer to the current version of the record. The patch works on a
+// JSON document of the writable fields of the record, and changing any other
+// field is an error. Fields the patch removes or sets to null are reset to
+// their zero value.
+func (app *application) readPatch(w http.ResponseWriter, r *http.Request, mediaType string, record any, writable ...string) error {
+	maxBytes := 1_048_576
+	r.Body = http.MaxBytesReader(w, r.Body, int64(maxBytes))
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		var maxBytesError *http.MaxBytesError
+		if errors.As(err, &maxBytesError) {
+			return fmt.Errorf("body must not be larger than %d bytes", maxBytesError.Limit)
+		}
+		return err
+	}
+	if len(bytes.TrimSpace(body)) == 0 {
+		return errors.New("body must not be empty")
+	}
+
+	doc, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	doc, err = trimFields(doc, writable)
+	if err != nil {
+		return err
+	}
+
+	switch mediaType {
+	case mergePatchType:
+		doc, err = patch.Merge(doc, body)
+	case jsonPatchType:
+		doc, err = patch.Apply(doc, body)
+	}
+	if err != nil {
+		return err
+	}
+
+	var fields map[string]json.RawMessage
+	err = json.Unmarshal(doc, &fields)
+	if err != nil {
+		return errors.New("patch must leave the record a JSON object")
+	}
+	for name, value := range fields {
+		if !slices.Contains(writable, name) {
+			return fmt.Errorf("patch changes unknown or read-only key %q", name)
+		}
+		if string(value) == "null" {
+			delete(fields, name)
+		}
+	}
+
+	doc, err = json.Marshal(fields)
+	if err != nil {
+		return err
+	}
+
+	zeroFields(reflect.ValueOf(record).Elem(), writable)
+
+	err = json.Unmarshal(doc, record)
+	if err != nil {
+		var unmarshalTypeError *json.UnmarshalTypeError
+		if errors.As(err, &unmarshalTypeError) {
+			return fmt.Errorf("patch sets incorrect JSON type for key %q", unmarshalTypeError.Field)
+		}
+		return err
+	}
+
+	return nil
+}
+
+// zeroFields resets the fields of a struct whose JSON names are in names.
+func zeroFields(v reflect.Value, names []string) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		switch {
+		case name == "" && field.Anonymous && field.Type.Kind() == reflect.Struct:
+			zeroFields(v.Field(i), names)
+		case name == "":
+			name = field.Name
+		}
+		if slices.Contains(names, name) {
+			v.Field(i).SetZero()
+		}
+	}
+}
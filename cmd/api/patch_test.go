@@ -0,0 +1,189 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Torkel-Aannestad/OMDB-api/internal/patch"
+)
+
+func TestPatchMediaType(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		want        string
+	}{
+		{name: "merge patch", contentType: "application/merge-patch+json", want: mergePatchType},
+		{name: "json patch", contentType: "application/json-patch+json", want: jsonPatchType},
+		{name: "parameters", contentType: "application/merge-patch+json; charset=utf-8", want: mergePatchType},
+		{name: "upper case", contentType: "Application/JSON-Patch+JSON", want: jsonPatchType},
+		{name: "plain json", contentType: "application/json", want: ""},
+		{name: "missing", contentType: "", want: ""},
+		{name: "malformed", contentType: "application/merge-patch+json; =", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPatch, "/", nil)
+			if tt.contentType != "" {
+				r.Header.Set("Content-Type", tt.contentType)
+			}
+			if got := patchMediaType(r); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+type patchRecord struct {
+	ID      int64    `json:"id"`
+	Name    string   `json:"name"`
+	Runtime int32    `json:"runtime,omitempty"`
+	Aliases []string `json:"aliases,omitempty"`
+	Version int32    `json:"version"`
+}
+
+func TestReadPatch(t *testing.T) {
+	current := patchRecord{ID: 1, Name: "Alien", Runtime: 117, Aliases: []string{"Alien³"}, Version: 3}
+
+	tests := []struct {
+		name      string
+		mediaType string
+		body      string
+		want      patchRecord
+		wantErr   error
+	}{
+		{
+			name:      "merge patch",
+			mediaType: mergePatchType,
+			body:      `{"name":"Aliens"}`,
+			want:      patchRecord{ID: 1, Name: "Aliens", Runtime: 117, Aliases: []string{"Alien³"}, Version: 3},
+		},
+		{
+			name:      "merge patch removing fields",
+			mediaType: mergePatchType,
+			body:      `{"runtime":null,"aliases":null}`,
+			want:      patchRecord{ID: 1, Name: "Alien", Version: 3},
+		},
+		{
+			name:      "empty merge patch",
+			mediaType: mergePatchType,
+			body:      `{}`,
+			want:      current,
+		},
+		{
+			name:      "merge patch removing a read-only field",
+			mediaType: mergePatchType,
+			body:      `{"id":null}`,
+			want:      current,
+		},
+		{name: "merge patch changing a read-only field", mediaType: mergePatchType, body: `{"version":4}`, wantErr: errAny},
+		{name: "merge patch adding an unknown field", mediaType: mergePatchType, body: `{"title":"Aliens"}`, wantErr: errAny},
+		{name: "merge patch with incorrect type", mediaType: mergePatchType, body: `{"runtime":"long"}`, wantErr: errAny},
+		{name: "merge patch replacing the record", mediaType: mergePatchType, body: `["Aliens"]`, wantErr: errAny},
+		{name: "malformed merge patch", mediaType: mergePatchType, body: `{"name":`, wantErr: errAny},
+		{
+			name:      "json patch",
+			mediaType: jsonPatchType,
+			body:      `[{"op":"replace","path":"/name","value":"Aliens"},{"op":"add","path":"/aliases/-","value":"Aliens²"},{"op":"remove","path":"/runtime"}]`,
+			want:      patchRecord{ID: 1, Name: "Aliens", Aliases: []string{"Alien³", "Aliens²"}, Version: 3},
+		},
+		{
+			name:      "json patch adding an omitted field",
+			mediaType: jsonPatchType,
+			body:      `[{"op":"remove","path":"/runtime"},{"op":"add","path":"/runtime","value":90}]`,
+			want:      patchRecord{ID: 1, Name: "Alien", Runtime: 90, Aliases: []string{"Alien³"}, Version: 3},
+		},
+		{
+			name:      "json patch with passing test",
+			mediaType: jsonPatchType,
+			body:      `[{"op":"test","path":"/name","value":"Alien"},{"op":"replace","path":"/name","value":"Aliens"}]`,
+			want:      patchRecord{ID: 1, Name: "Aliens", Runtime: 117, Aliases: []string{"Alien³"}, Version: 3},
+		},
+		{
+			name:      "json patch with failing test",
+			mediaType: jsonPatchType,
+			body:      `[{"op":"test","path":"/name","value":"Aliens"}]`,
+			wantErr:   patch.ErrTestFailed,
+		},
+		{name: "json patch testing a read-only field", mediaType: jsonPatchType, body: `[{"op":"test","path":"/id","value":1}]`, wantErr: errAny},
+		{name: "json patch adding a read-only field", mediaType: jsonPatchType, body: `[{"op":"add","path":"/id","value":2}]`, wantErr: errAny},
+		{name: "json patch replacing the record", mediaType: jsonPatchType, body: `[{"op":"replace","path":"","value":1}]`, wantErr: errAny},
+		{name: "json patch that is not an array", mediaType: jsonPatchType, body: `{"op":"remove","path":"/name"}`, wantErr: errAny},
+		{name: "empty body", mediaType: mergePatchType, body: ``, wantErr: errAny},
+		{name: "blank body", mediaType: jsonPatchType, body: " \n\t", wantErr: errAny},
+		{name: "body too large", mediaType: mergePatchType, body: `{"name":"` + strings.Repeat("a", 1_048_576) + `"}`, wantErr: errAny},
+	}
+
+	app := &application{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			record := current
+			record.Aliases = append([]string(nil), current.Aliases...)
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodPatch, "/", strings.NewReader(tt.body))
+
+			err := app.readPatch(w, r, tt.mediaType, &record, "name", "runtime", "aliases")
+			if tt.wantErr != nil {
+				if err == nil {
+					t.Fatalf("got %+v, want an error", record)
+				}
+				if tt.wantErr != errAny && !errors.Is(err, tt.wantErr) {
+					t.Errorf("got error %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(record, tt.want) {
+				t.Errorf("got %+v, want %+v", record, tt.want)
+			}
+		})
+	}
+}
+
+func TestZeroFields(t *testing.T) {
+	type Embedded struct {
+		Note string `json:"note"`
+	}
+	type record struct {
+		Embedded
+		ID    int64 `json:"id"`
+		Title string
+		Tags  []string `json:"tags,omitempty"`
+		Skip  string   `json:"-"`
+	}
+	full := record{Embedded: Embedded{Note: "n"}, ID: 1, Title: "t", Tags: []string{"a"}, Skip: "s"}
+
+	tests := []struct {
+		name  string
+		names []string
+		want  record
+	}{
+		{name: "no names", names: nil, want: full},
+		{name: "tagged field", names: []string{"tags"}, want: record{Embedded: Embedded{Note: "n"}, ID: 1, Title: "t", Skip: "s"}},
+		{name: "untagged field", names: []string{"Title"}, want: record{Embedded: Embedded{Note: "n"}, ID: 1, Tags: []string{"a"}, Skip: "s"}},
+		{name: "embedded field", names: []string{"note"}, want: record{ID: 1, Title: "t", Tags: []string{"a"}, Skip: "s"}},
+		{name: "go name of tagged field", names: []string{"ID", "Tags"}, want: full},
+		{name: "unknown name", names: []string{"missing"}, want: full},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := full
+			zeroFields(reflect.ValueOf(&got).Elem(), tt.names)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// errAny marks a test case that must fail with any error.
+var errAny = errors.New("any error")
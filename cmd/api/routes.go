@@ -3,6 +3,7 @@ package main
 import (
 	"net/http"
 
+	"github.com/Torkel-Aannestad/OMDB-api/internal/database"
 	"github.com/julienschmidt/httprouter"
 )
 
@@ -15,58 +16,105 @@ func (app *application) routes() http.Handler {
 
 	router.HandlerFunc(http.MethodGet, "/v1/healthcheck", app.healthcheckHandler)
 
-	router.HandlerFunc(http.MethodGet, "/v1/movies", app.protectedRoute("movies:read", app.listMoviesHandler))
-	router.HandlerFunc(http.MethodPost, "/v1/movies", app.protectedRoute("movies:write", app.createMovieHandler))
-	router.HandlerFunc(http.MethodGet, "/v1/movies/:id", app.protectedRoute("movies:read", app.getMovieHandler))
-	router.HandlerFunc(http.MethodPatch, "/v1/movies/:id", app.protectedRoute("movies:write", app.updateMovieHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/movies", app.protectedRoute("movies:read", app.sparseFields("movies", database.Movie{}, app.listMoviesHandler)))
+	router.HandlerFunc(http.MethodPost, "/v1/movies", app.protectedRoute("movies:write", app.idempotent(app.createMovieHandler)))
+	router.HandlerFunc(http.MethodGet, "/v1/movies/:id", app.protectedRoute("movies:read", app.sparseFields("movie", database.Movie{}, app.getMovieHandler)))
+	router.HandlerFunc(http.MethodPatch, "/v1/movies/:id", app.protectedRoute("movies:write", app.sparseFields("movie", database.Movie{}, app.updateMovieHandler)))
 	router.HandlerFunc(http.MethodDelete, "/v1/movies/:id", app.protectedRoute("movies:write", app.deleteMovieHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/movies/:id/references", app.protectedRoute("movie-references:read", app.noSparseFields(app.getMovieReferenceGraphHandler)))
 
-	router.HandlerFunc(http.MethodGet, "/v1/people", app.protectedRoute("people:read", app.listPeopleHandler))
-	router.HandlerFunc(http.MethodPost, "/v1/people", app.protectedRoute("people:write", app.createPeopleHandler))
-	router.HandlerFunc(http.MethodGet, "/v1/people/:id", app.protectedRoute("people:read", app.getPeopleHandler))
-	router.HandlerFunc(http.MethodPatch, "/v1/people/:id", app.protectedRoute("people:write", app.updatePeopleHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/search", app.protectedRoute("", app.sparseFields("results", database.SearchResult{}, app.searchHandler)))
+	router.HandlerFunc(http.MethodGet, "/v1/search/plots", app.protectedRoute("movies:read", app.sparseFields("results", database.PlotResult{}, app.searchPlotsHandler)))
+	router.HandlerFunc(http.MethodGet, "/v1/autocomplete", app.protectedRoute("", app.sparseFields("suggestions", database.Suggestion{}, app.autocompleteHandler)))
+
+	router.HandlerFunc(http.MethodGet, "/v1/series/:id/seasons", app.protectedRoute("movies:read", app.sparseFields("seasons", database.Season{}, app.getSeriesSeasonsHandler)))
+	router.HandlerFunc(http.MethodGet, "/v1/series/:id/episode-guide", app.protectedRoute("movies:read", app.noSparseFields(app.getEpisodeGuideHandler)))
+	router.HandlerFunc(http.MethodGet, "/v1/seasons/:id/episodes", app.protectedRoute("movies:read", app.sparseFields("episodes", database.Episode{}, app.getSeasonEpisodesHandler)))
+
+	router.HandlerFunc(http.MethodGet, "/v1/people", app.protectedRoute("people:read", app.sparseFields("people", database.Person{}, app.listPeopleHandler)))
+	router.HandlerFunc(http.MethodPost, "/v1/people", app.protectedRoute("people:write", app.idempotent(app.createPeopleHandler)))
+	router.HandlerFunc(http.MethodGet, "/v1/people/:id", app.protectedRoute("people:read", app.sparseFields("person", database.Person{}, app.getPeopleHandler)))
+	router.HandlerFunc(http.MethodPatch, "/v1/people/:id", app.protectedRoute("people:write", app.sparseFields("people", database.Person{}, app.updatePeopleHandler)))
 	router.HandlerFunc(http.MethodDelete, "/v1/people/:id", app.protectedRoute("people:write", app.deletePeopleHandler))
 
-	router.HandlerFunc(http.MethodPost, "/v1/casts", app.protectedRoute("casts:write", app.createCastHandler))
-	router.HandlerFunc(http.MethodGet, "/v1/casts/by-movie-id/:id", app.protectedRoute("casts:read", app.getCastsByMovieIdHandler))
-	router.HandlerFunc(http.MethodGet, "/v1/casts/by-person-id/:id", app.protectedRoute("casts:read", app.getCastsByPersonIdHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/casts", app.protectedRoute("casts:write", app.idempotent(app.createCastHandler)))
+	router.HandlerFunc(http.MethodPost, "/v1/casts/bulk", app.protectedRoute("casts:write", app.idempotent(app.bulkCastsHandler)))
+	router.HandlerFunc(http.MethodGet, "/v1/casts/by-movie-id/:id", app.protectedRoute("casts:read", app.sparseFields("casts", database.Cast{}, app.getCastsByMovieIdHandler)))
+	router.HandlerFunc(http.MethodGet, "/v1/casts/by-person-id/:id", app.protectedRoute("casts:read", app.sparseFields("casts", database.Cast{}, app.getCastsByPersonIdHandler)))
+	router.HandlerFunc(http.MethodGet, "/v1/movies/:id/credits", app.protectedRoute("casts:read", app.noSparseFields(app.getMovieCreditsHandler)))
+	router.HandlerFunc(http.MethodGet, "/v1/people/:id/filmography", app.protectedRoute("casts:read", app.sparseFields("jobs", database.FilmographyJob{}, app.getPersonFilmographyHandler)))
 	router.HandlerFunc(http.MethodPatch, "/v1/casts/:id", app.protectedRoute("casts:write", app.updateCastHandler))
 	router.HandlerFunc(http.MethodDelete, "/v1/casts/:id", app.protectedRoute("casts:write", app.deleteCastHandler))
 
-	router.HandlerFunc(http.MethodPost, "/v1/jobs", app.protectedRoute("jobs:write", app.createJobHandler))
-	router.HandlerFunc(http.MethodGet, "/v1/jobs/:id", app.protectedRoute("jobs:read", app.getJobHandler))
-	router.HandlerFunc(http.MethodPatch, "/v1/jobs/:id", app.protectedRoute("jobs:write", app.updateJobHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/jobs", app.protectedRoute("jobs:write", app.idempotent(app.createJobHandler)))
+	router.HandlerFunc(http.MethodGet, "/v1/jobs", app.protectedRoute("jobs:read", app.sparseFields("jobs", database.Job{}, app.getJobsByIDsHandler)))
+	router.HandlerFunc(http.MethodGet, "/v1/jobs/:id", app.protectedRoute("jobs:read", app.sparseFields("jobs", database.Job{}, app.getJobHandler)))
+	router.HandlerFunc(http.MethodPatch, "/v1/jobs/:id", app.protectedRoute("jobs:write", app.sparseFields("job", database.Job{}, app.updateJobHandler)))
 	router.HandlerFunc(http.MethodDelete, "/v1/jobs/:id", app.protectedRoute("jobs:write", app.deleteJobHandler))
 
-	router.HandlerFunc(http.MethodPost, "/v1/categories", app.protectedRoute("categories:write", app.createCategoryHandler))
-	router.HandlerFunc(http.MethodGet, "/v1/categories/:id", app.protectedRoute("categories:read", app.getCategoryHandler))
-	router.HandlerFunc(http.MethodPatch, "/v1/categories/:id", app.protectedRoute("categories:write", app.updateCategoryHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/categories", app.protectedRoute("categories:read", app.sparseFields("categories", database.Category{}, app.listCategoriesHandler)))
+	router.HandlerFunc(http.MethodPost, "/v1/categories", app.protectedRoute("categories:write", app.idempotent(app.createCategoryHandler)))
+	router.HandlerFunc(http.MethodGet, "/v1/categories/:id", app.protectedRoute("categories:read", app.sparseFields("category", database.Category{}, app.getCategoryHandler)))
+	router.HandlerFunc(http.MethodPatch, "/v1/categories/:id", app.protectedRoute("categories:write", app.sparseFields("category", database.Category{}, app.updateCategoryHandler)))
 	router.HandlerFunc(http.MethodDelete, "/v1/categories/:id", app.protectedRoute("categories:write", app.deleteCategoryHandler))
-
-	router.HandlerFunc(http.MethodPost, "/v1/movie-keywords", app.protectedRoute("category-items:write", app.createMovieKeywordsHandler))
-	router.HandlerFunc(http.MethodGet, "/v1/movie-keywords/:id", app.protectedRoute("category-items:read", app.getMovieKeywordsHandler)) //expects movieId
+	router.HandlerFunc(http.MethodGet, "/v1/categories/:id/children", app.protectedRoute("categories:read", app.sparseFields("categories", database.Category{}, app.getCategoryChildrenHandler)))
+	router.HandlerFunc(http.MethodGet, "/v1/categories/:id/ancestors", app.protectedRoute("categories:read", app.sparseFields("categories", database.Category{}, app.getCategoryAncestorsHandler)))
+	router.HandlerFunc(http.MethodGet, "/v1/categories/:id/descendants", app.protectedRoute("categories:read", app.noSparseFields(app.getCategoryDescendantsHandler)))
+	router.HandlerFunc(http.MethodPost, "/v1/categories/:id/move", app.protectedRoute("categories:admin", app.idempotent(app.moveCategoryHandler)))
+	router.HandlerFunc(http.MethodPost, "/v1/categories/:id/merge", app.protectedRoute("categories:admin", app.idempotent(app.mergeCategoryHandler)))
+
+	router.HandlerFunc(http.MethodPost, "/v1/movie-keywords", app.protectedRoute("category-items:write", app.idempotent(app.createMovieKeywordsHandler)))
+	router.HandlerFunc(http.MethodPost, "/v1/movie-keywords/bulk", app.protectedRoute("category-items:write", app.idempotent(app.bulkMovieKeywordsHandler)))
+	router.HandlerFunc(http.MethodGet, "/v1/movie-keywords/:id", app.protectedRoute("category-items:read", app.sparseFields("movie_keywords", database.CategoryItem{}, app.getMovieKeywordsHandler))) //expects movieId
 	router.HandlerFunc(http.MethodDelete, "/v1/movie-keywords", app.protectedRoute("category-items:write", app.deleteMovieKeywordHandler))
-	router.HandlerFunc(http.MethodPost, "/v1/movie-categories", app.protectedRoute("category-items:write", app.createMovieCategoriesHandler))
-	router.HandlerFunc(http.MethodGet, "/v1/movie-categories/:id", app.protectedRoute("category-items:read", app.getMovieCategoriesHandler)) //expects movieId
+	router.HandlerFunc(http.MethodPost, "/v1/movie-categories", app.protectedRoute("category-items:write", app.idempotent(app.createMovieCategoriesHandler)))
+	router.HandlerFunc(http.MethodPost, "/v1/movie-categories/bulk", app.protectedRoute("category-items:write", app.idempotent(app.bulkMovieCategoriesHandler)))
+	router.HandlerFunc(http.MethodGet, "/v1/movie-categories/:id", app.protectedRoute("category-items:read", app.sparseFields("movie_categories", database.CategoryItem{}, app.getMovieCategoriesHandler))) //expects movieId
 	router.HandlerFunc(http.MethodDelete, "/v1/movie-categories", app.protectedRoute("category-items:write", app.deleteMovieCategoryHandler))
 
-	router.HandlerFunc(http.MethodPost, "/v1/movie-links", app.protectedRoute("movie-links:write", app.createMovieLinkHandler))
-	router.HandlerFunc(http.MethodGet, "/v1/movie-links/:id", app.protectedRoute("movie-links:read", app.getMovieLinksHandler))       //expects movieId
-	router.HandlerFunc(http.MethodDelete, "/v1/movie-links/:id", app.protectedRoute("movie-links:write", app.deleteMovieLinkHandler)) //expects id from movie_links
-
-	router.HandlerFunc(http.MethodPost, "/v1/people-links", app.protectedRoute("people-links:write", app.createPeopleLinkHandler))
-	router.HandlerFunc(http.MethodGet, "/v1/people-links/:id", app.protectedRoute("people-links:read", app.getPeopleLinksHandler))       //expects personId
-	router.HandlerFunc(http.MethodDelete, "/v1/people-links/:id", app.protectedRoute("people-links:write", app.deletePeopleLinkHandler)) //expects id from people_links
-
-	router.HandlerFunc(http.MethodPost, "/v1/trailers", app.protectedRoute("trailers:write", app.createTrailerHandler))
-	router.HandlerFunc(http.MethodGet, "/v1/trailers/:id", app.protectedRoute("trailers:read", app.getTrailersHandler)) //expects movieId
+	router.HandlerFunc(http.MethodPost, "/v1/movie-countries", app.protectedRoute("movie-countries:write", app.idempotent(app.createMovieCountryHandler)))
+	router.HandlerFunc(http.MethodGet, "/v1/movie-countries/:id", app.protectedRoute("movie-countries:read", app.sparseFields("movie_countries", database.MovieCountry{}, app.getMovieCountriesHandler))) //expects movieId
+	router.HandlerFunc(http.MethodDelete, "/v1/movie-countries", app.protectedRoute("movie-countries:write", app.deleteMovieCountryHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/movie-languages", app.protectedRoute("movie-languages:write", app.idempotent(app.createMovieLanguageHandler)))
+	router.HandlerFunc(http.MethodGet, "/v1/movie-languages/:id", app.protectedRoute("movie-languages:read", app.sparseFields("movie_languages", database.MovieLanguage{}, app.getMovieLanguagesHandler))) //expects movieId
+	router.HandlerFunc(http.MethodDelete, "/v1/movie-languages", app.protectedRoute("movie-languages:write", app.deleteMovieLanguageHandler))
+
+	router.HandlerFunc(http.MethodPost, "/v1/movie-links", app.protectedRoute("movie-links:write", app.idempotent(app.createMovieLinkHandler)))
+	router.HandlerFunc(http.MethodGet, "/v1/movie-links/:id", app.protectedRoute("movie-links:read", app.sparseFields("movie_links", database.MovieLink{}, app.getMovieLinksHandler))) //expects movieId
+	router.HandlerFunc(http.MethodDelete, "/v1/movie-links/:id", app.protectedRoute("movie-links:write", app.deleteMovieLinkHandler))                                                  //expects id from movie_links
+
+	router.HandlerFunc(http.MethodPost, "/v1/movie-references", app.protectedRoute("movie-references:write", app.idempotent(app.createMovieReferenceHandler)))
+	router.HandlerFunc(http.MethodGet, "/v1/movie-references/:id", app.protectedRoute("movie-references:read", app.sparseFields("movie_references", database.MovieReference{}, app.getMovieReferencesHandler))) //expects movieId
+	router.HandlerFunc(http.MethodPatch, "/v1/movie-references/:id", app.protectedRoute("movie-references:write", app.updateMovieReferenceHandler))                                                             //expects id from movie_references
+	router.HandlerFunc(http.MethodDelete, "/v1/movie-references/:id", app.protectedRoute("movie-references:write", app.deleteMovieReferenceHandler))                                                            //expects id from movie_references
+
+	router.HandlerFunc(http.MethodPost, "/v1/movie-abstracts", app.protectedRoute("movie-abstracts:write", app.idempotent(app.createMovieAbstractHandler)))
+	router.HandlerFunc(http.MethodGet, "/v1/movie-abstracts/:id", app.protectedRoute("movie-abstracts:read", app.sparseFields("movie_abstracts", database.MovieAbstract{}, app.getMovieAbstractsHandler))) //expects movieId
+	router.HandlerFunc(http.MethodPatch, "/v1/movie-abstracts", app.protectedRoute("movie-abstracts:write", app.updateMovieAbstractHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/movie-abstracts", app.protectedRoute("movie-abstracts:write", app.deleteMovieAbstractHandler))
+
+	router.HandlerFunc(http.MethodPost, "/v1/movie-aliases", app.protectedRoute("movie-aliases:write", app.idempotent(app.createMovieAliasHandler)))
+	router.HandlerFunc(http.MethodGet, "/v1/movie-aliases/:id", app.protectedRoute("movie-aliases:read", app.sparseFields("movie_aliases", database.MovieAlias{}, app.getMovieAliasesHandler))) //expects movieId
+	router.HandlerFunc(http.MethodPatch, "/v1/movie-aliases/:id", app.protectedRoute("movie-aliases:write", app.updateMovieAliasHandler))                                                       //expects id from movie_aliases
+	router.HandlerFunc(http.MethodDelete, "/v1/movie-aliases/:id", app.protectedRoute("movie-aliases:write", app.deleteMovieAliasHandler))                                                      //expects id from movie_aliases
+
+	router.HandlerFunc(http.MethodPost, "/v1/people-links", app.protectedRoute("people-links:write", app.idempotent(app.createPeopleLinkHandler)))
+	router.HandlerFunc(http.MethodGet, "/v1/people-links/:id", app.protectedRoute("people-links:read", app.sparseFields("people_links", database.PeopleLink{}, app.getPeopleLinksHandler))) //expects personId
+	router.HandlerFunc(http.MethodDelete, "/v1/people-links/:id", app.protectedRoute("people-links:write", app.deletePeopleLinkHandler))                                                    //expects id from people_links
+
+	router.HandlerFunc(http.MethodPost, "/v1/trailers", app.protectedRoute("trailers:write", app.idempotent(app.createTrailerHandler)))
+	router.HandlerFunc(http.MethodGet, "/v1/trailers/:id", app.protectedRoute("trailers:read", app.sparseFields("trailers", database.Trailer{}, app.getTrailersHandler))) //expects movieId
 	router.HandlerFunc(http.MethodDelete, "/v1/trailers/:id", app.protectedRoute("trailers:write", app.deleteTrailerHandler))
 
-	router.HandlerFunc(http.MethodPost, "/v1/images", app.protectedRoute("images:write", app.createImageHandler))
-	router.HandlerFunc(http.MethodGet, "/v1/images/:id", app.protectedRoute("images:read", app.getImageHandler))
-	router.HandlerFunc(http.MethodGet, "/v1/images", app.protectedRoute("images:read", app.getImagesObjektIdHandler))
-	router.HandlerFunc(http.MethodPatch, "/v1/images/:id", app.protectedRoute("images:write", app.updateImageHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/images", app.protectedRoute("images:write", app.idempotent(app.createImageHandler)))
+	router.HandlerFunc(http.MethodGet, "/v1/images/:id", app.protectedRoute("images:read", app.sparseFields("image", database.Image{}, app.getImageHandler)))
+	router.HandlerFunc(http.MethodGet, "/v1/images", app.protectedRoute("images:read", app.sparseFields("images", database.Image{}, app.getImagesObjektIdHandler)))
+	router.HandlerFunc(http.MethodPatch, "/v1/images/:id", app.protectedRoute("images:write", app.sparseFields("image", database.Image{}, app.updateImageHandler)))
 	router.HandlerFunc(http.MethodDelete, "/v1/images/:id", app.protectedRoute("images:write", app.deleteImageHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/image-licenses", app.protectedRoute("images:write", app.idempotent(app.createImageLicenseHandler)))
+	router.HandlerFunc(http.MethodGet, "/v1/image-licenses/:id", app.protectedRoute("images:read", app.sparseFields("image_license", database.ImageLicense{}, app.getImageLicenseHandler))) //expects imageId
+	router.HandlerFunc(http.MethodPatch, "/v1/image-licenses/:id", app.protectedRoute("images:write", app.updateImageLicenseHandler))                                                       //expects imageId
+	router.HandlerFunc(http.MethodDelete, "/v1/image-licenses/:id", app.protectedRoute("images:write", app.deleteImageLicenseHandler))                                                      //expects imageId
 
 	router.HandlerFunc(http.MethodPost, "/v1/users", app.registerUserHandler)
 	router.HandlerFunc(http.MethodPut, "/v1/users/activate", app.authRateLimit(app.activateUserHandler))
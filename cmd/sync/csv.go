@@ -0,0 +1,168 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+)
+
+// nullValue is how the OMDB dataset writes NULL.
+const nullValue = `\N`
+
+// unescapeReader converts the backslash escaped quotes used in the OMDB
+// dataset (\") into the doubled quotes ("") expected by encoding/csv. Other
+// backslashes are passed through so that \N can be read as NULL.
+type unescapeReader struct {
+	r       *bufio.Reader
+	pending []byte
+}
+
+func (u *unescapeReader) Read(p []byte) (int, error) {
+	n := 0
+	for n < len(p) {
+		if len(u.pending) > 0 {
+			p[n] = u.pending[0]
+			u.pending = u.pending[1:]
+			n++
+			continue
+		}
+
+		b, err := u.r.ReadByte()
+		if err != nil {
+			if n > 0 {
+				return n, nil
+			}
+			return 0, err
+		}
+
+		if b == '\\' {
+			next, err := u.r.ReadByte()
+			switch {
+			case err != nil:
+				u.pending = append(u.pending, '\\')
+			case next == '"':
+				u.pending = append(u.pending, '"', '"')
+			case next == '\\':
+				u.pending = append(u.pending, '\\')
+			default:
+				u.pending = append(u.pending, '\\', next)
+			}
+			continue
+		}
+
+		p[n] = b
+		n++
+	}
+	return n, nil
+}
+
+// record is a row in a dataset file, values are looked up by column name.
+type record struct {
+	file    string
+	row     int
+	columns map[string]int
+	values  []string
+}
+
+// value returns the value of the first of the named columns found in the file
+// and false when the value is NULL or none of the columns exist.
+func (r record) value(names ...string) (string, bool) {
+	for _, name := range names {
+		if i, found := r.columns[name]; found && i < len(r.values) {
+			if r.values[i] == nullValue {
+				return "", false
+			}
+			return r.values[i], true
+		}
+	}
+	return "", false
+}
+
+func (r record) string(names ...string) string {
+	s, _ := r.value(names...)
+	return s
+}
+
+func (r record) int64(name string) (int64, bool, error) {
+	s, ok := r.value(name)
+	if !ok || s == "" {
+		return 0, false, nil
+	}
+	i, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, false, r.errorf("%s must be an integer", name)
+	}
+	return i, true, nil
+}
+
+func (r record) float64(name string) (float64, bool, error) {
+	s, ok := r.value(name)
+	if !ok || s == "" {
+		return 0, false, nil
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, false, r.errorf("%s must be a number", name)
+	}
+	return f, true, nil
+}
+
+func (r record) time(name string, layout string) (time.Time, bool, error) {
+	s, ok := r.value(name)
+	if !ok || s == "" {
+		return time.Time{}, false, nil
+	}
+	t, err := time.Parse(layout, s)
+	if err != nil {
+		return time.Time{}, false, r.errorf("%s must be a time in the format %s", name, layout)
+	}
+	return t, true, nil
+}
+
+func (r record) errorf(format string, args ...any) error {
+	return fmt.Errorf("%s row %d: %s", r.file, r.row, fmt.Sprintf(format, args...))
+}
+
+// forEachRecord calls fn for every row in the dataset file. The first row must
+// hold the column names.
+func forEachRecord(path string, fn func(record) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	reader := csv.NewReader(&unescapeReader{r: bufio.NewReader(f)})
+	reader.FieldsPerRecord = -1
+	reader.ReuseRecord = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[name] = i
+	}
+
+	for row := 1; ; row++ {
+		values, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+
+		err = fn(record{file: path, row: row, columns: columns, values: values})
+		if err != nil {
+			return err
+		}
+	}
+}
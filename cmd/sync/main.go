@@ -0,0 +1,110 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/Torkel-Aannestad/OMDB-api/internal/database"
+	"github.com/Torkel-Aannestad/OMDB-api/internal/vcs"
+	"github.com/joho/godotenv"
+	_ "github.com/lib/pq"
+)
+
+var (
+	version = vcs.Version()
+)
+
+type Config struct {
+	dataDir string
+	since   string
+	dryRun  bool
+	db      struct {
+		dsn          string
+		maxOpenConns int
+		maxIdleConns int
+		maxIdleTime  time.Duration
+	}
+}
+
+type application struct {
+	config Config
+	logger *slog.Logger
+	models *database.Models
+}
+
+func main() {
+	var cfg Config
+
+	godotenv.Load()
+	dsn := os.Getenv("OMDB_API_DB_DSN_DEV")
+
+	flag.StringVar(&cfg.dataDir, "data-dir", "sql/data-import/data", "directory with the downloaded OMDB CSV files")
+	flag.StringVar(&cfg.since, "since", "", "sync movies updated after this time (2006-01-02 15:04:05), defaults to the watermark of the last sync")
+	flag.BoolVar(&cfg.dryRun, "dry-run", false, "read the dataset and report what would be synced without writing")
+
+	//DB flags
+	flag.StringVar(&cfg.db.dsn, "db-dsn", dsn, "dsn for PG instance")
+	flag.IntVar(&cfg.db.maxOpenConns, "db-max-open-conns", 5, "PostgreSQL max open connections")
+	flag.IntVar(&cfg.db.maxIdleConns, "db-max-idle-conns", 5, "PostgreSQL max idle connections")
+	flag.DurationVar(&cfg.db.maxIdleTime, "db-max-idle-time", 15*time.Minute, "PostgreSQL max connection idle time")
+
+	displayVersion := flag.Bool("version", false, "Display version and exit")
+
+	flag.Parse()
+
+	if *displayVersion {
+		fmt.Printf("Version:\t%s\n", version)
+		os.Exit(0)
+	}
+
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	db, err := database.OpenDB(cfg.db.dsn, cfg.db.maxOpenConns, cfg.db.maxIdleConns, cfg.db.maxIdleTime)
+	if err != nil {
+		logger.Error(err.Error())
+		os.Exit(1)
+	}
+	defer db.Close()
+	logger.Info("database connection pool established")
+
+	app := &application{
+		config: cfg,
+		logger: logger,
+		models: database.NewModels(db),
+	}
+
+	var since time.Time
+	if cfg.since != "" {
+		since, err = time.Parse(timestampLayout, cfg.since)
+		if err != nil {
+			logger.Error("since must be in the format 2006-01-02 15:04:05")
+			os.Exit(1)
+		}
+	} else {
+		since, err = app.models.Sync.GetWatermark()
+		if err != nil {
+			logger.Error(fmt.Sprintf("could not find the watermark of the last sync: %v", err))
+			os.Exit(1)
+		}
+	}
+
+	result, err := app.sync(since)
+	if err != nil {
+		logger.Error(err.Error())
+		os.Exit(1)
+	}
+
+	logger.Info("sync complete",
+		"dry_run", cfg.dryRun,
+		"since", result.Since,
+		"watermark", result.Watermark,
+		"updated", result.Updated,
+		"missing", result.Missing,
+		"movies_synced", result.MoviesSynced,
+		"conflicts", result.Conflicts,
+		"skipped_rows", result.SkippedRows,
+	)
+}
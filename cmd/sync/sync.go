@@ -0,0 +1,445 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/Torkel-Aannestad/OMDB-api/internal/database"
+)
+
+const (
+	dateLayout      = "2006-01-02"
+	timestampLayout = "2006-01-02 15:04:05"
+	// editedBatchSize is the number of movies checked for edits per query.
+	editedBatchSize = 1000
+)
+
+// movieFiles maps each dataset file with movies to the kind of the movies.
+var movieFiles = []struct {
+	file string
+	kind string
+}{
+	{"all_movieseries.csv", "movieseries"},
+	{"all_series.csv", "series"},
+	{"all_movies.csv", "movie"},
+	{"all_seasons.csv", "season"},
+	{"all_episodes.csv", "episode"},
+}
+
+// kindOrder makes sure parents are written before their children, so that
+// parent_id and series_id can reference them.
+var kindOrder = map[string]int{"movieseries": 0, "series": 1, "movie": 2, "season": 3, "episode": 4}
+
+type syncResult struct {
+	Since        time.Time
+	Watermark    time.Time
+	Updated      int
+	Missing      int
+	MoviesSynced int
+	Conflicts    int
+	SkippedRows  int
+}
+
+func (app *application) path(file string) string {
+	return filepath.Join(app.config.dataDir, file)
+}
+
+// sync upserts the movies updated in OMDB after since, together with their
+// casts, categories, keywords and links. Movies created or edited through the
+// api are left alone and recorded as conflicts.
+func (app *application) sync(since time.Time) (syncResult, error) {
+	result := syncResult{Since: since, Watermark: since}
+
+	updates, err := app.readUpdates(since, &result)
+	if err != nil {
+		return result, err
+	}
+	app.logger.Info("movies updated since last sync", "since", since, "watermark", result.Watermark, "movies", len(updates))
+
+	movies, err := app.readMovies(updates)
+	if err != nil {
+		return result, err
+	}
+	result.Missing = len(updates) - len(movies)
+
+	if app.config.dryRun {
+		result.MoviesSynced = len(movies)
+		return result, nil
+	}
+
+	run := database.SyncRun{Since: since, Watermark: result.Watermark}
+	err = app.models.Sync.InsertRun(&run)
+	if err != nil {
+		return result, err
+	}
+
+	err = app.writeMovies(&run, movies, &result)
+	if err == nil && result.MoviesSynced > 0 {
+		app.logger.Info("refreshing abstract terms and movie facet counts")
+		err = app.models.Search.RefreshAbstractTerms()
+		if err == nil {
+			err = app.models.Movies.RefreshFacets()
+		}
+	}
+
+	run.MoviesSynced = result.MoviesSynced
+	run.Conflicts = result.Conflicts
+	run.Status = "completed"
+	if err != nil {
+		run.Status = "failed"
+	}
+
+	finishErr := app.models.Sync.FinishRun(&run)
+	if err != nil {
+		return result, err
+	}
+	return result, finishErr
+}
+
+// readUpdates returns the last update of each movie updated after since and
+// sets the watermark to the latest update seen.
+func (app *application) readUpdates(since time.Time, result *syncResult) (map[int64]time.Time, error) {
+	updates := make(map[int64]time.Time)
+
+	err := forEachRecord(app.path("movie_content_updates.csv"), func(rec record) error {
+		movieID, ok, err := rec.int64("movie_id")
+		if err != nil || !ok {
+			return err
+		}
+		lastUpdate, ok, err := rec.time("last_update", timestampLayout)
+		if err != nil || !ok {
+			return err
+		}
+
+		if lastUpdate.After(since) {
+			updates[movieID] = lastUpdate
+			if lastUpdate.After(result.Watermark) {
+				result.Watermark = lastUpdate
+			}
+		}
+		return nil
+	})
+
+	result.Updated = len(updates)
+	return updates, err
+}
+
+// readMovies reads the updated movies and the rows belonging to them from the
+// dataset. Updated movies that no longer exist in the dataset are left out.
+func (app *application) readMovies(updates map[int64]time.Time) (map[int64]*database.SyncMovie, error) {
+	movies := make(map[int64]*database.SyncMovie, len(updates))
+
+	for _, movieFile := range movieFiles {
+		err := forEachRecord(app.path(movieFile.file), func(rec record) error {
+			id, _, err := rec.int64("id")
+			if err != nil {
+				return err
+			}
+			lastUpdate, found := updates[id]
+			if !found {
+				return nil
+			}
+
+			parentID, hasParent, err := rec.int64("parent_id")
+			if err != nil {
+				return err
+			}
+			seriesID, hasSeries, err := rec.int64("series_id")
+			if err != nil {
+				return err
+			}
+			date, hasDate, err := rec.time("date", dateLayout)
+			if err != nil {
+				return err
+			}
+			if !hasDate {
+				date = time.Date(1888, 1, 1, 0, 0, 0, 0, time.UTC)
+			}
+			name := rec.string("name")
+			if name == "" {
+				name = "Unknown"
+			}
+
+			// values not found in movie_details, all_votes and the english
+			// abstracts get the same defaults as the full import
+			movies[id] = &database.SyncMovie{
+				LastUpdate: lastUpdate,
+				Movie: database.Movie{
+					ID:          id,
+					Name:        name,
+					ParentID:    database.NullInt64{NullInt64: sql.NullInt64{Int64: parentID, Valid: hasParent}},
+					SeriesID:    database.NullInt64{NullInt64: sql.NullInt64{Int64: seriesID, Valid: hasSeries}},
+					Date:        date,
+					Kind:        movieFile.kind,
+					Runtime:     -1,
+					Budget:      -1,
+					Revenue:     -1,
+					VoteAvarage: -1,
+					VoteCount:   -1,
+				},
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err := forEachRecord(app.path("movie_details.csv"), func(rec record) error {
+		movie, err := findMovie(movies, rec)
+		if movie == nil || err != nil {
+			return err
+		}
+		if runtime, ok, err := rec.int64("runtime"); err != nil {
+			return err
+		} else if ok {
+			movie.Movie.Runtime = runtime
+		}
+		if budget, ok, err := rec.float64("budget"); err != nil {
+			return err
+		} else if ok {
+			movie.Movie.Budget = budget
+		}
+		if revenue, ok, err := rec.float64("revenue"); err != nil {
+			return err
+		} else if ok {
+			movie.Movie.Revenue = revenue
+		}
+		movie.Movie.Homepage = rec.string("homepage")
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = forEachRecord(app.path("all_votes.csv"), func(rec record) error {
+		movie, err := findMovie(movies, rec)
+		if movie == nil || err != nil {
+			return err
+		}
+		if voteAverage, ok, err := rec.float64("vote_average"); err != nil {
+			return err
+		} else if ok {
+			movie.Movie.VoteAvarage = voteAverage
+		}
+		if votesCount, ok, err := rec.int64("votes_count"); err != nil {
+			return err
+		} else if ok {
+			movie.Movie.VoteCount = votesCount
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = forEachRecord(app.path("movie_abstracts_en.csv"), func(rec record) error {
+		movie, err := findMovie(movies, rec)
+		if movie == nil || err != nil {
+			return err
+		}
+		movie.Movie.Abstract = rec.string("abstract")
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	people := make(map[int64][]*database.SyncMovie)
+
+	err = forEachRecord(app.path("all_casts.csv"), func(rec record) error {
+		movie, err := findMovie(movies, rec)
+		if movie == nil || err != nil {
+			return err
+		}
+		personID, hasPerson, err := rec.int64("person_id")
+		if err != nil {
+			return err
+		}
+		jobID, hasJob, err := rec.int64("job_id")
+		if err != nil {
+			return err
+		}
+		if !hasPerson || !hasJob {
+			return nil
+		}
+		position, _, err := rec.int64("position")
+		if err != nil {
+			return err
+		}
+
+		movie.Casts = append(movie.Casts, database.Cast{
+			MovieID:  movie.Movie.ID,
+			PersonID: personID,
+			JobID:    jobID,
+			Role:     rec.string("role"),
+			Position: int32(position),
+		})
+		people[personID] = append(people[personID], movie)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = forEachRecord(app.path("all_people.csv"), func(rec record) error {
+		id, _, err := rec.int64("id")
+		if err != nil {
+			return err
+		}
+		castMovies, found := people[id]
+		if !found {
+			return nil
+		}
+
+		person := database.SyncPerson{
+			ID:       id,
+			Name:     rec.string("name"),
+			Birthday: time.Date(1888, 1, 1, 0, 0, 0, 0, time.UTC),
+			Deathday: time.Date(1888, 1, 1, 0, 0, 0, 0, time.UTC),
+			Gender:   99,
+		}
+		if person.Name == "" {
+			person.Name = "Unknown"
+		}
+		if birthday, ok, err := rec.time("birthday", dateLayout); err != nil {
+			return err
+		} else if ok {
+			person.Birthday = birthday
+		}
+		if deathday, ok, err := rec.time("deathday", dateLayout); err != nil {
+			return err
+		} else if ok {
+			person.Deathday = deathday
+		}
+		if gender, ok, err := rec.int64("gender"); err != nil {
+			return err
+		} else if ok {
+			person.Gender = int(gender)
+		}
+
+		for _, movie := range castMovies {
+			movie.People = append(movie.People, person)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for file, add := range map[string]func(*database.SyncMovie, int64){
+		"movie_categories.csv": func(movie *database.SyncMovie, id int64) { movie.Categories = append(movie.Categories, id) },
+		"movie_keywords.csv":   func(movie *database.SyncMovie, id int64) { movie.Keywords = append(movie.Keywords, id) },
+	} {
+		err = forEachRecord(app.path(file), func(rec record) error {
+			movie, err := findMovie(movies, rec)
+			if movie == nil || err != nil {
+				return err
+			}
+			categoryID, ok, err := rec.int64("category_id")
+			if err != nil || !ok {
+				return err
+			}
+			add(movie, categoryID)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = forEachRecord(app.path("movie_links.csv"), func(rec record) error {
+		movie, err := findMovie(movies, rec)
+		if movie == nil || err != nil {
+			return err
+		}
+		movie.Links = append(movie.Links, database.MovieLink{
+			Source:   rec.string("source"),
+			Key:      rec.string("key"),
+			MovieID:  movie.Movie.ID,
+			Language: rec.string("language", "language_iso_639_1"),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return movies, nil
+}
+
+// findMovie returns the movie referenced by the movie_id column of the record,
+// or nil when the movie is not being synced.
+func findMovie(movies map[int64]*database.SyncMovie, rec record) (*database.SyncMovie, error) {
+	movieID, ok, err := rec.int64("movie_id")
+	if err != nil || !ok {
+		return nil, err
+	}
+	return movies[movieID], nil
+}
+
+// writeMovies writes the movies that have not been edited through the api and
+// records a conflict for the others.
+func (app *application) writeMovies(run *database.SyncRun, movies map[int64]*database.SyncMovie, result *syncResult) error {
+	ordered := make([]*database.SyncMovie, 0, len(movies))
+	for _, movie := range movies {
+		ordered = append(ordered, movie)
+	}
+	sort.Slice(ordered, func(i, j int) bool {
+		a, b := ordered[i].Movie, ordered[j].Movie
+		if kindOrder[a.Kind] != kindOrder[b.Kind] {
+			return kindOrder[a.Kind] < kindOrder[b.Kind]
+		}
+		return a.ID < b.ID
+	})
+
+	for start := 0; start < len(ordered); start += editedBatchSize {
+		batch := ordered[start:min(start+editedBatchSize, len(ordered))]
+
+		movieIDs := make([]int64, 0, len(batch))
+		for _, movie := range batch {
+			movieIDs = append(movieIDs, movie.Movie.ID)
+		}
+
+		edited, err := app.models.Sync.GetEditedMovies(movieIDs)
+		if err != nil {
+			return err
+		}
+
+		for _, movie := range batch {
+			if reason, found := edited[movie.Movie.ID]; found {
+				err = app.models.Sync.InsertConflict(run.ID, movie.Movie.ID, movie.LastUpdate, reason)
+				if err != nil {
+					return err
+				}
+				app.logger.Warn("movie edited through the api, skipped", "movie_id", movie.Movie.ID, "reason", reason)
+				result.Conflicts++
+				continue
+			}
+
+			skipped, err := app.models.Sync.UpsertMovie(movie)
+			if errors.Is(err, database.ErrSyncConflict) {
+				err = app.models.Sync.InsertConflict(run.ID, movie.Movie.ID, movie.LastUpdate, "movie edited during the sync")
+				if err != nil {
+					return err
+				}
+				app.logger.Warn("movie edited through the api during the sync, skipped", "movie_id", movie.Movie.ID)
+				result.Conflicts++
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("movie %d: %w", movie.Movie.ID, err)
+			}
+			result.MoviesSynced++
+			result.SkippedRows += skipped
+		}
+
+		app.logger.Info("sync progress", "movies", min(start+editedBatchSize, len(ordered)), "of", len(ordered))
+	}
+
+	return nil
+}
@@ -0,0 +1,353 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Torkel-Aannestad/OMDB-api/internal/validator"
+)
+
+var ErrNoAbstract = errors.New("movie has no abstract")
+
+// textSearchConfigs maps language codes to the text search config with the
+// stemming and stop words of the language. Other languages are searched with
+// the simple config.
+var textSearchConfigs = map[string]string{
+	"da": "danish",
+	"de": "german",
+	"en": "english",
+	"es": "spanish",
+	"fi": "finnish",
+	"fr": "french",
+	"hu": "hungarian",
+	"it": "italian",
+	"nb": "norwegian",
+	"nl": "dutch",
+	"no": "norwegian",
+	"pt": "portuguese",
+	"ro": "romanian",
+	"ru": "russian",
+	"sv": "swedish",
+	"tr": "turkish",
+}
+
+func textSearchConfig(language string) string {
+	if config, found := textSearchConfigs[language]; found {
+		return config
+	}
+	return "simple"
+}
+
+type PlotResult struct {
+	MovieID  int64     `json:"movie_id"`
+	Name     string    `json:"name"`
+	Date     time.Time `json:"date"`
+	Language string    `json:"language"`
+	Score    float64   `json:"score"`
+	Snippet  string    `json:"snippet,omitempty"`
+}
+
+// SearchPlots returns the movies with an abstract in one of the languages
+// matching q, ranked by relevance. q is read as a web search, so quoted
+// phrases and -word are supported. The rank is normalized by the length of
+// the abstract, so that a long abstract does not outrank a short one just by
+// repeating the terms, and scaled to a score from 0 to 1.
+func (m SearchModel) SearchPlots(q string, languages []string, filters Filters) ([]*PlotResult, Metadata, error) {
+	var b queryBuilder
+	terms := b.arg(q)
+
+	matches := []string{}
+	for _, language := range languages {
+		// The config is a constant from textSearchConfigs, which is needed
+		// for the indexes to be used.
+		config := textSearchConfig(language)
+		document := "to_tsvector('" + config + "', abstract)"
+		query := "websearch_to_tsquery('" + config + "', " + terms + ")"
+
+		if language == DefaultAbstractLanguage {
+			matches = append(matches, `
+				SELECT id, '`+DefaultAbstractLanguage+`', '`+config+`', abstract, ts_rank_cd(`+document+`, `+query+`, 1|32)
+				FROM movies
+				WHERE `+document+` @@ `+query)
+			continue
+		}
+		matches = append(matches, `
+				SELECT movie_id, language, '`+config+`', abstract, ts_rank_cd(`+document+`, `+query+`, 1|32)
+				FROM movie_abstracts
+				WHERE language = `+b.arg(language)+` AND `+document+` @@ `+query)
+	}
+
+	// The snippets are made for the rows of the page only.
+	query := `
+		SELECT total, movie_id, name, date, language, score,
+			ts_headline(config::regconfig, abstract, websearch_to_tsquery(config::regconfig, ` + terms + `),
+				'StartSel=<mark>, StopSel=</mark>, MaxFragments=2, MaxWords=20, MinWords=8')
+		FROM (
+			SELECT count(*) OVER() AS total, best.movie_id, movies.name, movies.date, best.language, best.config, best.abstract, best.score
+			FROM (
+				SELECT DISTINCT ON (movie_id) movie_id, language, config, abstract, score
+				FROM (` + strings.Join(matches, "\n\t\t\t\tUNION ALL") + `
+				) AS matches (movie_id, language, config, abstract, score)
+				ORDER BY movie_id, score DESC
+			) best
+			JOIN movies ON movies.id = best.movie_id
+			ORDER BY score DESC, movie_id ASC
+			LIMIT ` + b.arg(filters.limit()) + ` OFFSET ` + b.arg(filters.offset()) + `
+		) page
+		ORDER BY score DESC, movie_id ASC`
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, b.args...)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	results := []*PlotResult{}
+
+	for rows.Next() {
+		var result PlotResult
+
+		err := rows.Scan(
+			&totalRecords,
+			&result.MovieID,
+			&result.Name,
+			&result.Date,
+			&result.Language,
+			&result.Score,
+			&result.Snippet,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		results = append(results, &result)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(filters.Page, filters.PageSize, totalRecords)
+
+	return results, metadata, nil
+}
+
+// similarQueryTerms is the number of the highest weighted terms of the
+// abstract used to find candidates, and similarCandidates the number of
+// candidates compared with the abstract.
+const (
+	similarQueryTerms = 16
+	similarCandidates = 200
+)
+
+// termVector is the TF-IDF vector of an abstract, keyed by term.
+type termVector map[string]float64
+
+// tfidf is the weight of a term found count times in an abstract and in
+// documents of total abstracts. The term frequency is dampened with log so
+// that a repeated term does not dominate.
+func tfidf(count, documents, total int) float64 {
+	if documents < 1 {
+		documents = 1
+	}
+	return (1 + math.Log(float64(count))) * math.Log(1+float64(total)/float64(documents))
+}
+
+func (v termVector) norm() float64 {
+	sum := 0.0
+	for _, weight := range v {
+		sum += weight * weight
+	}
+	return math.Sqrt(sum)
+}
+
+// cosine is the cosine similarity of two vectors, from 0 for no terms in
+// common to 1 for the same terms with the same weights.
+func (v termVector) cosine(other termVector) float64 {
+	norms := v.norm() * other.norm()
+	if norms == 0 {
+		return 0
+	}
+
+	dot := 0.0
+	for term, weight := range v {
+		dot += weight * other[term]
+	}
+	return dot / norms
+}
+
+// SimilarPlots returns up to limit movies with english abstracts similar to
+// the abstract of the movie, most similar first. The abstracts are compared
+// by the cosine similarity of their TF-IDF vectors, with the document
+// frequencies from abstract_terms. Only the candidates sharing the highest
+// weighted terms of the abstract are compared.
+func (m SearchModel) SimilarPlots(movieID int64, limit int) ([]*PlotResult, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	var abstract string
+	err := m.DB.QueryRowContext(ctx, `SELECT abstract FROM movies WHERE id = $1`, movieID).Scan(&abstract)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	if strings.TrimSpace(abstract) == "" {
+		return nil, ErrNoAbstract
+	}
+
+	var total int
+	err = m.DB.QueryRowContext(ctx, `SELECT coalesce((SELECT ndoc FROM abstract_terms WHERE term = ''), 0)`).Scan(&total)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT t.lexeme, coalesce(array_length(t.positions, 1), 1), coalesce(a.ndoc, 1)
+		FROM movies, unnest(to_tsvector('english', movies.abstract)) t
+		LEFT JOIN abstract_terms a ON a.term = t.lexeme
+		WHERE movies.id = $1`
+
+	rows, err := m.DB.QueryContext(ctx, query, movieID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	target := termVector{}
+	for rows.Next() {
+		var term string
+		var count, documents int
+
+		err := rows.Scan(&term, &count, &documents)
+		if err != nil {
+			return nil, err
+		}
+		target[term] = tfidf(count, documents, total)
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+	if len(target) == 0 {
+		return []*PlotResult{}, nil
+	}
+
+	terms := make([]string, 0, len(target))
+	for term := range target {
+		terms = append(terms, term)
+	}
+	sort.Slice(terms, func(i, j int) bool {
+		if target[terms[i]] != target[terms[j]] {
+			return target[terms[i]] > target[terms[j]]
+		}
+		return terms[i] < terms[j]
+	})
+	if len(terms) > similarQueryTerms {
+		terms = terms[:similarQueryTerms]
+	}
+
+	query = `
+		WITH candidates AS (
+			SELECT id, name, date, abstract
+			FROM movies
+			WHERE id <> $1 AND to_tsvector('english', abstract) @@ $2::tsquery
+			ORDER BY ts_rank_cd(to_tsvector('english', abstract), $2::tsquery, 1) DESC, id ASC
+			LIMIT $3
+		)
+		SELECT c.id, c.name, c.date, t.lexeme, coalesce(array_length(t.positions, 1), 1), coalesce(a.ndoc, 1)
+		FROM candidates c, unnest(to_tsvector('english', c.abstract)) t
+		LEFT JOIN abstract_terms a ON a.term = t.lexeme`
+
+	rows, err = m.DB.QueryContext(ctx, query, movieID, tsqueryAny(terms), similarCandidates)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := map[int64]*PlotResult{}
+	vectors := map[int64]termVector{}
+	for rows.Next() {
+		var result PlotResult
+		var term string
+		var count, documents int
+
+		err := rows.Scan(&result.MovieID, &result.Name, &result.Date, &term, &count, &documents)
+		if err != nil {
+			return nil, err
+		}
+
+		if _, found := results[result.MovieID]; !found {
+			result.Language = DefaultAbstractLanguage
+			results[result.MovieID] = &result
+			vectors[result.MovieID] = termVector{}
+		}
+		vectors[result.MovieID][term] = tfidf(count, documents, total)
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	similar := make([]*PlotResult, 0, len(results))
+	for id, result := range results {
+		result.Score = target.cosine(vectors[id])
+		similar = append(similar, result)
+	}
+	sort.Slice(similar, func(i, j int) bool {
+		if similar[i].Score != similar[j].Score {
+			return similar[i].Score > similar[j].Score
+		}
+		return similar[i].MovieID < similar[j].MovieID
+	})
+	if len(similar) > limit {
+		similar = similar[:limit]
+	}
+
+	return similar, nil
+}
+
+// tsqueryAny returns a tsquery matching any of the terms. The terms are
+// lexemes already, so they are quoted to be used as they are.
+func tsqueryAny(terms []string) string {
+	quoted := make([]string, 0, len(terms))
+	for _, term := range terms {
+		term = strings.NewReplacer(`\`, `\\`, `'`, `''`).Replace(term)
+		quoted = append(quoted, "'"+term+"'")
+	}
+	return strings.Join(quoted, " | ")
+}
+
+// RefreshAbstractTerms recomputes the document frequencies of the terms in the
+// english abstracts. It scans every abstract, so it has a longer timeout.
+func (m SearchModel) RefreshAbstractTerms() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, `REFRESH MATERIALIZED VIEW CONCURRENTLY abstract_terms`)
+	return err
+}
+
+func ValidatePlotSearch(v *validator.Validator, q string, similarTo int64, languages []string) {
+	v.Check(q != "" || similarTo != 0, "q", "must be provided unless similar_to is")
+	v.Check(q == "" || similarTo == 0, "similar_to", "must not be used together with q")
+	v.Check(len(q) <= 500, "q", "must not be more than 500 bytes long")
+	v.Check(similarTo >= 0, "similar_to", "must be a positive movie id")
+
+	for _, language := range languages {
+		ValidateLanguageCode(v, "lang", language)
+	}
+}
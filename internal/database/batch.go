@@ -0,0 +1,39 @@
+package database
+
+import (
+	"fmt"
+
+	"github.com/Torkel-Aannestad/OMDB-api/internal/validator"
+)
+
+// MaxBatchIDs is the number of records that can be fetched by id at once.
+const MaxBatchIDs = 100
+
+func ValidateBatchIDs(v *validator.Validator, ids []int64) {
+	v.Check(len(ids) > 0, "ids", "must be provided")
+	v.Check(len(ids) <= MaxBatchIDs, "ids", fmt.Sprintf("must not contain more than %d ids", MaxBatchIDs))
+	v.Check(validator.Unique(ids), "ids", "must not contain duplicate values")
+	for _, id := range ids {
+		v.Check(id > 0, "ids", "must only contain positive ids")
+	}
+}
+
+// inRequestOrder returns the records in the order of ids, and the ids that have
+// no record.
+func inRequestOrder[T any](ids []int64, records []*T, id func(*T) int64) ([]*T, []int64) {
+	byID := make(map[int64]*T, len(records))
+	for _, record := range records {
+		byID[id(record)] = record
+	}
+
+	ordered := make([]*T, 0, len(records))
+	missing := []int64{}
+	for _, id := range ids {
+		if record, found := byID[id]; found {
+			ordered = append(ordered, record)
+		} else {
+			missing = append(missing, id)
+		}
+	}
+	return ordered, missing
+}
@@ -0,0 +1,109 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// MaxBulkRecords is the number of records that can be written in one bulk
+// request.
+const MaxBulkRecords = 1000
+
+// bulkTimeout is used for bulk writes, which copy up to MaxBulkRecords rows.
+var bulkTimeout = 30 * time.Second
+
+// BulkRecord is a record of a bulk request with the line it was read from.
+type BulkRecord[T any] struct {
+	Line   int
+	Record *T
+}
+
+// BulkError holds the errors of the record on a line of a bulk request.
+type BulkError struct {
+	Line   int               `json:"line"`
+	Errors map[string]string `json:"errors"`
+}
+
+// BulkResult reports what a bulk request wrote. Unchanged counts the valid
+// records that were already stored.
+type BulkResult struct {
+	Received  int         `json:"received"`
+	Created   int64       `json:"created"`
+	Updated   int64       `json:"updated"`
+	Unchanged int64       `json:"unchanged"`
+	Errors    []BulkError `json:"errors"`
+}
+
+// copyRows copies rows into table with COPY FROM STDIN.
+func copyRows(ctx context.Context, tx *sql.Tx, table string, columns []string, rows [][]any) error {
+	stmt, err := tx.PrepareContext(ctx, pq.CopyIn(table, columns...))
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, row := range rows {
+		_, err = stmt.ExecContext(ctx, row...)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = stmt.ExecContext(ctx)
+	return err
+}
+
+// bulkErrors runs a query that returns the line, key and message of each
+// record that can not be written, and groups the messages by line. The first
+// message of a key is kept.
+func bulkErrors(ctx context.Context, tx *sql.Tx, query string) ([]BulkError, error) {
+	rows, err := tx.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	errs := []BulkError{}
+	for rows.Next() {
+		var line int
+		var key, message string
+		err = rows.Scan(&line, &key, &message)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(errs) == 0 || errs[len(errs)-1].Line != line {
+			errs = append(errs, BulkError{Line: line, Errors: map[string]string{}})
+		}
+		if _, exists := errs[len(errs)-1].Errors[key]; !exists {
+			errs[len(errs)-1].Errors[key] = message
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return errs, nil
+}
+
+// dropLines removes the records on the lines of errs from a bulk table.
+func dropLines(ctx context.Context, tx *sql.Tx, table string, errs []BulkError) error {
+	lines := make([]int64, len(errs))
+	for i, e := range errs {
+		lines[i] = int64(e.Line)
+	}
+
+	_, err := tx.ExecContext(ctx, `DELETE FROM `+table+` WHERE line = ANY($1)`, pq.Array(lines))
+	return err
+}
+
+// SortBulkErrors orders errs by line.
+func SortBulkErrors(errs []BulkError) {
+	slices.SortStableFunc(errs, func(a, b BulkError) int {
+		return a.Line - b.Line
+	})
+}
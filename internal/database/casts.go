@@ -4,9 +4,11 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/Torkel-Aannestad/OMDB-api/internal/validator"
+	"github.com/lib/pq"
 )
 
 type Cast struct {
@@ -199,6 +201,344 @@ func (m CastsModel) GetByPersonID(personID int64) ([]*Cast, error) {
 	return casts, nil
 }
 
+// Credit is a cast row with the person and job names resolved.
+type Credit struct {
+	CastID     int64  `json:"cast_id"`
+	PersonID   int64  `json:"person_id"`
+	PersonName string `json:"person_name"`
+	JobID      int64  `json:"job_id"`
+	JobName    string `json:"job_name"`
+	Role       string `json:"role"`
+	Position   int32  `json:"position"`
+}
+
+// CreditDepartment holds the credits of a movie for one job department.
+type CreditDepartment struct {
+	ID      int64     `json:"id"`
+	Name    string    `json:"name"`
+	Credits []*Credit `json:"credits"`
+}
+
+// crewDepartment is the department of jobs without a department.
+const crewDepartment = "Crew"
+
+// actorsDepartment is the department listed first in the credits.
+const actorsDepartment = "Actors"
+
+// crewCTE looks up the crew department by the name in $2. Departments are the
+// jobs that are their own department.
+const crewCTE = `
+	WITH crew AS (
+		SELECT id FROM jobs WHERE name = $2 AND department_id = id ORDER BY id LIMIT 1
+	)`
+
+// GetCredits returns the cast and crew of a movie grouped by the department
+// of the job, with the actors first. Credits are ordered by position within
+// each department.
+func (m CastsModel) GetCredits(movieID int64) ([]*CreditDepartment, error) {
+	if movieID < 0 {
+		return nil, ErrRecordNotFound
+	}
+
+	credits, err := m.GetCreditsForMovies([]int64{movieID})
+	if err != nil {
+		return nil, err
+	}
+
+	departments, found := credits[movieID]
+	if !found {
+		ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+		defer cancel()
+
+		var exists bool
+		err = m.DB.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM movies WHERE id = $1)`, movieID).Scan(&exists)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			return nil, ErrRecordNotFound
+		}
+		departments = []*CreditDepartment{}
+	}
+
+	return departments, nil
+}
+
+// GetCreditsForMovies returns the credits of each of the movies in one query,
+// grouped and ordered like GetCredits.
+func (m CastsModel) GetCreditsForMovies(movieIDs []int64) (map[int64][]*CreditDepartment, error) {
+	query := crewCTE + `
+	SELECT
+		c.movie_id,
+		c.id,
+		c.person_id,
+		p.name,
+		c.job_id,
+		j.name,
+		COALESCE(d.id, crew.id, 0),
+		COALESCE(d.name, $2),
+		c.role,
+		c.position
+	FROM casts c
+	JOIN people p ON p.id = c.person_id
+	JOIN jobs j ON j.id = c.job_id
+	LEFT JOIN jobs d ON d.id = j.department_id
+	LEFT JOIN crew ON true
+	WHERE c.movie_id = ANY($1)
+	ORDER BY c.movie_id, COALESCE(d.name, $2) <> $3, COALESCE(d.id, crew.id, 0), c.position, c.id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, pq.Array(movieIDs), crewDepartment, actorsDepartment)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	credits := make(map[int64][]*CreditDepartment)
+
+	for rows.Next() {
+		var movieID int64
+		var credit Credit
+		var department CreditDepartment
+
+		err := rows.Scan(
+			&movieID,
+			&credit.CastID,
+			&credit.PersonID,
+			&credit.PersonName,
+			&credit.JobID,
+			&credit.JobName,
+			&department.ID,
+			&department.Name,
+			&credit.Role,
+			&credit.Position,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		departments := credits[movieID]
+		if len(departments) == 0 || departments[len(departments)-1].ID != department.ID {
+			department.Credits = []*Credit{}
+			departments = append(departments, &department)
+			credits[movieID] = departments
+		}
+		current := departments[len(departments)-1]
+		current.Credits = append(current.Credits, &credit)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return credits, nil
+}
+
+// FilmographyCredit is a cast row of a person with the movie resolved.
+type FilmographyCredit struct {
+	CastID      int64     `json:"cast_id"`
+	MovieID     int64     `json:"movie_id"`
+	Name        string    `json:"name"`
+	Kind        string    `json:"kind"`
+	Date        time.Time `json:"date"`
+	VoteAvarage float64   `json:"vote_average"`
+	VoteCount   int64     `json:"vote_count"`
+	Role        string    `json:"role"`
+	Position    int32     `json:"position"`
+}
+
+// FilmographyJob holds the credits of a person for one job.
+type FilmographyJob struct {
+	JobID   int64                `json:"job_id"`
+	JobName string               `json:"job_name"`
+	Credits []*FilmographyCredit `json:"credits"`
+}
+
+// GetFilmography returns the movies a person is credited for, grouped by job
+// with acting jobs first. Within a job the credits are sorted by the sort
+// column of the filters. Pagination counts credits, so a job can continue on
+// the next page. An empty kind returns all kinds.
+func (m CastsModel) GetFilmography(personID int64, kind string, filters Filters) ([]*FilmographyJob, Metadata, error) {
+	if personID < 0 {
+		return nil, Metadata{}, ErrRecordNotFound
+	}
+
+	var q queryBuilder
+	crew, actors := q.arg(crewDepartment), q.arg(actorsDepartment)
+	q.where("c.person_id = ?", personID)
+	if kind != "" {
+		q.where("m.kind = ?", kind)
+	}
+
+	query := fmt.Sprintf(`
+	WITH crew AS (
+		SELECT id FROM jobs WHERE name = %[1]s AND department_id = id ORDER BY id LIMIT 1
+	)
+	SELECT
+		count(*) OVER(),
+		c.id,
+		c.job_id,
+		j.name,
+		m.id,
+		m.name,
+		m.kind,
+		m.date,
+		m.vote_average,
+		m.votes_count,
+		c.role,
+		c.position
+	FROM casts c
+	JOIN movies m ON m.id = c.movie_id
+	JOIN jobs j ON j.id = c.job_id
+	LEFT JOIN jobs d ON d.id = j.department_id
+	LEFT JOIN crew ON true
+	%[3]s
+	ORDER BY COALESCE(d.name, %[1]s) <> %[2]s, COALESCE(d.id, crew.id, 0), c.job_id, m.%[4]s %[5]s, m.id ASC
+	LIMIT %[6]s OFFSET %[7]s`, crew, actors, q.whereClause(), filters.getSortColumn(), filters.getSortDirection(), q.arg(filters.limit()), q.arg(filters.offset()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, q.args...)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	jobs := []*FilmographyJob{}
+
+	for rows.Next() {
+		var credit FilmographyCredit
+		var job FilmographyJob
+
+		err := rows.Scan(
+			&totalRecords,
+			&credit.CastID,
+			&job.JobID,
+			&job.JobName,
+			&credit.MovieID,
+			&credit.Name,
+			&credit.Kind,
+			&credit.Date,
+			&credit.VoteAvarage,
+			&credit.VoteCount,
+			&credit.Role,
+			&credit.Position,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		if len(jobs) == 0 || jobs[len(jobs)-1].JobID != job.JobID {
+			job.Credits = []*FilmographyCredit{}
+			jobs = append(jobs, &job)
+		}
+		current := jobs[len(jobs)-1]
+		current.Credits = append(current.Credits, &credit)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	if len(jobs) == 0 {
+		var exists bool
+		err = m.DB.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM people WHERE id = $1)`, personID).Scan(&exists)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		if !exists {
+			return nil, Metadata{}, ErrRecordNotFound
+		}
+	}
+
+	metadata := calculateMetadata(filters.Page, filters.PageSize, totalRecords)
+
+	return jobs, metadata, nil
+}
+
+// GetFilmographyForPeople returns the filmography of each of the people in one
+// query, grouped like GetFilmography with the newest movies first.
+func (m CastsModel) GetFilmographyForPeople(personIDs []int64) (map[int64][]*FilmographyJob, error) {
+	query := crewCTE + `
+	SELECT
+		c.person_id,
+		c.id,
+		c.job_id,
+		j.name,
+		m.id,
+		m.name,
+		m.kind,
+		m.date,
+		m.vote_average,
+		m.votes_count,
+		c.role,
+		c.position
+	FROM casts c
+	JOIN movies m ON m.id = c.movie_id
+	JOIN jobs j ON j.id = c.job_id
+	LEFT JOIN jobs d ON d.id = j.department_id
+	LEFT JOIN crew ON true
+	WHERE c.person_id = ANY($1)
+	ORDER BY c.person_id, COALESCE(d.name, $2) <> $3, COALESCE(d.id, crew.id, 0), c.job_id, m.date DESC, m.id ASC`
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, pq.Array(personIDs), crewDepartment, actorsDepartment)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	filmographies := make(map[int64][]*FilmographyJob)
+
+	for rows.Next() {
+		var personID int64
+		var credit FilmographyCredit
+		var job FilmographyJob
+
+		err := rows.Scan(
+			&personID,
+			&credit.CastID,
+			&job.JobID,
+			&job.JobName,
+			&credit.MovieID,
+			&credit.Name,
+			&credit.Kind,
+			&credit.Date,
+			&credit.VoteAvarage,
+			&credit.VoteCount,
+			&credit.Role,
+			&credit.Position,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		jobs := filmographies[personID]
+		if len(jobs) == 0 || jobs[len(jobs)-1].JobID != job.JobID {
+			job.Credits = []*FilmographyCredit{}
+			jobs = append(jobs, &job)
+			filmographies[personID] = jobs
+		}
+		current := jobs[len(jobs)-1]
+		current.Credits = append(current.Credits, &credit)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return filmographies, nil
+}
+
 func (m CastsModel) Update(cast *Cast) error {
 	query := `
 	UPDATE casts
@@ -259,6 +599,145 @@ func (m CastsModel) Delete(id int64) error {
 	return nil
 }
 
+// Bulk writes the casts of a bulk request in one transaction. Casts without an
+// id are inserted and casts with an id replace the stored cast. The records are
+// copied into a temporary table first, so that those referencing a movie,
+// person, job or cast that does not exist, and those repeating the id of an
+// earlier record, are reported by line. A repeated id would otherwise fail
+// the upsert, which can not change a row twice. With
+// bestEffort those records are skipped, otherwise nothing is written and only
+// the errors are returned.
+func (m CastsModel) Bulk(records []BulkRecord[Cast], bestEffort bool) (*BulkResult, error) {
+	result := &BulkResult{Errors: []BulkError{}}
+	if len(records) == 0 {
+		return result, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), bulkTimeout)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+	CREATE TEMPORARY TABLE bulk_casts (
+		line integer NOT NULL,
+		id bigint,
+		movie_id bigint NOT NULL,
+		person_id bigint NOT NULL,
+		job_id bigint NOT NULL,
+		role text NOT NULL,
+		position integer NOT NULL
+	) ON COMMIT DROP`)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := make([][]any, len(records))
+	for i, record := range records {
+		cast := record.Record
+		id := sql.NullInt64{Int64: cast.ID, Valid: cast.ID != 0}
+		rows[i] = []any{record.Line, id, cast.MovieID, cast.PersonID, cast.JobID, cast.Role, cast.Position}
+	}
+	err = copyRows(ctx, tx, "bulk_casts", []string{"line", "id", "movie_id", "person_id", "job_id", "role", "position"}, rows)
+	if err != nil {
+		return nil, err
+	}
+
+	// Lock the casts that are replaced, so that they can not be deleted
+	// before the upsert.
+	_, err = tx.ExecContext(ctx, `SELECT 1 FROM casts WHERE id IN (SELECT id FROM bulk_casts) FOR UPDATE`)
+	if err != nil {
+		return nil, err
+	}
+
+	result.Errors, err = bulkErrors(ctx, tx, `
+	SELECT line, 'id', 'cast does not exist' FROM bulk_casts b
+	WHERE b.id IS NOT NULL AND NOT EXISTS (SELECT 1 FROM casts WHERE id = b.id)
+	UNION ALL
+	SELECT line, 'id', 'must not be repeated in the request' FROM (
+		SELECT line, row_number() OVER (PARTITION BY id ORDER BY line) AS n
+		FROM bulk_casts WHERE id IS NOT NULL
+	) b
+	WHERE b.n > 1
+	UNION ALL
+	SELECT line, 'movie_id', 'movie does not exist' FROM bulk_casts b
+	WHERE NOT EXISTS (SELECT 1 FROM movies WHERE id = b.movie_id)
+	UNION ALL
+	SELECT line, 'person_id', 'person does not exist' FROM bulk_casts b
+	WHERE NOT EXISTS (SELECT 1 FROM people WHERE id = b.person_id)
+	UNION ALL
+	SELECT line, 'job_id', 'job does not exist' FROM bulk_casts b
+	WHERE NOT EXISTS (SELECT 1 FROM jobs WHERE id = b.job_id)
+	ORDER BY line`)
+	if err != nil {
+		return nil, err
+	}
+	if len(result.Errors) > 0 {
+		if !bestEffort {
+			return result, nil
+		}
+		err = dropLines(ctx, tx, "bulk_casts", result.Errors)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	res, err := tx.ExecContext(ctx, `
+	INSERT INTO casts (movie_id, person_id, job_id, role, position)
+	SELECT movie_id, person_id, job_id, role, position
+	FROM bulk_casts
+	WHERE id IS NULL
+	ORDER BY line`)
+	if err != nil {
+		return nil, err
+	}
+	result.Created, err = res.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+
+	var replaced int64
+	err = tx.QueryRowContext(ctx, `SELECT count(*) FROM bulk_casts WHERE id IS NOT NULL`).Scan(&replaced)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err = tx.ExecContext(ctx, `
+	INSERT INTO casts (id, movie_id, person_id, job_id, role, position)
+	SELECT id, movie_id, person_id, job_id, role, position
+	FROM bulk_casts
+	WHERE id IS NOT NULL
+	ON CONFLICT (id) DO UPDATE SET
+		movie_id = EXCLUDED.movie_id,
+		person_id = EXCLUDED.person_id,
+		job_id = EXCLUDED.job_id,
+		role = EXCLUDED.role,
+		position = EXCLUDED.position,
+		modified_at = NOW(),
+		version = casts.version + 1
+	WHERE (casts.movie_id, casts.person_id, casts.job_id, casts.role, casts.position)
+		IS DISTINCT FROM (EXCLUDED.movie_id, EXCLUDED.person_id, EXCLUDED.job_id, EXCLUDED.role, EXCLUDED.position)`)
+	if err != nil {
+		return nil, err
+	}
+	result.Updated, err = res.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	result.Unchanged = replaced - result.Updated
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
 func ValidateCast(v *validator.Validator, cast *Cast) {
 	v.Check(cast.MovieID != 0, "movie_id", "must be provided")
 	v.Check(cast.MovieID > 0, "movie_id", "must be a positive number")
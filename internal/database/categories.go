@@ -4,9 +4,17 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/Torkel-Aannestad/OMDB-api/internal/validator"
+	"github.com/lib/pq"
+)
+
+var (
+	ErrCategoryCycle          = errors.New("category can not be moved below itself")
+	ErrCategoryParentNotFound = errors.New("parent category not found")
 )
 
 type Category struct {
@@ -27,14 +35,18 @@ func (m CategoriesModel) Insert(category *Category) error {
 	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
 	defer cancel()
 
+	// A root category is its own root, like the imported ones, and a
+	// sub-category without root_id gets the root of its parent.
 	query := `
 	INSERT INTO categories (
-		name, 
+		id,
+		name,
 		parent_id,
 		root_id
 	)
-	VALUES ($1, $2, $3)
-	RETURNING id, created_at, modified_at, version`
+	SELECT new.id, $1, $2, COALESCE($3, (SELECT root_id FROM categories WHERE id = $2), new.id)
+	FROM (SELECT nextval(pg_get_serial_sequence('categories', 'id')) AS id) new
+	RETURNING id, root_id, created_at, modified_at, version`
 
 	args := []any{
 		category.Name,
@@ -44,6 +56,7 @@ func (m CategoriesModel) Insert(category *Category) error {
 
 	return m.DB.QueryRowContext(ctx, query, args...).Scan(
 		&category.ID,
+		&category.RootID.NullInt64,
 		&category.CreatedAt,
 		&category.ModifiedAt,
 		&category.Version,
@@ -91,13 +104,67 @@ func (m CategoriesModel) Get(id int64) (*Category, error) {
 	return &category, nil
 }
 
+// GetByIDs returns the categories with the ids in the order of ids, and the
+// ids that were not found.
+func (m CategoriesModel) GetByIDs(ids []int64) ([]*Category, []int64, error) {
+	query := `
+		SELECT 
+			id,
+			name, 
+			parent_id,
+			root_id, 
+			created_at,
+			modified_at,
+			version
+		FROM categories
+		WHERE id = ANY($1)`
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, pq.Array(ids))
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	categories := []*Category{}
+
+	for rows.Next() {
+		var category Category
+
+		err := rows.Scan(
+			&category.ID,
+			&category.Name,
+			&category.ParentID.NullInt64,
+			&category.RootID.NullInt64,
+			&category.CreatedAt,
+			&category.ModifiedAt,
+			&category.Version,
+		)
+		if err != nil {
+			return nil, nil, err
+		}
+		categories = append(categories, &category)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	categories, missing := inRequestOrder(ids, categories, func(category *Category) int64 { return category.ID })
+
+	return categories, missing, nil
+}
+
+// Update writes the name of the category. The parent and root are changed
+// with Move.
 func (m CategoriesModel) Update(category *Category) error {
 	query := `
 	UPDATE categories
 	SET 
 		name = $3,
-		parent_id = $4,
-		root_id = $5,
 		modified_at = NOW(),
 		version = version + 1
 	WHERE id = $1 and version = $2
@@ -107,8 +174,6 @@ func (m CategoriesModel) Update(category *Category) error {
 		&category.ID,
 		&category.Version,
 		&category.Name,
-		&category.ParentID,
-		&category.RootID,
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
@@ -153,4 +218,474 @@ func (m CategoriesModel) Delete(id int64) error {
 func ValidateCategory(v *validator.Validator, category *Category) {
 	v.Check(category.Name != "", "name", "must be provided")
 	v.Check(len(category.Name) <= 500, "name", "must not be more than 500 bytes long")
+	// root_id is set on insert, a root category has its own id as root_id.
+	v.Check(category.ID == 0 || category.RootID.Valid, "root_id", "must not be null")
+}
+
+// CategoryNode is a category with its children, used to return a subtree.
+type CategoryNode struct {
+	Category
+	Children []*CategoryNode `json:"children"`
+}
+
+// CategoryMerge holds the number of rows moved from the merged category to
+// the target category.
+type CategoryMerge struct {
+	Target          *Category `json:"target"`
+	MovieCategories int64     `json:"movie_categories"`
+	MovieKeywords   int64     `json:"movie_keywords"`
+	Children        int64     `json:"children"`
+}
+
+// maxCategoryDepth guards the recursive queries against cycles in the
+// imported data.
+const maxCategoryDepth = 100
+
+// likeEscaper escapes the wildcards of a LIKE pattern, so that a name
+// containing % or _ only matches itself.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// GetAll returns the root categories, or the children of parentID when it is
+// set. When name is given all categories matching the name are searched.
+func (m CategoriesModel) GetAll(name string, parentID NullInt64, filter Filters) ([]*Category, Metadata, error) {
+	sortColumn := filter.getSortColumn()
+	sortDirection := filter.getSortDirection()
+
+	query := fmt.Sprintf(`
+		SELECT count(*) OVER(), id, name, parent_id, root_id, created_at, modified_at, version
+		FROM categories
+		WHERE (name ILIKE '%%' || $1 || '%%' OR $1 = '')
+		AND (parent_id = $4 OR ($4 IS NULL AND ($1 <> '' OR parent_id IS NULL)))
+		ORDER BY %s %s, id ASC
+		LIMIT $2 OFFSET $3
+	`, sortColumn, sortDirection)
+
+	args := []any{likeEscaper.Replace(name), filter.limit(), filter.offset(), parentID.NullInt64}
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	categories := []*Category{}
+
+	for rows.Next() {
+		var category Category
+
+		err = rows.Scan(
+			&totalRecords,
+			&category.ID,
+			&category.Name,
+			&category.ParentID.NullInt64,
+			&category.RootID.NullInt64,
+			&category.CreatedAt,
+			&category.ModifiedAt,
+			&category.Version,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		categories = append(categories, &category)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(filter.Page, filter.PageSize, totalRecords)
+
+	return categories, metadata, nil
+}
+
+// GetAncestors returns the path from the root category down to and including
+// the category, for use as a breadcrumb.
+func (m CategoriesModel) GetAncestors(id int64) ([]*Category, error) {
+	if id < 0 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `
+	WITH RECURSIVE ancestors AS (
+		SELECT id, name, parent_id, root_id, created_at, modified_at, version, 0 AS depth
+		FROM categories
+		WHERE id = $1
+		UNION ALL
+		SELECT c.id, c.name, c.parent_id, c.root_id, c.created_at, c.modified_at, c.version, a.depth + 1
+		FROM categories c
+		JOIN ancestors a ON c.id = a.parent_id
+		WHERE a.depth < $2
+	)
+	SELECT id, name, parent_id, root_id, created_at, modified_at, version
+	FROM ancestors
+	ORDER BY depth DESC`
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, id, maxCategoryDepth)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	categories := []*Category{}
+
+	for rows.Next() {
+		var category Category
+
+		err = rows.Scan(
+			&category.ID,
+			&category.Name,
+			&category.ParentID.NullInt64,
+			&category.RootID.NullInt64,
+			&category.CreatedAt,
+			&category.ModifiedAt,
+			&category.Version,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		categories = append(categories, &category)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(categories) == 0 {
+		return nil, ErrRecordNotFound
+	}
+
+	return categories, nil
+}
+
+// GetDescendants returns the category with its descendants down to maxDepth
+// levels below it as a tree. Children are ordered by name.
+func (m CategoriesModel) GetDescendants(id int64, maxDepth int) (*CategoryNode, error) {
+	if id < 0 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `
+	WITH RECURSIVE descendants AS (
+		SELECT id, name, parent_id, root_id, created_at, modified_at, version, 0 AS depth
+		FROM categories
+		WHERE id = $1
+		UNION ALL
+		SELECT c.id, c.name, c.parent_id, c.root_id, c.created_at, c.modified_at, c.version, d.depth + 1
+		FROM categories c
+		JOIN descendants d ON c.parent_id = d.id
+		WHERE d.depth < $2
+	)
+	SELECT id, name, parent_id, root_id, created_at, modified_at, version
+	FROM descendants
+	ORDER BY depth, name, id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, id, min(maxDepth, maxCategoryDepth))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var root *CategoryNode
+	nodes := make(map[int64]*CategoryNode)
+
+	for rows.Next() {
+		node := CategoryNode{Children: []*CategoryNode{}}
+
+		err = rows.Scan(
+			&node.ID,
+			&node.Name,
+			&node.ParentID.NullInt64,
+			&node.RootID.NullInt64,
+			&node.CreatedAt,
+			&node.ModifiedAt,
+			&node.Version,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		// a category reached twice through a cycle is only added once
+		if _, found := nodes[node.ID]; found {
+			continue
+		}
+		nodes[node.ID] = &node
+
+		if root == nil {
+			root = &node
+			continue
+		}
+		if parent, found := nodes[node.ParentID.Int64]; found {
+			parent.Children = append(parent.Children, &node)
+		}
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	if root == nil {
+		return nil, ErrRecordNotFound
+	}
+
+	return root, nil
+}
+
+// inSubtree reports whether candidateID is the category id or one of its
+// descendants.
+func inSubtree(ctx context.Context, tx *sql.Tx, id, candidateID int64) (bool, error) {
+	query := `
+	WITH RECURSIVE subtree AS (
+		SELECT id FROM categories WHERE id = $1
+		UNION
+		SELECT c.id FROM categories c JOIN subtree s ON c.parent_id = s.id
+	)
+	SELECT EXISTS (SELECT 1 FROM subtree WHERE id = $2)`
+
+	var found bool
+	err := tx.QueryRowContext(ctx, query, id, candidateID).Scan(&found)
+	return found, err
+}
+
+// lockCategory returns the root of the category and locks the row for the
+// rest of the transaction. A root category has its own id as root_id.
+func lockCategory(ctx context.Context, tx *sql.Tx, id int64) (int64, error) {
+	var rootID int64
+	err := tx.QueryRowContext(ctx, `SELECT root_id FROM categories WHERE id = $1 FOR UPDATE`, id).Scan(&rootID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, ErrRecordNotFound
+		}
+		return 0, err
+	}
+	return rootID, nil
+}
+
+// setSubtreeRoot sets root_id on the descendants of the category, not
+// including the category itself.
+func setSubtreeRoot(ctx context.Context, tx *sql.Tx, id, rootID int64) error {
+	query := `
+	WITH RECURSIVE subtree AS (
+		SELECT id FROM categories WHERE parent_id = $1
+		UNION
+		SELECT c.id FROM categories c JOIN subtree s ON c.parent_id = s.id
+	)
+	UPDATE categories
+	SET
+		root_id = $2,
+		modified_at = NOW(),
+		version = version + 1
+	WHERE id IN (SELECT id FROM subtree) AND root_id IS DISTINCT FROM $2`
+
+	_, err := tx.ExecContext(ctx, query, id, rootID)
+	return err
+}
+
+// Move moves the category and its descendants below parentID, or makes it a
+// root category when parentID is null. root_id is rewritten for the whole
+// subtree. ErrCategoryCycle is returned when the new parent is the category
+// itself or one of its descendants, and ErrCategoryParentNotFound when the
+// parent does not exist.
+func (m CategoriesModel) Move(id int64, parentID NullInt64) (*Category, error) {
+	if id < 0 {
+		return nil, ErrRecordNotFound
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	_, err = lockCategory(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	rootID := id
+	if parentID.Valid {
+		cycle, err := inSubtree(ctx, tx, id, parentID.Int64)
+		if err != nil {
+			return nil, err
+		}
+		if cycle {
+			return nil, ErrCategoryCycle
+		}
+
+		rootID, err = lockCategory(ctx, tx, parentID.Int64)
+		if err != nil {
+			if errors.Is(err, ErrRecordNotFound) {
+				return nil, ErrCategoryParentNotFound
+			}
+			return nil, err
+		}
+	}
+
+	query := `
+	UPDATE categories
+	SET
+		parent_id = $2,
+		root_id = $3,
+		modified_at = NOW(),
+		version = version + 1
+	WHERE id = $1
+	RETURNING id, name, parent_id, root_id, created_at, modified_at, version`
+
+	var category Category
+
+	err = tx.QueryRowContext(ctx, query, id, parentID.NullInt64, rootID).Scan(
+		&category.ID,
+		&category.Name,
+		&category.ParentID.NullInt64,
+		&category.RootID.NullInt64,
+		&category.CreatedAt,
+		&category.ModifiedAt,
+		&category.Version,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	err = setSubtreeRoot(ctx, tx, id, rootID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return &category, nil
+}
+
+// Merge merges the category into the target category. Movies tagged with the
+// category are tagged with the target instead, children of the category are
+// moved below the target and the category is deleted. ErrCategoryCycle is
+// returned when the target is the category itself or one of its descendants.
+func (m CategoriesModel) Merge(id, targetID int64) (*CategoryMerge, error) {
+	if id < 0 {
+		return nil, ErrRecordNotFound
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	_, err = lockCategory(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	cycle, err := inSubtree(ctx, tx, id, targetID)
+	if err != nil {
+		return nil, err
+	}
+	if cycle {
+		return nil, ErrCategoryCycle
+	}
+
+	rootID, err := lockCategory(ctx, tx, targetID)
+	if err != nil {
+		if errors.Is(err, ErrRecordNotFound) {
+			return nil, ErrCategoryParentNotFound
+		}
+		return nil, err
+	}
+
+	var merge CategoryMerge
+
+	for table, count := range map[string]*int64{"movie_categories": &merge.MovieCategories, "movie_keywords": &merge.MovieKeywords} {
+		// rows for movies already tagged with the target are removed when the
+		// category is deleted
+		query := fmt.Sprintf(`
+		UPDATE %[1]s t
+		SET
+			category_id = $2,
+			modified_at = NOW()
+		WHERE t.category_id = $1
+		AND NOT EXISTS (SELECT 1 FROM %[1]s o WHERE o.movie_id = t.movie_id AND o.category_id = $2)`, table)
+
+		result, err := tx.ExecContext(ctx, query, id, targetID)
+		if err != nil {
+			return nil, err
+		}
+		*count, err = result.RowsAffected()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = setSubtreeRoot(ctx, tx, id, rootID)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := tx.ExecContext(ctx, `
+	UPDATE categories
+	SET
+		parent_id = $2,
+		modified_at = NOW(),
+		version = version + 1
+	WHERE parent_id = $1`, id, targetID)
+	if err != nil {
+		return nil, err
+	}
+	merge.Children, err = result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM categories WHERE id = $1`, id)
+	if err != nil {
+		return nil, err
+	}
+
+	var target Category
+
+	err = tx.QueryRowContext(ctx, `
+	SELECT id, name, parent_id, root_id, created_at, modified_at, version
+	FROM categories
+	WHERE id = $1`, targetID).Scan(
+		&target.ID,
+		&target.Name,
+		&target.ParentID.NullInt64,
+		&target.RootID.NullInt64,
+		&target.CreatedAt,
+		&target.ModifiedAt,
+		&target.Version,
+	)
+	if err != nil {
+		return nil, err
+	}
+	merge.Target = &target
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return &merge, nil
 }
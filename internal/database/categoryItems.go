@@ -6,6 +6,9 @@ import (
 	"errors"
 	"fmt"
 	"time"
+
+	"github.com/Torkel-Aannestad/OMDB-api/internal/validator"
+	"github.com/lib/pq"
 )
 
 var errCategoryItemTableName = errors.New("table value must be movie_keywords or movie_categories")
@@ -129,3 +132,162 @@ func (m CategoryItemsModel) Delete(movieID, categoryID int64, tableName string)
 	}
 	return nil
 }
+
+// GetCategoriesForMovies returns the categories or keywords of each of the
+// movies in one query, depending on tableName.
+func (m CategoryItemsModel) GetCategoriesForMovies(movieIDs []int64, tableName string) (map[int64][]*Category, error) {
+	err := categoryTableNameValidation(tableName)
+	if err != nil {
+		return nil, err
+	}
+	query := fmt.Sprintf(`
+		SELECT 
+			i.movie_id,
+			c.id,
+			c.name,
+			c.parent_id,
+			c.root_id,
+			c.version
+		FROM %v i
+		JOIN categories c ON c.id = i.category_id
+		WHERE i.movie_id = ANY($1)
+		ORDER BY c.name, c.id`, tableName)
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, pq.Array(movieIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	categories := make(map[int64][]*Category)
+
+	for rows.Next() {
+		var movieID int64
+		var category Category
+
+		err := rows.Scan(
+			&movieID,
+			&category.ID,
+			&category.Name,
+			&category.ParentID.NullInt64,
+			&category.RootID.NullInt64,
+			&category.Version,
+		)
+		if err != nil {
+			return nil, err
+		}
+		categories[movieID] = append(categories[movieID], &category)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return categories, nil
+}
+
+// Bulk links the movies and categories of a bulk request in one transaction.
+// Links that are already stored are left as they are. Records referencing a
+// movie or category that does not exist are reported by line. With bestEffort
+// those records are skipped, otherwise nothing is written and only the errors
+// are returned.
+func (m CategoryItemsModel) Bulk(records []BulkRecord[CategoryItem], tableName string, bestEffort bool) (*BulkResult, error) {
+	err := categoryTableNameValidation(tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &BulkResult{Errors: []BulkError{}}
+	if len(records) == 0 {
+		return result, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), bulkTimeout)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+	CREATE TEMPORARY TABLE bulk_category_items (
+		line integer NOT NULL,
+		movie_id bigint NOT NULL,
+		category_id bigint NOT NULL
+	) ON COMMIT DROP`)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := make([][]any, len(records))
+	for i, record := range records {
+		rows[i] = []any{record.Line, record.Record.MovieId, record.Record.CategoryId}
+	}
+	err = copyRows(ctx, tx, "bulk_category_items", []string{"line", "movie_id", "category_id"}, rows)
+	if err != nil {
+		return nil, err
+	}
+
+	result.Errors, err = bulkErrors(ctx, tx, `
+	SELECT line, 'movie_id', 'movie does not exist' FROM bulk_category_items b
+	WHERE NOT EXISTS (SELECT 1 FROM movies WHERE id = b.movie_id)
+	UNION ALL
+	SELECT line, 'category_id', 'category does not exist' FROM bulk_category_items b
+	WHERE NOT EXISTS (SELECT 1 FROM categories WHERE id = b.category_id)
+	ORDER BY line`)
+	if err != nil {
+		return nil, err
+	}
+	if len(result.Errors) > 0 {
+		if !bestEffort {
+			return result, nil
+		}
+		err = dropLines(ctx, tx, "bulk_category_items", result.Errors)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var valid int64
+	err = tx.QueryRowContext(ctx, `SELECT count(*) FROM bulk_category_items`).Scan(&valid)
+	if err != nil {
+		return nil, err
+	}
+
+	query := fmt.Sprintf(`
+	INSERT INTO %v (movie_id, category_id)
+	SELECT DISTINCT movie_id, category_id
+	FROM bulk_category_items
+	ON CONFLICT (movie_id, category_id) DO NOTHING`, tableName)
+
+	res, err := tx.ExecContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	result.Created, err = res.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	result.Unchanged = valid - result.Created
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func ValidateCategoryItem(v *validator.Validator, categoryItem *CategoryItem) {
+	v.Check(categoryItem.MovieId != 0, "movie_id", "must be provided")
+	v.Check(categoryItem.MovieId > 0, "movie_id", "must be a positive number")
+
+	v.Check(categoryItem.CategoryId != 0, "category_id", "must be provided")
+	v.Check(categoryItem.CategoryId > 0, "category_id", "must be a positive number")
+}
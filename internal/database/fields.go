@@ -0,0 +1,47 @@
+package database
+
+import (
+	"slices"
+	"strings"
+)
+
+// Fields holds the JSON fields a client selected with ?fields=. An empty set
+// selects every field.
+type Fields []string
+
+func (f Fields) Include(field string) bool {
+	return len(f) == 0 || slices.Contains(f, field)
+}
+
+// column is a column a query can leave out. fields are the JSON fields filled
+// from it, and dest returns where to scan it. A column without fields is always
+// selected.
+type column[T any] struct {
+	expr   string
+	fields []string
+	dest   func(*T) any
+}
+
+// selectColumns returns the select list of the columns needed for the fields,
+// and a function returning the scan destinations of a row in the same order.
+func selectColumns[T any](columns []column[T], fields Fields) (string, func(*T) []any) {
+	var selected []column[T]
+	var exprs []string
+
+	for _, c := range columns {
+		if len(c.fields) == 0 || slices.ContainsFunc(c.fields, fields.Include) {
+			selected = append(selected, c)
+			exprs = append(exprs, c.expr)
+		}
+	}
+
+	dests := func(row *T) []any {
+		dest := make([]any, len(selected))
+		for i, c := range selected {
+			dest[i] = c.dest(row)
+		}
+		return dest
+	}
+
+	return strings.Join(exprs, ", "), dests
+}
@@ -1,6 +1,13 @@
 package database
 
 import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
 	"strings"
 
 	"github.com/Torkel-Aannestad/OMDB-api/internal/validator"
@@ -11,6 +18,47 @@ type Filters struct {
 	PageSize     int
 	Sort         string
 	SortSafelist []string
+	// After and Before hold a cursor from the metadata of a previous page.
+	// When one is set the list is paginated by keyset instead of page number.
+	After  string
+	Before string
+	// ExactCount counts all matching rows for the metadata. Otherwise the
+	// total is the query planner's estimate, which is far cheaper on big
+	// tables.
+	ExactCount bool
+}
+
+// cursor identifies a row in a list by the value of the sort column and the
+// id, which breaks ties. Value is nil when the sort column is NULL.
+type cursor struct {
+	Sort  string  `json:"s"`
+	Value *string `json:"v"`
+	ID    int64   `json:"id"`
+}
+
+func encodeCursor(c cursor) string {
+	js, err := json.Marshal(c)
+	if err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(js)
+}
+
+func decodeCursor(s string) (cursor, error) {
+	js, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return cursor{}, err
+	}
+
+	var c cursor
+	err = json.Unmarshal(js, &c)
+	if err != nil {
+		return cursor{}, err
+	}
+	if c.ID < 1 {
+		return cursor{}, errors.New("invalid cursor id")
+	}
+	return c, nil
 }
 
 func ValidateFilters(v *validator.Validator, f Filters) {
@@ -19,6 +67,26 @@ func ValidateFilters(v *validator.Validator, f Filters) {
 	v.Check(f.PageSize > 0, "page_size", "must be greater than zero")
 	v.Check(f.PageSize <= 100, "page_size", "must be a maximum of 100")
 	v.Check(validator.PermittedValue(f.Sort, f.SortSafelist...), "sort", "invalid sort value")
+
+	if f.keysetMode() {
+		v.Check(f.After == "" || f.Before == "", "before", "must not be used together with after")
+		v.Check(f.Page == 1, "page", "must not be used together with after or before")
+	}
+	for key, value := range map[string]string{"after": f.After, "before": f.Before} {
+		if value == "" {
+			continue
+		}
+		c, err := decodeCursor(value)
+		if err != nil {
+			v.AddError(key, "must be a cursor from the metadata of a previous page")
+			continue
+		}
+		v.Check(c.Sort == f.Sort, key, "cursor does not match the sort parameter")
+	}
+}
+
+func (f Filters) keysetMode() bool {
+	return f.After != "" || f.Before != ""
 }
 
 func (f Filters) getSortColumn() string {
@@ -37,31 +105,163 @@ func (f Filters) getSortDirection() string {
 	return "ASC"
 }
 
+// keyset adds the conditions selecting the rows after or before the cursor to
+// the query and returns the ORDER BY clause. Rows with a NULL sort value come
+// last in both directions. Before a cursor the rows are selected in reverse
+// order, and paginate puts them back in order.
+func (f Filters) keyset(q *queryBuilder, table string) string {
+	column := table + "." + f.getSortColumn()
+	id := table + ".id"
+
+	greater, less := ">", "<"
+	direction, reverse := "ASC", "DESC"
+	if f.getSortDirection() == "DESC" {
+		greater, less = less, greater
+		direction, reverse = reverse, direction
+	}
+
+	if f.After != "" {
+		c, err := decodeCursor(f.After)
+		if err != nil {
+			panic("unsafe cursor: " + err.Error())
+		}
+		if c.Value != nil {
+			q.where(fmt.Sprintf("%s %s ? OR (%s = ? AND %s > ?) OR %s IS NULL", column, greater, column, id, column), *c.Value, *c.Value, c.ID)
+		} else {
+			q.where(fmt.Sprintf("%s IS NULL AND %s > ?", column, id), c.ID)
+		}
+	}
+
+	if f.Before != "" {
+		c, err := decodeCursor(f.Before)
+		if err != nil {
+			panic("unsafe cursor: " + err.Error())
+		}
+		if c.Value != nil {
+			q.where(fmt.Sprintf("%s %s ? OR (%s = ? AND %s < ?)", column, less, column, id), *c.Value, *c.Value, c.ID)
+		} else {
+			q.where(fmt.Sprintf("%s IS NOT NULL OR %s < ?", column, id), c.ID)
+		}
+		return fmt.Sprintf("%s %s NULLS FIRST, %s DESC", column, reverse, id)
+	}
+
+	return fmt.Sprintf("%s %s NULLS LAST, %s ASC", column, direction, id)
+}
+
 func (f Filters) limit() int {
 	return f.PageSize
 }
 
 func (f Filters) offset() int {
+	if f.keysetMode() {
+		return 0
+	}
 	return (f.Page - 1) * f.PageSize
 }
 
+// countRecords returns the number of rows in table matching the conditions
+// of the query. Unless the client asked for an exact count the planner's
+// estimate is returned.
+func countRecords(ctx context.Context, db *sql.DB, f Filters, table string, q queryBuilder) (int, error) {
+	if f.ExactCount {
+		var total int
+		err := db.QueryRowContext(ctx, "SELECT count(*) FROM "+table+" "+q.whereClause(), q.args...).Scan(&total)
+		return total, err
+	}
+
+	var js []byte
+	err := db.QueryRowContext(ctx, "EXPLAIN (FORMAT JSON) SELECT 1 FROM "+table+" "+q.whereClause(), q.args...).Scan(&js)
+	if err != nil {
+		return 0, err
+	}
+
+	var explain []struct {
+		Plan struct {
+			Rows float64 `json:"Plan Rows"`
+		}
+	}
+	err = json.Unmarshal(js, &explain)
+	if err != nil {
+		return 0, err
+	}
+	if len(explain) == 0 {
+		return 0, errors.New("empty query plan")
+	}
+	return int(explain[0].Plan.Rows), nil
+}
+
+// paginate returns the rows of a list query built with keyset together with
+// the metadata of the page. The query must fetch one row more than the page
+// size to tell whether there are more rows. keys holds the sort value and id
+// of each row.
+func paginate[T any](f Filters, rows []T, keys []cursor, totalRecords int) ([]T, Metadata) {
+	for i := range keys {
+		keys[i].Sort = f.Sort
+	}
+
+	more := len(rows) > f.PageSize
+	if more {
+		rows, keys = rows[:f.PageSize], keys[:f.PageSize]
+	}
+	if f.Before != "" {
+		slices.Reverse(rows)
+		slices.Reverse(keys)
+	}
+
+	metadata := Metadata{PageSize: f.PageSize}
+	if !f.keysetMode() {
+		metadata.CurrentPage = f.Page
+		metadata.FirstPage = 1
+	}
+	if f.ExactCount {
+		metadata.TotalRecords = totalRecords
+		if !f.keysetMode() {
+			metadata.LastPage = (totalRecords + f.PageSize - 1) / f.PageSize
+		}
+	} else {
+		metadata.EstimatedRecords = totalRecords
+	}
+
+	if len(rows) == 0 {
+		return rows, metadata
+	}
+	if more || f.Before != "" {
+		metadata.NextCursor = encodeCursor(keys[len(keys)-1])
+		metadata.HasMore = true
+	}
+	if (!f.keysetMode() && f.Page > 1) || (f.Before != "" && more) || f.After != "" {
+		metadata.PrevCursor = encodeCursor(keys[0])
+	}
+	return rows, metadata
+}
+
 type Metadata struct {
 	CurrentPage  int `json:"current_page,omitempty"`
 	PageSize     int `json:"page_size,omitempty"`
 	FirstPage    int `json:"first_page,omitempty"`
 	LastPage     int `json:"last_page,omitempty"`
 	TotalRecords int `json:"total_records,omitempty"`
+	// EstimatedRecords is set instead of TotalRecords when the total is
+	// estimated.
+	EstimatedRecords int    `json:"estimated_records,omitempty"`
+	HasMore          bool   `json:"has_more,omitempty"`
+	NextCursor       string `json:"next_cursor,omitempty"`
+	PrevCursor       string `json:"prev_cursor,omitempty"`
+	Next             string `json:"next,omitempty"`
+	Prev             string `json:"prev,omitempty"`
 }
 
 func calculateMetadata(page, pageSize, totalRecords int) Metadata {
 	if totalRecords == 0 {
 		return Metadata{}
 	}
+	lastPage := (totalRecords + pageSize - 1) / pageSize
 	return Metadata{
+		HasMore:      page < lastPage,
 		CurrentPage:  page,
 		PageSize:     pageSize,
 		FirstPage:    1,
-		LastPage:     (totalRecords + pageSize - 1) / pageSize,
+		LastPage:     lastPage,
 		TotalRecords: totalRecords,
 	}
 }
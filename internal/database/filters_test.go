@@ -0,0 +1,325 @@
+package database
+
+import (
+	"encoding/base64"
+	"reflect"
+	"testing"
+
+	"github.com/Torkel-Aannestad/OMDB-api/internal/validator"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	str := func(s string) *string { return &s }
+
+	tests := []struct {
+		name string
+		c    cursor
+	}{
+		{name: "value", c: cursor{Sort: "title", Value: str("Alien"), ID: 348}},
+		{name: "descending sort", c: cursor{Sort: "-date", Value: str("1979-05-25"), ID: 348}},
+		{name: "null value", c: cursor{Sort: "budget", ID: 1}},
+		{name: "empty value", c: cursor{Sort: "title", Value: str(""), ID: 2}},
+		{name: "value needing escapes", c: cursor{Sort: "title", Value: str(`"Ah!" ½/?&=`), ID: 3}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := encodeCursor(tt.c)
+			got, err := decodeCursor(s)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.c) {
+				t.Errorf("got %+v, want %+v", got, tt.c)
+			}
+		})
+	}
+}
+
+func TestDecodeCursorInvalid(t *testing.T) {
+	encode := func(s string) string { return base64.RawURLEncoding.EncodeToString([]byte(s)) }
+
+	tests := []struct {
+		name string
+		s    string
+	}{
+		{name: "empty", s: ""},
+		{name: "not base64", s: "not a cursor!"},
+		{name: "padded base64", s: base64.URLEncoding.EncodeToString([]byte(`{"s":"title","v":"a","id":1}`))},
+		{name: "not json", s: encode("title:1")},
+		{name: "wrong types", s: encode(`{"s":"title","v":1,"id":"1"}`)},
+		{name: "missing id", s: encode(`{"s":"title","v":"a"}`)},
+		{name: "zero id", s: encode(`{"s":"title","v":"a","id":0}`)},
+		{name: "negative id", s: encode(`{"s":"title","v":"a","id":-1}`)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodeCursor(tt.s)
+			if err == nil {
+				t.Errorf("got %+v, want an error", got)
+			}
+		})
+	}
+}
+
+func TestValidateFiltersCursors(t *testing.T) {
+	safelist := []string{"id", "title", "-id", "-title"}
+	titleCursor := encodeCursor(cursor{Sort: "title", ID: 1})
+
+	tests := []struct {
+		name       string
+		f          Filters
+		wantErrors []string
+	}{
+		{name: "page", f: Filters{Page: 3}},
+		{name: "after", f: Filters{Page: 1, After: titleCursor}},
+		{name: "before", f: Filters{Page: 1, Before: titleCursor}},
+		{name: "after and before", f: Filters{Page: 1, After: titleCursor, Before: titleCursor}, wantErrors: []string{"before"}},
+		{name: "cursor and page", f: Filters{Page: 2, After: titleCursor}, wantErrors: []string{"page"}},
+		{name: "malformed after", f: Filters{Page: 1, After: "abc!"}, wantErrors: []string{"after"}},
+		{name: "malformed before", f: Filters{Page: 1, Before: "abc"}, wantErrors: []string{"before"}},
+		{
+			name:       "cursor for another sort",
+			f:          Filters{Page: 1, After: encodeCursor(cursor{Sort: "-title", ID: 1})},
+			wantErrors: []string{"after"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := validator.New()
+			f := tt.f
+			f.PageSize = 20
+			f.Sort = "title"
+			f.SortSafelist = safelist
+
+			ValidateFilters(v, f)
+
+			for _, key := range tt.wantErrors {
+				if _, found := v.Errors[key]; !found {
+					t.Errorf("missing error for %s, got %v", key, v.Errors)
+				}
+			}
+			if len(v.Errors) != len(tt.wantErrors) {
+				t.Errorf("got errors %v, want errors for %v", v.Errors, tt.wantErrors)
+			}
+		})
+	}
+}
+
+func TestPaginate(t *testing.T) {
+	key := func(id int64) cursor { return cursor{ID: id} }
+	sorted := func(id int64) string { return encodeCursor(cursor{Sort: "id", ID: id}) }
+	cursorTo := sorted(10)
+
+	tests := []struct {
+		name     string
+		f        Filters
+		rows     []int64
+		total    int
+		wantRows []int64
+		want     Metadata
+	}{
+		{
+			name:     "first page",
+			f:        Filters{Page: 1, PageSize: 2},
+			rows:     []int64{1, 2, 3},
+			total:    5,
+			wantRows: []int64{1, 2},
+			want:     Metadata{CurrentPage: 1, FirstPage: 1, PageSize: 2, EstimatedRecords: 5, HasMore: true, NextCursor: sorted(2)},
+		},
+		{
+			name:     "middle page",
+			f:        Filters{Page: 2, PageSize: 2, ExactCount: true},
+			rows:     []int64{3, 4, 5},
+			total:    5,
+			wantRows: []int64{3, 4},
+			want: Metadata{
+				CurrentPage: 2, FirstPage: 1, LastPage: 3, PageSize: 2, TotalRecords: 5,
+				HasMore: true, NextCursor: sorted(4), PrevCursor: sorted(3),
+			},
+		},
+		{
+			name:     "last page",
+			f:        Filters{Page: 3, PageSize: 2, ExactCount: true},
+			rows:     []int64{5},
+			total:    5,
+			wantRows: []int64{5},
+			want:     Metadata{CurrentPage: 3, FirstPage: 1, LastPage: 3, PageSize: 2, TotalRecords: 5, PrevCursor: sorted(5)},
+		},
+		{
+			name:     "exactly one page",
+			f:        Filters{Page: 1, PageSize: 2, ExactCount: true},
+			rows:     []int64{1, 2},
+			total:    2,
+			wantRows: []int64{1, 2},
+			want:     Metadata{CurrentPage: 1, FirstPage: 1, LastPage: 1, PageSize: 2, TotalRecords: 2},
+		},
+		{
+			name:     "empty page",
+			f:        Filters{Page: 1, PageSize: 2, ExactCount: true},
+			rows:     []int64{},
+			wantRows: []int64{},
+			want:     Metadata{CurrentPage: 1, FirstPage: 1, PageSize: 2},
+		},
+		{
+			name:     "after with more",
+			f:        Filters{Page: 1, PageSize: 2, After: cursorTo},
+			rows:     []int64{11, 12, 13},
+			total:    40,
+			wantRows: []int64{11, 12},
+			want:     Metadata{PageSize: 2, EstimatedRecords: 40, HasMore: true, NextCursor: sorted(12), PrevCursor: sorted(11)},
+		},
+		{
+			name:     "after at the end",
+			f:        Filters{Page: 1, PageSize: 2, After: cursorTo, ExactCount: true},
+			rows:     []int64{11},
+			total:    11,
+			wantRows: []int64{11},
+			want:     Metadata{PageSize: 2, TotalRecords: 11, PrevCursor: sorted(11)},
+		},
+		{
+			name:     "after past the end",
+			f:        Filters{Page: 1, PageSize: 2, After: cursorTo},
+			rows:     []int64{},
+			wantRows: []int64{},
+			want:     Metadata{PageSize: 2},
+		},
+		{
+			name:     "before with more",
+			f:        Filters{Page: 1, PageSize: 2, Before: cursorTo},
+			rows:     []int64{9, 8, 7},
+			total:    40,
+			wantRows: []int64{8, 9},
+			want:     Metadata{PageSize: 2, EstimatedRecords: 40, HasMore: true, NextCursor: sorted(9), PrevCursor: sorted(8)},
+		},
+		{
+			name:     "before at the start",
+			f:        Filters{Page: 1, PageSize: 2, Before: cursorTo},
+			rows:     []int64{9, 8},
+			total:    40,
+			wantRows: []int64{8, 9},
+			want:     Metadata{PageSize: 2, EstimatedRecords: 40, HasMore: true, NextCursor: sorted(9)},
+		},
+		{
+			name:     "before with one row",
+			f:        Filters{Page: 1, PageSize: 2, Before: cursorTo},
+			rows:     []int64{9},
+			wantRows: []int64{9},
+			want:     Metadata{PageSize: 2, HasMore: true, NextCursor: sorted(9)},
+		},
+		{
+			name:     "before the first row",
+			f:        Filters{Page: 1, PageSize: 2, Before: sorted(1)},
+			rows:     []int64{},
+			wantRows: []int64{},
+			want:     Metadata{PageSize: 2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := tt.f
+			f.Sort = "id"
+			keys := make([]cursor, len(tt.rows))
+			for i, id := range tt.rows {
+				keys[i] = key(id)
+			}
+
+			rows, metadata := paginate(f, tt.rows, keys, tt.total)
+
+			if !reflect.DeepEqual(rows, tt.wantRows) {
+				t.Errorf("got rows %v, want %v", rows, tt.wantRows)
+			}
+			if metadata != tt.want {
+				t.Errorf("got metadata %+v, want %+v", metadata, tt.want)
+			}
+		})
+	}
+}
+
+func TestKeyset(t *testing.T) {
+	str := func(s string) *string { return &s }
+
+	tests := []struct {
+		name      string
+		f         Filters
+		wantWhere string
+		wantOrder string
+		wantArgs  []any
+	}{
+		{
+			name:      "no cursor",
+			f:         Filters{Sort: "title"},
+			wantOrder: "m.title ASC NULLS LAST, m.id ASC",
+		},
+		{
+			name:      "descending without cursor",
+			f:         Filters{Sort: "-title"},
+			wantOrder: "m.title DESC NULLS LAST, m.id ASC",
+		},
+		{
+			name:      "after",
+			f:         Filters{Sort: "title", After: encodeCursor(cursor{Sort: "title", Value: str("Alien"), ID: 348})},
+			wantWhere: "WHERE (m.title > $1 OR (m.title = $2 AND m.id > $3) OR m.title IS NULL)",
+			wantOrder: "m.title ASC NULLS LAST, m.id ASC",
+			wantArgs:  []any{"Alien", "Alien", int64(348)},
+		},
+		{
+			name:      "after descending",
+			f:         Filters{Sort: "-title", After: encodeCursor(cursor{Sort: "-title", Value: str("Alien"), ID: 348})},
+			wantWhere: "WHERE (m.title < $1 OR (m.title = $2 AND m.id > $3) OR m.title IS NULL)",
+			wantOrder: "m.title DESC NULLS LAST, m.id ASC",
+			wantArgs:  []any{"Alien", "Alien", int64(348)},
+		},
+		{
+			name:      "after null",
+			f:         Filters{Sort: "title", After: encodeCursor(cursor{Sort: "title", ID: 348})},
+			wantWhere: "WHERE (m.title IS NULL AND m.id > $1)",
+			wantOrder: "m.title ASC NULLS LAST, m.id ASC",
+			wantArgs:  []any{int64(348)},
+		},
+		{
+			name:      "before",
+			f:         Filters{Sort: "title", Before: encodeCursor(cursor{Sort: "title", Value: str("Alien"), ID: 348})},
+			wantWhere: "WHERE (m.title < $1 OR (m.title = $2 AND m.id < $3))",
+			wantOrder: "m.title DESC NULLS FIRST, m.id DESC",
+			wantArgs:  []any{"Alien", "Alien", int64(348)},
+		},
+		{
+			name:      "before descending",
+			f:         Filters{Sort: "-title", Before: encodeCursor(cursor{Sort: "-title", Value: str("Alien"), ID: 348})},
+			wantWhere: "WHERE (m.title > $1 OR (m.title = $2 AND m.id < $3))",
+			wantOrder: "m.title ASC NULLS FIRST, m.id DESC",
+			wantArgs:  []any{"Alien", "Alien", int64(348)},
+		},
+		{
+			name:      "before null",
+			f:         Filters{Sort: "title", Before: encodeCursor(cursor{Sort: "title", ID: 348})},
+			wantWhere: "WHERE (m.title IS NOT NULL OR m.id < $1)",
+			wantOrder: "m.title DESC NULLS FIRST, m.id DESC",
+			wantArgs:  []any{int64(348)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := tt.f
+			f.SortSafelist = []string{"title", "-title"}
+			var q queryBuilder
+
+			order := f.keyset(&q, "m")
+
+			if where := q.whereClause(); where != tt.wantWhere {
+				t.Errorf("got where %q, want %q", where, tt.wantWhere)
+			}
+			if order != tt.wantOrder {
+				t.Errorf("got order %q, want %q", order, tt.wantOrder)
+			}
+			if !reflect.DeepEqual(q.args, tt.wantArgs) {
+				t.Errorf("got args %#v, want %#v", q.args, tt.wantArgs)
+			}
+		})
+	}
+}
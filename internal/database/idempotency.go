@@ -0,0 +1,158 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/Torkel-Aannestad/OMDB-api/internal/validator"
+)
+
+// abandonedAfter is how long a request may hold an idempotency key before the
+// key is given to a retry. It is longer than any request can run.
+var abandonedAfter = time.Minute
+
+// IdempotencyKey is the Idempotency-Key of a POST request with the fingerprint
+// of the request, and the response once the request has completed. Status is
+// 0 while the request is in progress.
+type IdempotencyKey struct {
+	UserID      int64
+	Key         string
+	Fingerprint []byte
+	Status      int
+	Header      ResponseHeader
+	Body        []byte
+	CreatedAt   time.Time
+	ExpiresAt   time.Time
+}
+
+// ResponseHeader holds the response headers that are replayed with a stored
+// response.
+type ResponseHeader map[string][]string
+
+func (h ResponseHeader) Value() (driver.Value, error) {
+	return json.Marshal(h)
+}
+
+func (h *ResponseHeader) Scan(value any) error {
+	if value == nil {
+		*h = nil
+		return nil
+	}
+	b, ok := value.([]byte)
+	if !ok {
+		return errors.New("type assertion to []byte failed")
+	}
+	return json.Unmarshal(b, &h)
+}
+
+func ValidateIdempotencyKey(v *validator.Validator, key string) {
+	v.Check(key != "", "Idempotency-Key", "must be provided")
+	v.Check(len(key) <= 255, "Idempotency-Key", "must not be longer than 255 characters")
+}
+
+type IdempotencyKeysModel struct {
+	DB *sql.DB
+}
+
+// Reserve stores the key for a request that is starting, and reports whether
+// the user was free to use it. Expired keys of the user are removed first, and
+// a key held by a request that was abandoned is taken over by a retry of the
+// same request. A different request with the key is not given the key, so that
+// it is rejected like any reuse of a key.
+func (m IdempotencyKeysModel) Reserve(key *IdempotencyKey, ttl time.Duration) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE user_id = $1 AND expires_at < NOW()`, key.UserID)
+	if err != nil {
+		return false, err
+	}
+
+	query := `
+	INSERT INTO idempotency_keys (user_id, key, fingerprint, expires_at)
+	VALUES ($1, $2, $3, $4)
+	ON CONFLICT (user_id, key) DO UPDATE SET
+		created_at = NOW(),
+		expires_at = EXCLUDED.expires_at
+	WHERE idempotency_keys.status IS NULL
+		AND idempotency_keys.created_at < $5
+		AND idempotency_keys.fingerprint = EXCLUDED.fingerprint`
+
+	now := time.Now()
+	key.CreatedAt = now
+	key.ExpiresAt = now.Add(ttl)
+
+	args := []any{key.UserID, key.Key, key.Fingerprint, key.ExpiresAt, now.Add(-abandonedAfter)}
+
+	result, err := m.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return false, err
+	}
+	affectedRows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affectedRows == 1, nil
+}
+
+func (m IdempotencyKeysModel) Get(userID int64, key string) (*IdempotencyKey, error) {
+	query := `
+	SELECT user_id, key, fingerprint, coalesce(status, 0), header, body, created_at, expires_at
+	FROM idempotency_keys
+	WHERE user_id = $1 AND key = $2 AND expires_at >= NOW()`
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	var idempotencyKey IdempotencyKey
+	err := m.DB.QueryRowContext(ctx, query, userID, key).Scan(
+		&idempotencyKey.UserID,
+		&idempotencyKey.Key,
+		&idempotencyKey.Fingerprint,
+		&idempotencyKey.Status,
+		&idempotencyKey.Header,
+		&idempotencyKey.Body,
+		&idempotencyKey.CreatedAt,
+		&idempotencyKey.ExpiresAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrRecordNotFound
+		} else {
+			return nil, err
+		}
+	}
+
+	return &idempotencyKey, nil
+}
+
+// Complete stores the response of the request holding the key.
+func (m IdempotencyKeysModel) Complete(key *IdempotencyKey) error {
+	query := `
+	UPDATE idempotency_keys
+	SET status = $3, header = $4, body = $5
+	WHERE user_id = $1 AND key = $2 AND status IS NULL`
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, key.UserID, key.Key, key.Status, key.Header, key.Body)
+	return err
+}
+
+// Release removes a key whose request failed, so that it can be retried.
+func (m IdempotencyKeysModel) Release(key *IdempotencyKey) error {
+	query := `
+	DELETE FROM idempotency_keys
+	WHERE user_id = $1 AND key = $2 AND status IS NULL`
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, key.UserID, key.Key)
+	return err
+}
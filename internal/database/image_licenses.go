@@ -0,0 +1,167 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/Torkel-Aannestad/OMDB-api/internal/validator"
+)
+
+// ImageLicense holds the attribution that must be shown together with an
+// image. LicenseID refers to the license list used by OMDB.
+type ImageLicense struct {
+	ImageID    int64     `json:"image_id"`
+	Source     string    `json:"source"`
+	LicenseID  int64     `json:"license_id"`
+	Author     string    `json:"author"`
+	Version    int32     `json:"version"`
+	CreatedAt  time.Time `json:"-"`
+	ModifiedAt time.Time `json:"-"`
+}
+
+type ImageLicensesModel struct {
+	DB *sql.DB
+}
+
+func (m ImageLicensesModel) Insert(imageLicense *ImageLicense) error {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	query := `
+	INSERT INTO image_licenses (
+		image_id,
+		source,
+		license_id,
+		author
+	)
+	VALUES ($1, $2, $3, $4)
+	RETURNING created_at, modified_at, version`
+
+	args := []any{
+		imageLicense.ImageID,
+		imageLicense.Source,
+		imageLicense.LicenseID,
+		imageLicense.Author,
+	}
+
+	return m.DB.QueryRowContext(ctx, query, args...).Scan(
+		&imageLicense.CreatedAt,
+		&imageLicense.ModifiedAt,
+		&imageLicense.Version,
+	)
+}
+
+func (m ImageLicensesModel) Get(imageID int64) (*ImageLicense, error) {
+	if imageID < 0 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `
+	SELECT
+		image_id,
+		source,
+		license_id,
+		author,
+		version,
+		created_at,
+		modified_at
+	FROM image_licenses
+	WHERE image_id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	var imageLicense ImageLicense
+	err := m.DB.QueryRowContext(ctx, query, imageID).Scan(
+		&imageLicense.ImageID,
+		&imageLicense.Source,
+		&imageLicense.LicenseID,
+		&imageLicense.Author,
+		&imageLicense.Version,
+		&imageLicense.CreatedAt,
+		&imageLicense.ModifiedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrRecordNotFound
+		} else {
+			return nil, err
+		}
+	}
+
+	return &imageLicense, nil
+}
+
+func (m ImageLicensesModel) Update(imageLicense *ImageLicense) error {
+	query := `
+	UPDATE image_licenses
+	SET
+		source = $3,
+		license_id = $4,
+		author = $5,
+		modified_at = NOW(),
+		version = version + 1
+	WHERE image_id = $1 and version = $2
+	RETURNING version`
+
+	args := []any{
+		&imageLicense.ImageID,
+		&imageLicense.Version,
+		&imageLicense.Source,
+		&imageLicense.LicenseID,
+		&imageLicense.Author,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&imageLicense.Version)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrEditConflict
+		} else {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m ImageLicensesModel) Delete(imageID int64) error {
+	if imageID < 0 {
+		return ErrRecordNotFound
+	}
+
+	stmt := `
+		DELETE FROM image_licenses WHERE image_id = $1
+	`
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, stmt, imageID)
+	if err != nil {
+		return err
+	}
+	affectedRows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affectedRows == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
+
+func ValidateImageLicense(v *validator.Validator, imageLicense *ImageLicense) {
+	v.Check(imageLicense.ImageID != 0, "image_id", "must be provided")
+	v.Check(imageLicense.ImageID > 0, "image_id", "must be a positive number")
+
+	v.Check(imageLicense.Source != "", "source", "must be provided")
+	v.Check(len(imageLicense.Source) <= 500, "source", "must not be more than 500 bytes long")
+
+	v.Check(imageLicense.LicenseID >= 0, "license_id", "must be a positive number")
+
+	v.Check(imageLicense.Author != "", "author", "must be provided")
+	v.Check(len(imageLicense.Author) <= 500, "author", "must not be more than 500 bytes long")
+}
@@ -7,21 +7,45 @@ import (
 	"time"
 
 	"github.com/Torkel-Aannestad/OMDB-api/internal/validator"
+	"github.com/lib/pq"
 )
 
 type Image struct {
-	ID         int64     `json:"id"`
-	ObjectID   int64     `json:"object_id"`
-	ObjectType string    `json:"object_type"`
-	CreatedAt  time.Time `json:"-"`
-	ModifiedAt time.Time `json:"-"`
-	Version    int32     `json:"version"`
+	ID         int64         `json:"id"`
+	ObjectID   int64         `json:"object_id"`
+	ObjectType string        `json:"object_type"`
+	License    *ImageLicense `json:"license"`
+	CreatedAt  time.Time     `json:"-"`
+	ModifiedAt time.Time     `json:"-"`
+	Version    int32         `json:"version"`
 }
 
 type ImagesModel struct {
 	DB *sql.DB
 }
 
+// nullImageLicense is scanned from a LEFT JOIN on image_licenses, all fields
+// are null when the image has no license.
+type nullImageLicense struct {
+	Source    sql.NullString
+	LicenseID sql.NullInt64
+	Author    sql.NullString
+	Version   sql.NullInt32
+}
+
+func (l nullImageLicense) toImageLicense(imageID int64) *ImageLicense {
+	if !l.Version.Valid {
+		return nil
+	}
+	return &ImageLicense{
+		ImageID:   imageID,
+		Source:    l.Source.String,
+		LicenseID: l.LicenseID.Int64,
+		Author:    l.Author.String,
+		Version:   l.Version.Int32,
+	}
+}
+
 func (m ImagesModel) Insert(image *Image) error {
 	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
 	defer cancel()
@@ -51,19 +75,25 @@ func (m ImagesModel) Get(id int64) (*Image, error) {
 
 	query := `
 	SELECT 
-		id,  
-		object_id,  
-		object_type,
-		version,
-		created_at,
-		modified_at
-	FROM images
-	WHERE id = $1`
+		i.id,  
+		i.object_id,  
+		i.object_type,
+		i.version,
+		i.created_at,
+		i.modified_at,
+		l.source,
+		l.license_id,
+		l.author,
+		l.version
+	FROM images i
+	LEFT JOIN image_licenses l ON l.image_id = i.id
+	WHERE i.id = $1`
 
 	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
 	defer cancel()
 
 	var image Image
+	var license nullImageLicense
 	err := m.DB.QueryRowContext(ctx, query, id).Scan(
 		&image.ID,
 		&image.ObjectID,
@@ -71,6 +101,10 @@ func (m ImagesModel) Get(id int64) (*Image, error) {
 		&image.Version,
 		&image.CreatedAt,
 		&image.ModifiedAt,
+		&license.Source,
+		&license.LicenseID,
+		&license.Author,
+		&license.Version,
 	)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -79,9 +113,73 @@ func (m ImagesModel) Get(id int64) (*Image, error) {
 			return nil, err
 		}
 	}
+	image.License = license.toImageLicense(image.ID)
 
 	return &image, nil
 }
+
+// GetByIDs returns the images with the ids in the order of ids, and the ids
+// that were not found.
+func (m ImagesModel) GetByIDs(ids []int64) ([]*Image, []int64, error) {
+	query := `
+	SELECT 
+		i.id,  
+		i.object_id,  
+		i.object_type,
+		i.version,
+		i.created_at,
+		i.modified_at,
+		l.source,
+		l.license_id,
+		l.author,
+		l.version
+	FROM images i
+	LEFT JOIN image_licenses l ON l.image_id = i.id
+	WHERE i.id = ANY($1)`
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, pq.Array(ids))
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	images := []*Image{}
+
+	for rows.Next() {
+		var image Image
+		var license nullImageLicense
+
+		err := rows.Scan(
+			&image.ID,
+			&image.ObjectID,
+			&image.ObjectType,
+			&image.Version,
+			&image.CreatedAt,
+			&image.ModifiedAt,
+			&license.Source,
+			&license.LicenseID,
+			&license.Author,
+			&license.Version,
+		)
+		if err != nil {
+			return nil, nil, err
+		}
+		image.License = license.toImageLicense(image.ID)
+		images = append(images, &image)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	images, missing := inRequestOrder(ids, images, func(image *Image) int64 { return image.ID })
+
+	return images, missing, nil
+}
 func (m ImagesModel) GetImagesForObject(objectID int64, objectType string) ([]*Image, error) {
 	if objectID < 0 {
 		return nil, ErrRecordNotFound
@@ -89,14 +187,20 @@ func (m ImagesModel) GetImagesForObject(objectID int64, objectType string) ([]*I
 
 	query := `
 	SELECT 
-		id,  
-		object_id,  
-		object_type,
-		version,
-		created_at,
-		modified_at
-	FROM images
-	WHERE object_id = $1 AND object_type = $2`
+		i.id,  
+		i.object_id,  
+		i.object_type,
+		i.version,
+		i.created_at,
+		i.modified_at,
+		l.source,
+		l.license_id,
+		l.author,
+		l.version
+	FROM images i
+	LEFT JOIN image_licenses l ON l.image_id = i.id
+	WHERE i.object_id = $1 AND i.object_type = $2
+	ORDER BY i.id`
 
 	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
 	defer cancel()
@@ -111,6 +215,7 @@ func (m ImagesModel) GetImagesForObject(objectID int64, objectType string) ([]*I
 
 	for rows.Next() {
 		var image Image
+		var license nullImageLicense
 
 		err := rows.Scan(
 			&image.ID,
@@ -119,10 +224,15 @@ func (m ImagesModel) GetImagesForObject(objectID int64, objectType string) ([]*I
 			&image.Version,
 			&image.CreatedAt,
 			&image.ModifiedAt,
+			&license.Source,
+			&license.LicenseID,
+			&license.Author,
+			&license.Version,
 		)
 		if err != nil {
 			return nil, err
 		}
+		image.License = license.toImageLicense(image.ID)
 		images = append(images, &image)
 	}
 
@@ -134,6 +244,68 @@ func (m ImagesModel) GetImagesForObject(objectID int64, objectType string) ([]*I
 	return images, nil
 }
 
+// GetImagesForObjects returns the images of each of the objects of the given
+// type in one query.
+func (m ImagesModel) GetImagesForObjects(objectIDs []int64, objectType string) (map[int64][]*Image, error) {
+	query := `
+	SELECT 
+		i.id,  
+		i.object_id,  
+		i.object_type,
+		i.version,
+		i.created_at,
+		i.modified_at,
+		l.source,
+		l.license_id,
+		l.author,
+		l.version
+	FROM images i
+	LEFT JOIN image_licenses l ON l.image_id = i.id
+	WHERE i.object_id = ANY($1) AND i.object_type = $2
+	ORDER BY i.id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, pq.Array(objectIDs), objectType)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	images := make(map[int64][]*Image)
+
+	for rows.Next() {
+		var image Image
+		var license nullImageLicense
+
+		err := rows.Scan(
+			&image.ID,
+			&image.ObjectID,
+			&image.ObjectType,
+			&image.Version,
+			&image.CreatedAt,
+			&image.ModifiedAt,
+			&license.Source,
+			&license.LicenseID,
+			&license.Author,
+			&license.Version,
+		)
+		if err != nil {
+			return nil, err
+		}
+		image.License = license.toImageLicense(image.ID)
+		images[image.ObjectID] = append(images[image.ObjectID], &image)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return images, nil
+}
+
 func (m ImagesModel) Update(image *Image) error {
 	query := `
 	UPDATE images
@@ -7,14 +7,16 @@ import (
 	"time"
 
 	"github.com/Torkel-Aannestad/OMDB-api/internal/validator"
+	"github.com/lib/pq"
 )
 
 type Job struct {
-	ID         int64     `json:"id"`
-	Name       string    `json:"name"`
-	CreatedAt  time.Time `json:"-"`
-	ModifiedAt time.Time `json:"-"`
-	Version    int32     `json:"version"`
+	ID           int64     `json:"id"`
+	Name         string    `json:"name"`
+	DepartmentID NullInt64 `json:"department_id"`
+	CreatedAt    time.Time `json:"-"`
+	ModifiedAt   time.Time `json:"-"`
+	Version      int32     `json:"version"`
 }
 
 type JobsModel struct {
@@ -27,12 +29,13 @@ func (m JobsModel) Insert(job *Job) error {
 
 	query := `
 	INSERT INTO jobs (
-		name
+		name,
+		department_id
 	)
-	VALUES ($1)
+	VALUES ($1, $2)
 	RETURNING id, created_at, modified_at, version`
 
-	return m.DB.QueryRowContext(ctx, query, job.Name).Scan(
+	return m.DB.QueryRowContext(ctx, query, job.Name, job.DepartmentID.NullInt64).Scan(
 		&job.ID,
 		&job.CreatedAt,
 		&job.ModifiedAt,
@@ -50,6 +53,7 @@ func (m JobsModel) Get(id int64) (*Job, error) {
 		SELECT 
 			id,  
 			name,
+			department_id,
 			created_at,
 			modified_at,
 			version
@@ -62,6 +66,7 @@ func (m JobsModel) Get(id int64) (*Job, error) {
 	err := m.DB.QueryRowContext(ctx, query, id).Scan(
 		&job.ID,
 		&job.Name,
+		&job.DepartmentID.NullInt64,
 		&job.CreatedAt,
 		&job.ModifiedAt,
 		&job.Version,
@@ -77,11 +82,64 @@ func (m JobsModel) Get(id int64) (*Job, error) {
 	return &job, nil
 }
 
+// GetByIDs returns the jobs with the ids in the order of ids, and the ids that
+// were not found.
+func (m JobsModel) GetByIDs(ids []int64) ([]*Job, []int64, error) {
+	query := `
+		SELECT 
+			id,  
+			name,
+			department_id,
+			created_at,
+			modified_at,
+			version
+		FROM jobs
+		WHERE id = ANY($1)`
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, pq.Array(ids))
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	jobs := []*Job{}
+
+	for rows.Next() {
+		var job Job
+
+		err := rows.Scan(
+			&job.ID,
+			&job.Name,
+			&job.DepartmentID.NullInt64,
+			&job.CreatedAt,
+			&job.ModifiedAt,
+			&job.Version,
+		)
+		if err != nil {
+			return nil, nil, err
+		}
+		jobs = append(jobs, &job)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	jobs, missing := inRequestOrder(ids, jobs, func(job *Job) int64 { return job.ID })
+
+	return jobs, missing, nil
+}
+
 func (m JobsModel) Update(job *Job) error {
 	query := `
 	UPDATE jobs
 	SET 
 		name = $3,
+		department_id = $4,
 		modified_at = NOW(),
 		version = version + 1
 	WHERE id = $1 and version = $2
@@ -91,6 +149,7 @@ func (m JobsModel) Update(job *Job) error {
 		&job.ID,
 		&job.Version,
 		&job.Name,
+		&job.DepartmentID,
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
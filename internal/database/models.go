@@ -11,35 +11,55 @@ var (
 )
 
 type Models struct {
-	Users         *UserModel
-	Tokens        *TokenModel
-	Permissions   *PermissionModel
-	Movies        *MovieModel
-	People        *PeopleModel
-	Categories    *CategoriesModel
-	CategoryItems *CategoryItemsModel
-	Casts         *CastsModel
-	Jobs          *JobsModel
-	Images        *ImagesModel
-	MovieLinks    *MovieLinkModel
-	PeopleLinks   *PeopleLinkModel
-	Trailer       *TrailersModel
+	Users           *UserModel
+	Tokens          *TokenModel
+	Permissions     *PermissionModel
+	Movies          *MovieModel
+	People          *PeopleModel
+	Categories      *CategoriesModel
+	CategoryItems   *CategoryItemsModel
+	Casts           *CastsModel
+	Jobs            *JobsModel
+	Images          *ImagesModel
+	MovieLinks      *MovieLinkModel
+	PeopleLinks     *PeopleLinkModel
+	Trailer         *TrailersModel
+	MovieCountries  *MovieCountriesModel
+	MovieLanguages  *MovieLanguagesModel
+	MovieReferences *MovieReferencesModel
+	MovieAbstracts  *MovieAbstractsModel
+	MovieAliases    *MovieAliasesModel
+	ImageLicenses   *ImageLicensesModel
+	Sync            *SyncModel
+	Series          *SeriesModel
+	Search          *SearchModel
+	IdempotencyKeys *IdempotencyKeysModel
 }
 
 func NewModels(db *sql.DB) *Models {
 	return &Models{
-		Users:         &UserModel{DB: db},
-		Tokens:        &TokenModel{DB: db},
-		Permissions:   &PermissionModel{DB: db},
-		Movies:        &MovieModel{DB: db},
-		People:        &PeopleModel{DB: db},
-		Categories:    &CategoriesModel{DB: db},
-		CategoryItems: &CategoryItemsModel{DB: db},
-		Casts:         &CastsModel{DB: db},
-		Jobs:          &JobsModel{DB: db},
-		Images:        &ImagesModel{DB: db},
-		MovieLinks:    &MovieLinkModel{DB: db},
-		PeopleLinks:   &PeopleLinkModel{DB: db},
-		Trailer:       &TrailersModel{DB: db},
+		Users:           &UserModel{DB: db},
+		Tokens:          &TokenModel{DB: db},
+		Permissions:     &PermissionModel{DB: db},
+		Movies:          &MovieModel{DB: db},
+		People:          &PeopleModel{DB: db},
+		Categories:      &CategoriesModel{DB: db},
+		CategoryItems:   &CategoryItemsModel{DB: db},
+		Casts:           &CastsModel{DB: db},
+		Jobs:            &JobsModel{DB: db},
+		Images:          &ImagesModel{DB: db},
+		MovieLinks:      &MovieLinkModel{DB: db},
+		PeopleLinks:     &PeopleLinkModel{DB: db},
+		Trailer:         &TrailersModel{DB: db},
+		MovieCountries:  &MovieCountriesModel{DB: db},
+		MovieLanguages:  &MovieLanguagesModel{DB: db},
+		MovieReferences: &MovieReferencesModel{DB: db},
+		MovieAbstracts:  &MovieAbstractsModel{DB: db},
+		MovieAliases:    &MovieAliasesModel{DB: db},
+		ImageLicenses:   &ImageLicensesModel{DB: db},
+		Sync:            &SyncModel{DB: db},
+		Series:          &SeriesModel{DB: db},
+		Search:          &SearchModel{DB: db},
+		IdempotencyKeys: &IdempotencyKeysModel{DB: db},
 	}
 }
@@ -0,0 +1,271 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/Torkel-Aannestad/OMDB-api/internal/validator"
+	"github.com/lib/pq"
+)
+
+// DefaultAbstractLanguage is the language of movies.abstract. Abstracts in
+// other languages are stored in movie_abstracts.
+const DefaultAbstractLanguage = "en"
+
+type MovieAbstract struct {
+	MovieID    int64     `json:"movie_id"`
+	Language   string    `json:"language"`
+	Abstract   string    `json:"abstract"`
+	Version    int32     `json:"version"`
+	CreatedAt  time.Time `json:"-"`
+	ModifiedAt time.Time `json:"-"`
+}
+
+type MovieAbstractsModel struct {
+	DB *sql.DB
+}
+
+func (m MovieAbstractsModel) Insert(movieAbstract *MovieAbstract) error {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	query := `
+	WITH inserted AS (
+		INSERT INTO movie_abstracts (
+			movie_id,
+			language,
+			abstract
+		)
+		VALUES ($1, $2, $3)
+		RETURNING movie_id, created_at, modified_at, version
+	), synced AS (` + touchSyncedMovies("inserted") + `
+	), touched AS (` + touchMovies("inserted") + `
+	)
+	SELECT created_at, modified_at, version FROM inserted`
+
+	args := []any{
+		movieAbstract.MovieID,
+		movieAbstract.Language,
+		movieAbstract.Abstract,
+	}
+
+	return m.DB.QueryRowContext(ctx, query, args...).Scan(
+		&movieAbstract.CreatedAt,
+		&movieAbstract.ModifiedAt,
+		&movieAbstract.Version,
+	)
+}
+
+func (m MovieAbstractsModel) Get(movieID int64, language string) (*MovieAbstract, error) {
+	if movieID < 0 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `
+	SELECT
+		movie_id,
+		language,
+		abstract,
+		version,
+		created_at,
+		modified_at
+	FROM movie_abstracts
+	WHERE movie_id = $1 AND language = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	var movieAbstract MovieAbstract
+	err := m.DB.QueryRowContext(ctx, query, movieID, language).Scan(
+		&movieAbstract.MovieID,
+		&movieAbstract.Language,
+		&movieAbstract.Abstract,
+		&movieAbstract.Version,
+		&movieAbstract.CreatedAt,
+		&movieAbstract.ModifiedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrRecordNotFound
+		} else {
+			return nil, err
+		}
+	}
+
+	return &movieAbstract, nil
+}
+
+func (m MovieAbstractsModel) GetByMovieID(movieID int64) ([]*MovieAbstract, error) {
+	if movieID < 0 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `
+	SELECT
+		movie_id,
+		language,
+		abstract,
+		version
+	FROM movie_abstracts
+	WHERE movie_id = $1
+	ORDER BY language`
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, movieID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	movieAbstracts := []*MovieAbstract{}
+
+	for rows.Next() {
+		var movieAbstract MovieAbstract
+
+		err := rows.Scan(
+			&movieAbstract.MovieID,
+			&movieAbstract.Language,
+			&movieAbstract.Abstract,
+			&movieAbstract.Version,
+		)
+		if err != nil {
+			return nil, err
+		}
+		movieAbstracts = append(movieAbstracts, &movieAbstract)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return movieAbstracts, nil
+}
+
+// GetForMovies returns the abstracts for the given movies in any of the given
+// languages, keyed by movie id and then language.
+func (m MovieAbstractsModel) GetForMovies(movieIDs []int64, languages []string) (map[int64]map[string]string, error) {
+	abstracts := make(map[int64]map[string]string)
+	if len(movieIDs) == 0 || len(languages) == 0 {
+		return abstracts, nil
+	}
+
+	query := `
+	SELECT
+		movie_id,
+		language,
+		abstract
+	FROM movie_abstracts
+	WHERE movie_id = ANY($1) AND language = ANY($2)`
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, pq.Array(movieIDs), pq.Array(languages))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var movieID int64
+		var language, abstract string
+
+		err := rows.Scan(&movieID, &language, &abstract)
+		if err != nil {
+			return nil, err
+		}
+
+		if abstracts[movieID] == nil {
+			abstracts[movieID] = make(map[string]string)
+		}
+		abstracts[movieID][language] = abstract
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return abstracts, nil
+}
+
+func (m MovieAbstractsModel) Update(movieAbstract *MovieAbstract) error {
+	query := `
+	WITH updated AS (
+		UPDATE movie_abstracts
+		SET
+			abstract = $4,
+			modified_at = NOW(),
+			version = version + 1
+		WHERE movie_id = $1 AND language = $2 AND version = $3
+		RETURNING movie_id, version
+	), synced AS (` + touchSyncedMovies("updated") + `
+	), touched AS (` + touchMovies("updated") + `
+	)
+	SELECT version FROM updated`
+
+	args := []any{
+		&movieAbstract.MovieID,
+		&movieAbstract.Language,
+		&movieAbstract.Version,
+		&movieAbstract.Abstract,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&movieAbstract.Version)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrEditConflict
+		} else {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m MovieAbstractsModel) Delete(movieID int64, language string) error {
+	if movieID < 0 {
+		return ErrRecordNotFound
+	}
+
+	stmt := `
+	WITH deleted AS (
+		DELETE FROM movie_abstracts WHERE movie_id = $1 AND language = $2
+		RETURNING movie_id
+	), synced AS (` + touchSyncedMovies("deleted") + `
+	)` + touchMovies("deleted")
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, stmt, movieID, language)
+	if err != nil {
+		return err
+	}
+	affectedRows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affectedRows == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
+
+func ValidateMovieAbstract(v *validator.Validator, movieAbstract *MovieAbstract) {
+	v.Check(movieAbstract.MovieID != 0, "movie_id", "must be provided")
+	v.Check(movieAbstract.MovieID > 0, "movie_id", "must be a positive number")
+
+	ValidateLanguageCode(v, "language", movieAbstract.Language)
+	v.Check(movieAbstract.Language != DefaultAbstractLanguage, "language", "the english abstract is updated through the abstract field on the movie")
+
+	v.Check(movieAbstract.Abstract != "", "abstract", "must be provided")
+	v.Check(len(movieAbstract.Abstract) <= 4096, "abstract", "must not be longer than 4096")
+}
@@ -0,0 +1,292 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/Torkel-Aannestad/OMDB-api/internal/validator"
+	"github.com/lib/pq"
+)
+
+// MovieAlias is an alternate title for a movie. CountryCode is empty when the
+// title is used in every country speaking the language.
+type MovieAlias struct {
+	ID          int64     `json:"id"`
+	MovieID     int64     `json:"movie_id"`
+	Name        string    `json:"name"`
+	Language    string    `json:"language"`
+	CountryCode string    `json:"country_code"`
+	Version     int32     `json:"version"`
+	CreatedAt   time.Time `json:"-"`
+	ModifiedAt  time.Time `json:"-"`
+}
+
+type MovieAliasesModel struct {
+	DB *sql.DB
+}
+
+func (m MovieAliasesModel) Insert(movieAlias *MovieAlias) error {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	query := `
+	WITH inserted AS (
+		INSERT INTO movie_aliases (
+			movie_id,
+			name,
+			language,
+			country_code
+		)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, movie_id, created_at, modified_at, version
+	), synced AS (` + touchSyncedMovies("inserted") + `
+	), touched AS (` + touchMovies("inserted") + `
+	)
+	SELECT id, created_at, modified_at, version FROM inserted`
+
+	args := []any{
+		movieAlias.MovieID,
+		movieAlias.Name,
+		movieAlias.Language,
+		movieAlias.CountryCode,
+	}
+
+	return m.DB.QueryRowContext(ctx, query, args...).Scan(
+		&movieAlias.ID,
+		&movieAlias.CreatedAt,
+		&movieAlias.ModifiedAt,
+		&movieAlias.Version,
+	)
+}
+
+func (m MovieAliasesModel) Get(id int64) (*MovieAlias, error) {
+	if id < 0 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `
+	SELECT
+		id,
+		movie_id,
+		name,
+		language,
+		country_code,
+		version,
+		created_at,
+		modified_at
+	FROM movie_aliases
+	WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	var movieAlias MovieAlias
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(
+		&movieAlias.ID,
+		&movieAlias.MovieID,
+		&movieAlias.Name,
+		&movieAlias.Language,
+		&movieAlias.CountryCode,
+		&movieAlias.Version,
+		&movieAlias.CreatedAt,
+		&movieAlias.ModifiedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrRecordNotFound
+		} else {
+			return nil, err
+		}
+	}
+
+	return &movieAlias, nil
+}
+
+func (m MovieAliasesModel) GetByMovieID(movieID int64) ([]*MovieAlias, error) {
+	if movieID < 0 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `
+	SELECT
+		id,
+		movie_id,
+		name,
+		language,
+		country_code,
+		version
+	FROM movie_aliases
+	WHERE movie_id = $1
+	ORDER BY language, country_code, id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, movieID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	movieAliases := []*MovieAlias{}
+
+	for rows.Next() {
+		var movieAlias MovieAlias
+
+		err := rows.Scan(
+			&movieAlias.ID,
+			&movieAlias.MovieID,
+			&movieAlias.Name,
+			&movieAlias.Language,
+			&movieAlias.CountryCode,
+			&movieAlias.Version,
+		)
+		if err != nil {
+			return nil, err
+		}
+		movieAliases = append(movieAliases, &movieAlias)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return movieAliases, nil
+}
+
+// GetForMovies returns the aliases for the given movies in any of the given
+// languages, ordered by movie and id.
+func (m MovieAliasesModel) GetForMovies(movieIDs []int64, languages []string) ([]*MovieAlias, error) {
+	movieAliases := []*MovieAlias{}
+	if len(movieIDs) == 0 || len(languages) == 0 {
+		return movieAliases, nil
+	}
+
+	query := `
+	SELECT
+		id,
+		movie_id,
+		name,
+		language,
+		country_code,
+		version
+	FROM movie_aliases
+	WHERE movie_id = ANY($1) AND language = ANY($2)
+	ORDER BY movie_id, id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, pq.Array(movieIDs), pq.Array(languages))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var movieAlias MovieAlias
+
+		err := rows.Scan(
+			&movieAlias.ID,
+			&movieAlias.MovieID,
+			&movieAlias.Name,
+			&movieAlias.Language,
+			&movieAlias.CountryCode,
+			&movieAlias.Version,
+		)
+		if err != nil {
+			return nil, err
+		}
+		movieAliases = append(movieAliases, &movieAlias)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return movieAliases, nil
+}
+
+func (m MovieAliasesModel) Update(movieAlias *MovieAlias) error {
+	query := `
+	WITH updated AS (
+		UPDATE movie_aliases
+		SET
+			name = $3,
+			language = $4,
+			country_code = $5,
+			modified_at = NOW(),
+			version = version + 1
+		WHERE id = $1 and version = $2
+		RETURNING movie_id, version
+	), synced AS (` + touchSyncedMovies("updated") + `
+	), touched AS (` + touchMovies("updated") + `
+	)
+	SELECT version FROM updated`
+
+	args := []any{
+		&movieAlias.ID,
+		&movieAlias.Version,
+		&movieAlias.Name,
+		&movieAlias.Language,
+		&movieAlias.CountryCode,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&movieAlias.Version)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrEditConflict
+		} else {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m MovieAliasesModel) Delete(id int64) error {
+	if id < 0 {
+		return ErrRecordNotFound
+	}
+
+	stmt := `
+	WITH deleted AS (
+		DELETE FROM movie_aliases WHERE id = $1
+		RETURNING movie_id
+	), synced AS (` + touchSyncedMovies("deleted") + `
+	)` + touchMovies("deleted")
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, stmt, id)
+	if err != nil {
+		return err
+	}
+	affectedRows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affectedRows == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
+
+func ValidateMovieAlias(v *validator.Validator, movieAlias *MovieAlias) {
+	v.Check(movieAlias.MovieID != 0, "movie_id", "must be provided")
+	v.Check(movieAlias.MovieID > 0, "movie_id", "must be a positive number")
+
+	v.Check(movieAlias.Name != "", "name", "must be provided")
+	v.Check(len(movieAlias.Name) <= 500, "name", "must not be more than 500 bytes long")
+
+	ValidateLanguageCode(v, "language", movieAlias.Language)
+	if movieAlias.CountryCode != "" {
+		ValidateCountryCode(v, "country_code", movieAlias.CountryCode)
+	}
+}
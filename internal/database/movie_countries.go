@@ -0,0 +1,134 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/Torkel-Aannestad/OMDB-api/internal/validator"
+)
+
+type MovieCountry struct {
+	MovieID     int64     `json:"movie_id"`
+	CountryCode string    `json:"country_code"`
+	CreatedAt   time.Time `json:"-"`
+	ModifiedAt  time.Time `json:"-"`
+}
+
+type MovieCountriesModel struct {
+	DB *sql.DB
+}
+
+func (m MovieCountriesModel) Insert(movieCountry *MovieCountry) error {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	query := `
+	WITH inserted AS (
+		INSERT INTO movie_countries (
+			movie_id,
+			country_code
+		)
+		VALUES ($1, $2)
+		RETURNING movie_id, created_at, modified_at
+	), synced AS (` + touchSyncedMovies("inserted") + `
+	), touched AS (` + touchMovies("inserted") + `
+	)
+	SELECT created_at, modified_at FROM inserted`
+
+	args := []any{
+		movieCountry.MovieID,
+		movieCountry.CountryCode,
+	}
+
+	return m.DB.QueryRowContext(ctx, query, args...).Scan(
+		&movieCountry.CreatedAt,
+		&movieCountry.ModifiedAt,
+	)
+}
+
+func (m MovieCountriesModel) Get(movieID int64) ([]*MovieCountry, error) {
+	if movieID < 0 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `
+		SELECT 
+			movie_id,  
+			country_code
+		FROM movie_countries
+		WHERE movie_id = $1
+		ORDER BY country_code`
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, movieID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	movieCountries := []*MovieCountry{}
+
+	for rows.Next() {
+		var movieCountry MovieCountry
+
+		err := rows.Scan(
+			&movieCountry.MovieID,
+			&movieCountry.CountryCode,
+		)
+		if err != nil {
+			return nil, err
+		}
+		movieCountries = append(movieCountries, &movieCountry)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return movieCountries, nil
+}
+
+func (m MovieCountriesModel) Delete(movieID int64, countryCode string) error {
+	if movieID < 0 {
+		return ErrRecordNotFound
+	}
+
+	stmt := `
+	WITH deleted AS (
+		DELETE FROM movie_countries WHERE movie_id = $1 AND country_code = $2
+		RETURNING movie_id
+	), synced AS (` + touchSyncedMovies("deleted") + `
+	)` + touchMovies("deleted")
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, stmt, movieID, countryCode)
+	if err != nil {
+		return err
+	}
+	affectedRows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affectedRows == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
+
+func ValidateMovieCountry(v *validator.Validator, movieCountry *MovieCountry) {
+	v.Check(movieCountry.MovieID != 0, "movie_id", "must be provided")
+	v.Check(movieCountry.MovieID > 0, "movie_id", "must be a positive number")
+
+	ValidateCountryCode(v, "country_code", movieCountry.CountryCode)
+}
+
+func ValidateCountryCode(v *validator.Validator, key string, countryCode string) {
+	v.Check(countryCode != "", key, "must be provided")
+	v.Check(validator.Matches(countryCode, validator.CountryCodeRX), key, "must be a two letter ISO 3166-1 code")
+}
@@ -0,0 +1,146 @@
+package database
+
+import (
+	"context"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Torkel-Aannestad/OMDB-api/internal/validator"
+	"github.com/lib/pq"
+)
+
+// MovieFacets are the facets that can be counted for the movie list.
+var MovieFacets = []string{"kind", "decade", "category", "language"}
+
+// maxFacetValues is the number of values returned for each facet. Kinds and
+// decades are few, so the limit is for the most common categories and
+// languages.
+const maxFacetValues = 20
+
+var maxFacetValuesSQL = strconv.Itoa(maxFacetValues)
+
+// FacetValue is the number of movies with a value of a facet. Name is the
+// display name of category ids and language codes.
+type FacetValue struct {
+	Value string `json:"value"`
+	Name  string `json:"name,omitempty"`
+	Count int    `json:"count"`
+}
+
+// facetQueries holds the query counting each facet for the movies in
+// matched. Kinds and decades are ordered by value, the others by count.
+var facetQueries = map[string]string{
+	"kind": `
+		(SELECT 'kind', kind::text, '', count(*)
+		FROM matched
+		GROUP BY kind
+		ORDER BY 2)`,
+	"decade": `
+		(SELECT 'decade', (floor(extract(year FROM date) / 10) * 10)::int::text, '', count(*)
+		FROM matched
+		GROUP BY 2
+		ORDER BY 2)`,
+	"category": `
+		(SELECT 'category', categories.id::text, categories.name, count(*)
+		FROM matched
+		JOIN movie_categories ON movie_categories.movie_id = matched.id
+		JOIN categories ON categories.id = movie_categories.category_id
+		GROUP BY categories.id
+		ORDER BY 4 DESC, 3
+		LIMIT ` + maxFacetValuesSQL + `)`,
+	"language": `
+		(SELECT 'language', movie_languages.language_code, coalesce(languages.name, ''), count(*)
+		FROM matched
+		JOIN movie_languages ON movie_languages.movie_id = matched.id
+		LEFT JOIN languages ON languages.code = movie_languages.language_code
+		GROUP BY movie_languages.language_code, languages.name
+		ORDER BY 4 DESC, 2
+		LIMIT ` + maxFacetValuesSQL + `)`,
+}
+
+// GetFacets counts the values of the facets for the movies matching the
+// filters. The matching movies are found once and shared by the facets.
+// Without filters the counts are read from movie_facet_counts.
+func (m MovieModel) GetFacets(movieFilters MovieFilters, facets []string) (map[string][]FacetValue, error) {
+	var q queryBuilder
+	movieFilters.apply(&q)
+
+	var query string
+	if len(q.conditions) == 0 {
+		query = `
+			SELECT facet, value, name, count
+			FROM (
+				SELECT *, row_number() OVER (PARTITION BY facet ORDER BY count DESC, value) AS rank
+				FROM movie_facet_counts
+				WHERE facet = ANY(` + q.arg(pq.Array(facets)) + `)
+			) counts
+			WHERE facet IN ('kind', 'decade') OR rank <= ` + maxFacetValuesSQL + `
+			ORDER BY facet, CASE WHEN facet IN ('kind', 'decade') THEN value END, count DESC, value`
+	} else {
+		queries := []string{}
+		for _, facet := range MovieFacets {
+			if slices.Contains(facets, facet) {
+				queries = append(queries, facetQueries[facet])
+			}
+		}
+
+		query = `
+			WITH matched AS MATERIALIZED (
+				SELECT movies.id, movies.kind, movies.date
+				FROM movies
+				` + q.whereClause() + `
+			)
+			` + strings.Join(queries, "\n\t\t\tUNION ALL")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, q.args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := map[string][]FacetValue{}
+	for _, facet := range facets {
+		counts[facet] = []FacetValue{}
+	}
+
+	for rows.Next() {
+		var facet string
+		var value FacetValue
+
+		err := rows.Scan(&facet, &value.Value, &value.Name, &value.Count)
+		if err != nil {
+			return nil, err
+		}
+
+		counts[facet] = append(counts[facet], value)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return counts, nil
+}
+
+// RefreshFacets recomputes the facet counts of all movies.
+func (m MovieModel) RefreshFacets() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, `REFRESH MATERIALIZED VIEW CONCURRENTLY movie_facet_counts`)
+	return err
+}
+
+func ValidateFacets(v *validator.Validator, facets []string) {
+	for _, facet := range facets {
+		v.Check(validator.PermittedValue(facet, MovieFacets...), "facets", "must be one of the following values: "+strings.Join(MovieFacets, ", "))
+	}
+	v.Check(validator.Unique(facets), "facets", "must not contain duplicate values")
+}
@@ -0,0 +1,134 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/Torkel-Aannestad/OMDB-api/internal/validator"
+)
+
+type MovieLanguage struct {
+	MovieID      int64     `json:"movie_id"`
+	LanguageCode string    `json:"language_code"`
+	CreatedAt    time.Time `json:"-"`
+	ModifiedAt   time.Time `json:"-"`
+}
+
+type MovieLanguagesModel struct {
+	DB *sql.DB
+}
+
+func (m MovieLanguagesModel) Insert(movieLanguage *MovieLanguage) error {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	query := `
+	WITH inserted AS (
+		INSERT INTO movie_languages (
+			movie_id,
+			language_code
+		)
+		VALUES ($1, $2)
+		RETURNING movie_id, created_at, modified_at
+	), synced AS (` + touchSyncedMovies("inserted") + `
+	), touched AS (` + touchMovies("inserted") + `
+	)
+	SELECT created_at, modified_at FROM inserted`
+
+	args := []any{
+		movieLanguage.MovieID,
+		movieLanguage.LanguageCode,
+	}
+
+	return m.DB.QueryRowContext(ctx, query, args...).Scan(
+		&movieLanguage.CreatedAt,
+		&movieLanguage.ModifiedAt,
+	)
+}
+
+func (m MovieLanguagesModel) Get(movieID int64) ([]*MovieLanguage, error) {
+	if movieID < 0 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `
+		SELECT 
+			movie_id,  
+			language_code
+		FROM movie_languages
+		WHERE movie_id = $1
+		ORDER BY language_code`
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, movieID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	movieLanguages := []*MovieLanguage{}
+
+	for rows.Next() {
+		var movieLanguage MovieLanguage
+
+		err := rows.Scan(
+			&movieLanguage.MovieID,
+			&movieLanguage.LanguageCode,
+		)
+		if err != nil {
+			return nil, err
+		}
+		movieLanguages = append(movieLanguages, &movieLanguage)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return movieLanguages, nil
+}
+
+func (m MovieLanguagesModel) Delete(movieID int64, languageCode string) error {
+	if movieID < 0 {
+		return ErrRecordNotFound
+	}
+
+	stmt := `
+	WITH deleted AS (
+		DELETE FROM movie_languages WHERE movie_id = $1 AND language_code = $2
+		RETURNING movie_id
+	), synced AS (` + touchSyncedMovies("deleted") + `
+	)` + touchMovies("deleted")
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, stmt, movieID, languageCode)
+	if err != nil {
+		return err
+	}
+	affectedRows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affectedRows == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
+
+func ValidateMovieLanguage(v *validator.Validator, movieLanguage *MovieLanguage) {
+	v.Check(movieLanguage.MovieID != 0, "movie_id", "must be provided")
+	v.Check(movieLanguage.MovieID > 0, "movie_id", "must be a positive number")
+
+	ValidateLanguageCode(v, "language_code", movieLanguage.LanguageCode)
+}
+
+func ValidateLanguageCode(v *validator.Validator, key string, languageCode string) {
+	v.Check(languageCode != "", key, "must be provided")
+	v.Check(validator.Matches(languageCode, validator.LanguageCodeRX), key, "must be a two letter ISO 639-1 code")
+}
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/Torkel-Aannestad/OMDB-api/internal/validator"
+	"github.com/lib/pq"
 )
 
 type MovieLink struct {
@@ -132,3 +133,52 @@ func ValidateMovieLink(v *validator.Validator, MovieLink *MovieLink) {
 	v.Check(validator.PermittedValue(MovieLink.Key, "wikidata", "wikipedia", "imdbperson"), "source", "must not be of the following values 'wikidata', 'wikipedia' or 'imdbperson'")
 
 }
+
+// GetForMovies returns the links of each of the movies in one query.
+func (m MovieLinkModel) GetForMovies(movieIDs []int64) (map[int64][]*MovieLink, error) {
+	query := `
+		SELECT 
+			id,
+			source,  
+			key,
+			movie_id,
+			language
+		FROM movie_links
+		WHERE movie_id = ANY($1)
+		ORDER BY id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, pq.Array(movieIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	movieLinks := make(map[int64][]*MovieLink)
+
+	for rows.Next() {
+		var movieLink MovieLink
+
+		err := rows.Scan(
+			&movieLink.ID,
+			&movieLink.Source,
+			&movieLink.Key,
+			&movieLink.MovieID,
+			&movieLink.Language,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		movieLinks[movieLink.MovieID] = append(movieLinks[movieLink.MovieID], &movieLink)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return movieLinks, nil
+}
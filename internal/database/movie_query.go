@@ -0,0 +1,295 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Torkel-Aannestad/OMDB-api/internal/validator"
+)
+
+// MovieQueryFields are the fields of the movie query language.
+var MovieQueryFields = []string{"budget", "cast", "country", "genre", "keyword", "kind", "language", "rating", "revenue", "runtime", "sort", "votes", "year"}
+
+// queryTerm is a field:value term of a movie query. Words without a field have
+// an empty field.
+type queryTerm struct {
+	field string
+	value string
+}
+
+// tokenizeQuery splits q into terms at spaces outside double quotes. Quotes
+// keep the spaces of a value, like genre:"Film Noir", and are removed. A
+// colon inside quotes is part of the value.
+func tokenizeQuery(q string) ([]queryTerm, error) {
+	terms := []queryTerm{}
+
+	var raw strings.Builder
+	colon := -1
+	inQuote := false
+	quoted := false
+
+	flush := func() {
+		if raw.Len() == 0 && !quoted {
+			return
+		}
+		s := raw.String()
+		if colon >= 0 {
+			terms = append(terms, queryTerm{field: strings.ToLower(s[:colon]), value: s[colon+1:]})
+		} else {
+			terms = append(terms, queryTerm{value: s})
+		}
+		raw.Reset()
+		colon = -1
+		quoted = false
+	}
+
+	for _, r := range q {
+		switch {
+		case r == '"':
+			inQuote = !inQuote
+			quoted = true
+		case r == ':' && !inQuote && colon < 0 && raw.Len() > 0:
+			colon = raw.Len()
+			raw.WriteRune(r)
+		case (r == ' ' || r == '\t') && !inQuote:
+			flush()
+		default:
+			raw.WriteRune(r)
+		}
+	}
+	if inQuote {
+		return nil, errors.New("has an unterminated quote")
+	}
+	flush()
+
+	return terms, nil
+}
+
+// parseRange parses the bounds of a range, which is one of "a", "a..b", "a..",
+// "..b", ">a", ">=a", "<b" and "<=b". The bounds of ranges are inclusive, so
+// next is used to turn ">a" into the bound just after a and "<b" into the
+// bound just before b.
+func parseRange[T any](value string, parse func(string) (T, error), next func(T, int) T) (*T, *T, error) {
+	bound := func(s string) (*T, error) {
+		if s == "" {
+			return nil, nil
+		}
+		parsed, err := parse(s)
+		if err != nil {
+			return nil, err
+		}
+		return &parsed, nil
+	}
+
+	exclusive := func(s string, direction int) (*T, error) {
+		parsed, err := bound(s)
+		if err != nil || parsed == nil {
+			return nil, errors.New("missing bound")
+		}
+		after := next(*parsed, direction)
+		return &after, nil
+	}
+
+	switch {
+	case strings.HasPrefix(value, ">="):
+		min, err := bound(value[2:])
+		if min == nil && err == nil {
+			err = errors.New("missing bound")
+		}
+		return min, nil, err
+	case strings.HasPrefix(value, "<="):
+		max, err := bound(value[2:])
+		if max == nil && err == nil {
+			err = errors.New("missing bound")
+		}
+		return nil, max, err
+	case strings.HasPrefix(value, ">"):
+		min, err := exclusive(value[1:], 1)
+		return min, nil, err
+	case strings.HasPrefix(value, "<"):
+		max, err := exclusive(value[1:], -1)
+		return nil, max, err
+	}
+
+	from, to, isRange := strings.Cut(value, "..")
+	if !isRange {
+		to = from
+	}
+	if from == "" && to == "" {
+		return nil, nil, errors.New("missing bound")
+	}
+
+	min, err := bound(from)
+	if err != nil {
+		return nil, nil, err
+	}
+	max, err := bound(to)
+	if err != nil {
+		return nil, nil, err
+	}
+	return min, max, nil
+}
+
+func parseInt64(s string) (int64, error) {
+	return strconv.ParseInt(s, 10, 64)
+}
+
+func nextInt64(i int64, direction int) int64 {
+	return i + int64(direction)
+}
+
+func parseFloat(s string) (float64, error) {
+	f, err := strconv.ParseFloat(s, 64)
+	if err == nil && (math.IsNaN(f) || math.IsInf(f, 0)) {
+		return 0, errors.New("not a finite number")
+	}
+	return f, err
+}
+
+func nextFloat(f float64, direction int) float64 {
+	return math.Nextafter(f, math.Inf(direction))
+}
+
+// setOnce sets dst to value, unless dst is already set by an earlier term or
+// a query parameter.
+func setOnce[T comparable](v *validator.Validator, key string, dst *T, value T) {
+	var zero T
+	if value == zero {
+		return
+	}
+	if *dst != zero {
+		v.AddError(key, "must only be given once and not together with the matching query parameter")
+		return
+	}
+	*dst = value
+}
+
+// ParseMovieQuery parses a movie query like
+//
+//	kind:movie year:1990..1999 genre:"Film Noir" cast:"Humphrey Bogart" rating:>7 sort:-date
+//
+// into the filters and returns the sort of the query, if any. Words without a
+// field search the title. Errors are added to v with the key q.<field>. The
+// filters are still to be checked with ValidateMovieFilters.
+func ParseMovieQuery(v *validator.Validator, q string, f *MovieFilters) string {
+	terms, err := tokenizeQuery(q)
+	if err != nil {
+		v.AddError("q", err.Error())
+		return ""
+	}
+
+	words := []string{}
+	sort := ""
+
+	for _, term := range terms {
+		key := "q." + term.field
+		value := strings.TrimSpace(term.value)
+
+		if term.field == "" {
+			words = append(words, value)
+			continue
+		}
+		if value == "" {
+			v.AddError(key, "must have a value")
+			continue
+		}
+
+		switch term.field {
+		case "kind":
+			value = strings.ToLower(value)
+			if !validator.PermittedValue(value, "movie", "series", "season", "episode", "movieseries") {
+				v.AddError(key, "must be one of the following values: movie, series, season, episode, movieseries")
+				continue
+			}
+			setOnce(v, key, &f.Kind, value)
+		case "country":
+			value = strings.ToUpper(value)
+			if !validator.Matches(value, validator.CountryCodeRX) {
+				v.AddError(key, "must be a two letter ISO 3166-1 code")
+				continue
+			}
+			setOnce(v, key, &f.Country, value)
+		case "language":
+			value = strings.ToLower(value)
+			if !validator.Matches(value, validator.LanguageCodeRX) {
+				v.AddError(key, "must be a two letter ISO 639-1 code")
+				continue
+			}
+			setOnce(v, key, &f.Language, value)
+		case "sort":
+			setOnce(v, key, &sort, value)
+
+		case "genre":
+			f.CategoryNames = append(f.CategoryNames, value)
+		case "keyword":
+			f.KeywordNames = append(f.KeywordNames, value)
+		case "cast":
+			f.PersonNames = append(f.PersonNames, value)
+
+		case "year":
+			min, max, err := parseRange(value, parseInt64, nextInt64)
+			if err != nil {
+				v.AddError(key, "must be a year or a range like 1990..1999, >1990 or <=1999")
+				continue
+			}
+			inRange := func(year *int64) bool {
+				return year == nil || (*year >= 1800 && *year <= 9999)
+			}
+			if !inRange(min) || !inRange(max) {
+				v.AddError(key, "must be a year between 1800 and 9999")
+				continue
+			}
+			if min != nil {
+				from := time.Date(int(*min), time.January, 1, 0, 0, 0, 0, time.UTC)
+				setOnce(v, key, &f.DateFrom, &from)
+			}
+			if max != nil {
+				to := time.Date(int(*max), time.December, 31, 0, 0, 0, 0, time.UTC)
+				setOnce(v, key, &f.DateTo, &to)
+			}
+
+		case "runtime", "votes":
+			min, max, err := parseRange(value, parseInt64, nextInt64)
+			if err != nil {
+				v.AddError(key, "must be an integer or a range like 90..120, >90 or <=120")
+				continue
+			}
+			if term.field == "runtime" {
+				setOnce(v, key, &f.RuntimeMin, min)
+				setOnce(v, key, &f.RuntimeMax, max)
+			} else {
+				setOnce(v, key, &f.VotesCountMin, min)
+				setOnce(v, key, &f.VotesCountMax, max)
+			}
+
+		case "rating", "budget", "revenue":
+			min, max, err := parseRange(value, parseFloat, nextFloat)
+			if err != nil {
+				v.AddError(key, "must be a number or a range like 6.5..8, >7 or <=8")
+				continue
+			}
+			switch term.field {
+			case "rating":
+				setOnce(v, key, &f.VoteAvgMin, min)
+				setOnce(v, key, &f.VoteAvgMax, max)
+			case "budget":
+				setOnce(v, key, &f.BudgetMin, min)
+				setOnce(v, key, &f.BudgetMax, max)
+			case "revenue":
+				setOnce(v, key, &f.RevenueMin, min)
+				setOnce(v, key, &f.RevenueMax, max)
+			}
+
+		default:
+			v.AddError(key, fmt.Sprintf("unknown field, must be one of the following: %s", strings.Join(MovieQueryFields, ", ")))
+		}
+	}
+
+	setOnce(v, "q", &f.Name, strings.Join(words, " "))
+
+	return sort
+}
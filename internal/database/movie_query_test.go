@@ -0,0 +1,251 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Torkel-Aannestad/OMDB-api/internal/validator"
+)
+
+func TestTokenizeQuery(t *testing.T) {
+	tests := []struct {
+		name    string
+		q       string
+		want    []queryTerm
+		wantErr bool
+	}{
+		{name: "empty", q: "", want: []queryTerm{}},
+		{name: "only spaces", q: "  \t ", want: []queryTerm{}},
+		{name: "word", q: "alien", want: []queryTerm{{value: "alien"}}},
+		{name: "field", q: "kind:movie", want: []queryTerm{{field: "kind", value: "movie"}}},
+		{name: "field is lower cased", q: "KIND:Movie", want: []queryTerm{{field: "kind", value: "Movie"}}},
+		{
+			name: "words and fields",
+			q:    "the  thing year:1982\tkind:movie",
+			want: []queryTerm{{value: "the"}, {value: "thing"}, {field: "year", value: "1982"}, {field: "kind", value: "movie"}},
+		},
+		{name: "quoted value", q: `genre:"Film Noir"`, want: []queryTerm{{field: "genre", value: "Film Noir"}}},
+		{name: "quoted words", q: `"star wars"`, want: []queryTerm{{value: "star wars"}}},
+		{name: "colon in quotes", q: `"alien: covenant"`, want: []queryTerm{{value: "alien: covenant"}}},
+		{name: "second colon is part of the value", q: "a:b:c", want: []queryTerm{{field: "a", value: "b:c"}}},
+		{name: "leading colon", q: ":movie", want: []queryTerm{{value: ":movie"}}},
+		{name: "empty value", q: "kind:", want: []queryTerm{{field: "kind", value: ""}}},
+		{name: "empty quotes", q: `cast:""`, want: []queryTerm{{field: "cast", value: ""}}},
+		{name: "bare empty quotes", q: `""`, want: []queryTerm{{value: ""}}},
+		{name: "unterminated quote", q: `genre:"Film Noir`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tokenizeQuery(tt.q)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("got %v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRange(t *testing.T) {
+	ptr := func(i int64) *int64 { return &i }
+
+	tests := []struct {
+		name    string
+		value   string
+		min     *int64
+		max     *int64
+		wantErr bool
+	}{
+		{name: "single value", value: "5", min: ptr(5), max: ptr(5)},
+		{name: "range", value: "1..10", min: ptr(1), max: ptr(10)},
+		{name: "open end", value: "1..", min: ptr(1)},
+		{name: "open start", value: "..10", max: ptr(10)},
+		{name: "greater than", value: ">5", min: ptr(6)},
+		{name: "greater than or equal", value: ">=5", min: ptr(5)},
+		{name: "less than", value: "<5", max: ptr(4)},
+		{name: "less than or equal", value: "<=5", max: ptr(5)},
+		{name: "negative", value: "-5..-1", min: ptr(-5), max: ptr(-1)},
+		{name: "empty", value: "", wantErr: true},
+		{name: "only dots", value: "..", wantErr: true},
+		{name: "greater than without bound", value: ">", wantErr: true},
+		{name: "greater than or equal without bound", value: ">=", wantErr: true},
+		{name: "less than without bound", value: "<", wantErr: true},
+		{name: "less than or equal without bound", value: "<=", wantErr: true},
+		{name: "not a number", value: "abc", wantErr: true},
+		{name: "bad start", value: "a..10", wantErr: true},
+		{name: "bad end", value: "1..b", wantErr: true},
+		{name: "three dots", value: "1...10", wantErr: true},
+		{name: "overflow", value: "99999999999999999999", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			min, max, err := parseRange(tt.value, parseInt64, nextInt64)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("got %v..%v, want an error", min, max)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(min, tt.min) {
+				t.Errorf("got min %v, want %v", min, tt.min)
+			}
+			if !reflect.DeepEqual(max, tt.max) {
+				t.Errorf("got max %v, want %v", max, tt.max)
+			}
+		})
+	}
+}
+
+func TestParseRangeFloat(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		wantErr bool
+	}{
+		{name: "decimal range", value: "6.5..8"},
+		{name: "greater than", value: ">7"},
+		{name: "not a number", value: "NaN", wantErr: true},
+		{name: "infinity", value: ">Inf", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := parseRange(tt.value, parseFloat, nextFloat)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("got error %v, want error %t", err, tt.wantErr)
+			}
+		})
+	}
+
+	min, _, err := parseRange(">7", parseFloat, nextFloat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *min <= 7 {
+		t.Errorf("got min %v, want just above 7", *min)
+	}
+}
+
+func TestParseMovieQuery(t *testing.T) {
+	date := func(year int, month time.Month, day int) *time.Time {
+		d := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+		return &d
+	}
+	ptr := func(i int64) *int64 { return &i }
+	fptr := func(f float64) *float64 { return &f }
+
+	tests := []struct {
+		name       string
+		q          string
+		filters    MovieFilters
+		want       MovieFilters
+		wantSort   string
+		wantErrors []string
+	}{
+		{name: "empty", q: ""},
+		{name: "words", q: "the thing", want: MovieFilters{Name: "the thing"}},
+		{
+			name: "fields",
+			q:    `kind:Movie country:us language:EN genre:"Film Noir" keyword:heist cast:"Humphrey Bogart"`,
+			want: MovieFilters{
+				Kind:          "movie",
+				Country:       "US",
+				Language:      "en",
+				CategoryNames: []string{"Film Noir"},
+				KeywordNames:  []string{"heist"},
+				PersonNames:   []string{"Humphrey Bogart"},
+			},
+		},
+		{
+			name: "repeated list fields",
+			q:    "genre:Drama genre:Crime",
+			want: MovieFilters{CategoryNames: []string{"Drama", "Crime"}},
+		},
+		{name: "sort", q: "sort:-date", wantSort: "-date"},
+		{
+			name: "year range",
+			q:    "year:1990..1999",
+			want: MovieFilters{DateFrom: date(1990, time.January, 1), DateTo: date(1999, time.December, 31)},
+		},
+		{name: "year after", q: "year:>1990", want: MovieFilters{DateFrom: date(1991, time.January, 1)}},
+		{name: "lowest year", q: "year:1800", want: MovieFilters{DateFrom: date(1800, time.January, 1), DateTo: date(1800, time.December, 31)}},
+		{name: "highest year", q: "year:9999", want: MovieFilters{DateFrom: date(9999, time.January, 1), DateTo: date(9999, time.December, 31)}},
+		{name: "year too low", q: "year:1799", wantErrors: []string{"q.year"}},
+		{name: "year too high", q: "year:10000", wantErrors: []string{"q.year"}},
+		{name: "year after the highest year", q: "year:>9999", wantErrors: []string{"q.year"}},
+		{name: "year before the lowest year", q: "year:<1800", wantErrors: []string{"q.year"}},
+		{name: "huge year", q: "year:..99999999999", wantErrors: []string{"q.year"}},
+		{name: "year not a number", q: "year:soon", wantErrors: []string{"q.year"}},
+		{
+			name: "integer ranges",
+			q:    "runtime:90..120 votes:>=1000",
+			want: MovieFilters{RuntimeMin: ptr(90), RuntimeMax: ptr(120), VotesCountMin: ptr(1000)},
+		},
+		{
+			name: "number ranges",
+			q:    "rating:6.5..8 budget:<=1000000 revenue:1000..",
+			want: MovieFilters{VoteAvgMin: fptr(6.5), VoteAvgMax: fptr(8), BudgetMax: fptr(1000000), RevenueMin: fptr(1000)},
+		},
+		{name: "runtime not an integer", q: "runtime:1.5", wantErrors: []string{"q.runtime"}},
+		{name: "rating without bound", q: "rating:>", wantErrors: []string{"q.rating"}},
+		{name: "empty value", q: "kind:", wantErrors: []string{"q.kind"}},
+		{name: "empty quoted value", q: `cast:""`, wantErrors: []string{"q.cast"}},
+		{name: "bad kind", q: "kind:film", wantErrors: []string{"q.kind"}},
+		{name: "bad country", q: "country:usa", wantErrors: []string{"q.country"}},
+		{name: "bad language", q: "language:english", wantErrors: []string{"q.language"}},
+		{name: "unknown field", q: "director:Nolan", wantErrors: []string{"q.director"}},
+		{name: "unterminated quote", q: `cast:"Humphrey`, wantErrors: []string{"q"}},
+		{name: "field given twice", q: "kind:movie kind:series", want: MovieFilters{Kind: "movie"}, wantErrors: []string{"q.kind"}},
+		{
+			name:       "field given as query parameter",
+			q:          "country:US",
+			filters:    MovieFilters{Country: "NO"},
+			want:       MovieFilters{Country: "NO"},
+			wantErrors: []string{"q.country"},
+		},
+		{
+			name:       "words given as query parameter",
+			q:          "alien",
+			filters:    MovieFilters{Name: "aliens"},
+			want:       MovieFilters{Name: "aliens"},
+			wantErrors: []string{"q"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := validator.New()
+			filters := tt.filters
+
+			sort := ParseMovieQuery(v, tt.q, &filters)
+
+			for _, key := range tt.wantErrors {
+				if _, found := v.Errors[key]; !found {
+					t.Errorf("missing error for %s, got %v", key, v.Errors)
+				}
+			}
+			if len(v.Errors) != len(tt.wantErrors) {
+				t.Errorf("got errors %v, want errors for %v", v.Errors, tt.wantErrors)
+			}
+			if sort != tt.wantSort {
+				t.Errorf("got sort %q, want %q", sort, tt.wantSort)
+			}
+			if !reflect.DeepEqual(filters, tt.want) {
+				t.Errorf("got filters %+v, want %+v", filters, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,399 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/Torkel-Aannestad/OMDB-api/internal/validator"
+	"github.com/lib/pq"
+)
+
+var MovieReferenceTypes = []string{"Remake", "SpinOff", "Influence", "Parody", "Homage", "Reference"}
+
+// movieReferenceRelations holds the wording used for an edge read from movie_id
+// to referenced_id, and the inverse wording read from referenced_id to movie_id.
+var movieReferenceRelations = map[string][2]string{
+	"Remake":    {"remake of", "remade as"},
+	"SpinOff":   {"spin-off of", "spun off as"},
+	"Influence": {"influenced by", "influenced"},
+	"Parody":    {"parody of", "parodied by"},
+	"Homage":    {"homage to", "paid homage by"},
+	"Reference": {"references", "referenced by"},
+}
+
+type MovieReference struct {
+	ID           int64     `json:"id"`
+	MovieID      int64     `json:"movie_id"`
+	ReferencedID int64     `json:"referenced_id"`
+	Type         string    `json:"type"`
+	Version      int32     `json:"version"`
+	CreatedAt    time.Time `json:"-"`
+	ModifiedAt   time.Time `json:"-"`
+}
+
+type MovieReferenceNode struct {
+	ID    int64     `json:"id"`
+	Name  string    `json:"name"`
+	Date  time.Time `json:"date"`
+	Kind  string    `json:"kind"`
+	Depth int       `json:"depth"`
+}
+
+type MovieReferenceEdge struct {
+	MovieID         int64  `json:"movie_id"`
+	ReferencedID    int64  `json:"referenced_id"`
+	Type            string `json:"type"`
+	Relation        string `json:"relation"`
+	InverseRelation string `json:"inverse_relation"`
+	Depth           int    `json:"depth"`
+}
+
+type MovieReferenceGraph struct {
+	MovieID int64                 `json:"movie_id"`
+	Depth   int                   `json:"depth"`
+	Nodes   []*MovieReferenceNode `json:"nodes"`
+	Edges   []*MovieReferenceEdge `json:"edges"`
+}
+
+type MovieReferencesModel struct {
+	DB *sql.DB
+}
+
+func (m MovieReferencesModel) Insert(movieReference *MovieReference) error {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	query := `
+	INSERT INTO movie_references (
+		movie_id,
+		referenced_id,
+		type
+	)
+	VALUES ($1, $2, $3)
+	RETURNING id, created_at, modified_at, version`
+
+	args := []any{
+		movieReference.MovieID,
+		movieReference.ReferencedID,
+		movieReference.Type,
+	}
+
+	return m.DB.QueryRowContext(ctx, query, args...).Scan(
+		&movieReference.ID,
+		&movieReference.CreatedAt,
+		&movieReference.ModifiedAt,
+		&movieReference.Version,
+	)
+}
+
+func (m MovieReferencesModel) Get(id int64) (*MovieReference, error) {
+	if id < 0 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `
+	SELECT
+		id,
+		movie_id,
+		referenced_id,
+		type,
+		version,
+		created_at,
+		modified_at
+	FROM movie_references
+	WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	var movieReference MovieReference
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(
+		&movieReference.ID,
+		&movieReference.MovieID,
+		&movieReference.ReferencedID,
+		&movieReference.Type,
+		&movieReference.Version,
+		&movieReference.CreatedAt,
+		&movieReference.ModifiedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrRecordNotFound
+		} else {
+			return nil, err
+		}
+	}
+
+	return &movieReference, nil
+}
+
+// GetByMovieID returns the references where the movie is either the referencing
+// or the referenced movie.
+func (m MovieReferencesModel) GetByMovieID(movieID int64) ([]*MovieReference, error) {
+	if movieID < 0 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `
+	SELECT
+		id,
+		movie_id,
+		referenced_id,
+		type,
+		version
+	FROM movie_references
+	WHERE movie_id = $1 OR referenced_id = $1
+	ORDER BY id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, movieID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	movieReferences := []*MovieReference{}
+
+	for rows.Next() {
+		var movieReference MovieReference
+
+		err := rows.Scan(
+			&movieReference.ID,
+			&movieReference.MovieID,
+			&movieReference.ReferencedID,
+			&movieReference.Type,
+			&movieReference.Version,
+		)
+		if err != nil {
+			return nil, err
+		}
+		movieReferences = append(movieReferences, &movieReference)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return movieReferences, nil
+}
+
+// GetGraph walks the reference graph from movieID up to maxDepth edges away.
+// Direction is one of "both", "outgoing" (movies this movie references) or
+// "incoming" (movies referencing this movie). Each edge is reported once with
+// the shortest depth it was reached at.
+func (m MovieReferencesModel) GetGraph(movieID int64, maxDepth int, direction string) (*MovieReferenceGraph, error) {
+	if movieID < 0 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `
+	SELECT movie_id, referenced_id, type
+	FROM movie_references
+	WHERE ($2 IN ('both', 'outgoing') AND movie_id = ANY($1))
+	OR ($2 IN ('both', 'incoming') AND referenced_id = ANY($1))
+	ORDER BY movie_id, referenced_id, type`
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	graph := MovieReferenceGraph{
+		MovieID: movieID,
+		Depth:   maxDepth,
+		Nodes:   []*MovieReferenceNode{},
+		Edges:   []*MovieReferenceEdge{},
+	}
+
+	// The graph is walked breadth first with one query per depth, so that each
+	// movie is reached at its shortest distance from the starting movie and
+	// only the movies found at the previous depth are expanded.
+	nodeDepths := map[int64]int{movieID: 0}
+	nodeIDs := []int64{movieID}
+	seenEdges := make(map[MovieReferenceEdge]bool)
+	frontier := []int64{movieID}
+
+	for depth := 1; depth <= maxDepth && len(frontier) > 0; depth++ {
+		edges, err := m.getEdges(ctx, query, frontier, direction)
+		if err != nil {
+			return nil, err
+		}
+
+		frontier = []int64{}
+		for _, edge := range edges {
+			key := MovieReferenceEdge{MovieID: edge.MovieID, ReferencedID: edge.ReferencedID, Type: edge.Type}
+			if seenEdges[key] {
+				continue
+			}
+			seenEdges[key] = true
+
+			relation, ok := movieReferenceRelations[edge.Type]
+			if !ok {
+				relation = movieReferenceRelations["Reference"]
+			}
+			edge.Relation = relation[0]
+			edge.InverseRelation = relation[1]
+			edge.Depth = depth
+
+			for _, id := range []int64{edge.MovieID, edge.ReferencedID} {
+				if _, seen := nodeDepths[id]; !seen {
+					nodeDepths[id] = depth
+					nodeIDs = append(nodeIDs, id)
+					frontier = append(frontier, id)
+				}
+			}
+
+			graph.Edges = append(graph.Edges, edge)
+		}
+	}
+
+	nodeQuery := `
+	SELECT id, name, date, kind
+	FROM movies
+	WHERE id = ANY($1)`
+
+	nodeRows, err := m.DB.QueryContext(ctx, nodeQuery, pq.Array(nodeIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer nodeRows.Close()
+
+	nodes := make(map[int64]*MovieReferenceNode, len(nodeIDs))
+
+	for nodeRows.Next() {
+		var node MovieReferenceNode
+
+		err := nodeRows.Scan(
+			&node.ID,
+			&node.Name,
+			&node.Date,
+			&node.Kind,
+		)
+		if err != nil {
+			return nil, err
+		}
+		node.Depth = nodeDepths[node.ID]
+		nodes[node.ID] = &node
+	}
+
+	err = nodeRows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, found := nodes[movieID]; !found {
+		return nil, ErrRecordNotFound
+	}
+
+	for _, id := range nodeIDs {
+		if node, found := nodes[id]; found {
+			graph.Nodes = append(graph.Nodes, node)
+		}
+	}
+
+	return &graph, nil
+}
+
+// getEdges returns the references of the movies in ids for GetGraph.
+func (m MovieReferencesModel) getEdges(ctx context.Context, query string, ids []int64, direction string) ([]*MovieReferenceEdge, error) {
+	rows, err := m.DB.QueryContext(ctx, query, pq.Array(ids), direction)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	edges := []*MovieReferenceEdge{}
+	for rows.Next() {
+		var edge MovieReferenceEdge
+
+		err := rows.Scan(
+			&edge.MovieID,
+			&edge.ReferencedID,
+			&edge.Type,
+		)
+		if err != nil {
+			return nil, err
+		}
+		edges = append(edges, &edge)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return edges, nil
+}
+
+func (m MovieReferencesModel) Update(movieReference *MovieReference) error {
+	query := `
+	UPDATE movie_references
+	SET
+		movie_id = $3,
+		referenced_id = $4,
+		type = $5,
+		modified_at = NOW(),
+		version = version + 1
+	WHERE id = $1 and version = $2
+	RETURNING version`
+
+	args := []any{
+		&movieReference.ID,
+		&movieReference.Version,
+		&movieReference.MovieID,
+		&movieReference.ReferencedID,
+		&movieReference.Type,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&movieReference.Version)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrEditConflict
+		} else {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m MovieReferencesModel) Delete(id int64) error {
+	if id < 0 {
+		return ErrRecordNotFound
+	}
+
+	stmt := `
+		DELETE FROM movie_references WHERE id = $1
+	`
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, stmt, id)
+	if err != nil {
+		return err
+	}
+	affectedRows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affectedRows == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
+
+func ValidateMovieReference(v *validator.Validator, movieReference *MovieReference) {
+	v.Check(movieReference.MovieID != 0, "movie_id", "must be provided")
+	v.Check(movieReference.MovieID > 0, "movie_id", "must be a positive number")
+
+	v.Check(movieReference.ReferencedID != 0, "referenced_id", "must be provided")
+	v.Check(movieReference.ReferencedID > 0, "referenced_id", "must be a positive number")
+	v.Check(movieReference.ReferencedID != movieReference.MovieID, "referenced_id", "must not be the same as movie_id")
+
+	v.Check(validator.PermittedValue(movieReference.Type, MovieReferenceTypes...), "type", "must be one of the following values: Remake, SpinOff, Influence, Parody, Homage, Reference")
+}
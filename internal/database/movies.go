@@ -8,25 +8,31 @@ import (
 	"time"
 
 	"github.com/Torkel-Aannestad/OMDB-api/internal/validator"
+	"github.com/lib/pq"
 )
 
 type Movie struct {
-	ID          int64     `json:"id"`
-	ParentID    NullInt64 `json:"parent_id"`
-	SeriesID    NullInt64 `json:"series_id"`
-	Name        string    `json:"name"`
-	Date        time.Time `json:"date"`
-	Kind        string    `json:"kind"`
-	Runtime     int64     `json:"runtime"`
-	Budget      float64   `json:"budget"`
-	Revenue     float64   `json:"revenue"`
-	Homepage    string    `json:"homepage"`
-	VoteAvarage float64   `json:"vote_average"`
-	VoteCount   int64     `json:"vote_count"`
-	Abstract    string    `json:"abstract"`
-	Version     int32     `json:"version"`
-	CreatedAt   time.Time `json:"-"`
-	ModifiedAt  time.Time `json:"-"`
+	ID               int64     `json:"id"`
+	ParentID         NullInt64 `json:"parent_id"`
+	SeriesID         NullInt64 `json:"series_id"`
+	Name             string    `json:"name"`
+	Title            string    `json:"title"`
+	Date             time.Time `json:"date"`
+	Kind             string    `json:"kind"`
+	Runtime          int64     `json:"runtime"`
+	Budget           float64   `json:"budget"`
+	Revenue          float64   `json:"revenue"`
+	Homepage         string    `json:"homepage"`
+	VoteAvarage      float64   `json:"vote_average"`
+	VoteCount        int64     `json:"vote_count"`
+	Abstract         string    `json:"abstract"`
+	AbstractLanguage string    `json:"abstract_language"`
+	Countries        []string  `json:"countries"`
+	Languages        []string  `json:"languages"`
+	Aliases          []string  `json:"aliases"`
+	Version          int32     `json:"version"`
+	CreatedAt        time.Time `json:"-"`
+	ModifiedAt       time.Time `json:"-"`
 }
 
 type MovieModel struct {
@@ -77,54 +83,65 @@ func (m MovieModel) Insert(movie *Movie) error {
 	)
 }
 
+// movieColumns are the columns of a movie that ?fields= can leave out. The id
+// and the bookkeeping columns are always selected.
+var movieColumns = []column[Movie]{
+	{"id", nil, func(m *Movie) any { return &m.ID }},
+	{"name", []string{"name", "title"}, func(m *Movie) any { return &m.Name }},
+	{"parent_id", []string{"parent_id"}, func(m *Movie) any { return &m.ParentID }},
+	{"date", []string{"date"}, func(m *Movie) any { return &m.Date }},
+	{"series_id", []string{"series_id"}, func(m *Movie) any { return &m.SeriesID }},
+	{"kind", []string{"kind"}, func(m *Movie) any { return &m.Kind }},
+	{"runtime", []string{"runtime"}, func(m *Movie) any { return &m.Runtime }},
+	{"budget", []string{"budget"}, func(m *Movie) any { return &m.Budget }},
+	{"revenue", []string{"revenue"}, func(m *Movie) any { return &m.Revenue }},
+	{"homepage", []string{"homepage"}, func(m *Movie) any { return &m.Homepage }},
+	{"vote_average", []string{"vote_average"}, func(m *Movie) any { return &m.VoteAvarage }},
+	{"votes_count", []string{"vote_count"}, func(m *Movie) any { return &m.VoteCount }},
+	{"abstract", []string{"abstract", "abstract_language"}, func(m *Movie) any { return &m.Abstract }},
+	{
+		"ARRAY(SELECT country_code FROM movie_countries WHERE movie_id = movies.id ORDER BY country_code)",
+		[]string{"countries"},
+		func(m *Movie) any { return pq.Array(&m.Countries) },
+	},
+	{
+		"ARRAY(SELECT language_code FROM movie_languages WHERE movie_id = movies.id ORDER BY language_code)",
+		[]string{"languages"},
+		func(m *Movie) any { return pq.Array(&m.Languages) },
+	},
+	{
+		"ARRAY(SELECT DISTINCT name FROM movie_aliases WHERE movie_id = movies.id ORDER BY name)",
+		[]string{"aliases"},
+		func(m *Movie) any { return pq.Array(&m.Aliases) },
+	},
+	{"created_at", nil, func(m *Movie) any { return &m.CreatedAt }},
+	{"modified_at", nil, func(m *Movie) any { return &m.ModifiedAt }},
+	{"version", nil, func(m *Movie) any { return &m.Version }},
+}
+
 func (m MovieModel) Get(id int64) (*Movie, error) {
+	return m.GetFields(id, nil)
+}
+
+// GetFields returns the movie with only the columns needed for fields read
+// from the database.
+func (m MovieModel) GetFields(id int64, fields Fields) (*Movie, error) {
 	if id < 0 {
 		return nil, ErrRecordNotFound
 	}
 	movie := Movie{}
 
-	query := `
-	SELECT 
-		id,
-		name,
-		parent_id,
-		date,
-		series_id,
-		kind,
-		runtime,
-		budget,
-		revenue,
-		homepage,
-		vote_average,
-		votes_count,
-		abstract,
-		created_at,
-		modified_at,
-		version
+	columns, dests := selectColumns(movieColumns, fields)
+
+	query := fmt.Sprintf(`
+	SELECT %s
 	FROM movies
-	WHERE id = $1;`
+	WHERE id = $1;`, columns)
 
 	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
 	defer cancel()
 
-	err := m.DB.QueryRowContext(ctx, query, id).Scan(
-		&movie.ID,
-		&movie.Name,
-		&movie.ParentID,
-		&movie.Date,
-		&movie.SeriesID,
-		&movie.Kind,
-		&movie.Runtime,
-		&movie.Budget,
-		&movie.Revenue,
-		&movie.Homepage,
-		&movie.VoteAvarage,
-		&movie.VoteCount,
-		&movie.Abstract,
-		&movie.CreatedAt,
-		&movie.ModifiedAt,
-		&movie.Version,
-	)
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(dests(&movie)...)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, ErrRecordNotFound
@@ -132,75 +149,227 @@ func (m MovieModel) Get(id int64) (*Movie, error) {
 			return nil, err
 		}
 	}
+	movie.Title = movie.Name
+	movie.AbstractLanguage = DefaultAbstractLanguage
 
 	return &movie, nil
 }
 
-func (m MovieModel) GetAll(name string, kind string, filters Filters) ([]*Movie, Metadata, error) {
+// GetByIDs returns the movies with the ids in the order of ids, and the ids
+// that were not found.
+func (m MovieModel) GetByIDs(ids []int64, fields Fields) ([]*Movie, []int64, error) {
+	columns, dests := selectColumns(movieColumns, fields)
 
-	sortColumn := filters.getSortColumn()
-	sortDirection := filters.getSortDirection()
+	query := fmt.Sprintf(`
+	SELECT %s
+	FROM movies
+	WHERE id = ANY($1)`, columns)
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
 
-	var kindFilter string
-	if kind != "" {
-		kindFilter = "kind = $4"
-	} else {
-		kindFilter = "1=1"
+	rows, err := m.DB.QueryContext(ctx, query, pq.Array(ids))
+	if err != nil {
+		return nil, nil, err
 	}
+	defer rows.Close()
 
-	query := fmt.Sprintf(`
-		SELECT count(*) OVER(), id, name, parent_id, date, series_id, kind, runtime, budget, revenue, homepage, vote_average, votes_count, abstract, created_at, modified_at, version
-		FROM movies
-		WHERE (to_tsvector('simple', name) @@ plainto_tsquery('simple', $1) OR $1 = '')
-		AND (%s)
-		ORDER BY %s %s, id ASC
-		LIMIT $2 OFFSET $3
-	`, kindFilter, sortColumn, sortDirection)
+	movies := []*Movie{}
+
+	for rows.Next() {
+		var movie Movie
+
+		err := rows.Scan(dests(&movie)...)
+		if err != nil {
+			return nil, nil, err
+		}
+		movie.Title = movie.Name
+		movie.AbstractLanguage = DefaultAbstractLanguage
+		movies = append(movies, &movie)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	movies, missing := inRequestOrder(ids, movies, func(movie *Movie) int64 { return movie.ID })
+
+	return movies, missing, nil
+}
+
+// MovieFilters holds the filters of the movie list. Empty strings, nil
+// pointers and empty lists are not filtered on. Ranges include both ends.
+// CategoryIDs, KeywordIDs and PersonIDs match movies having all of the ids,
+// and CategoryNames, KeywordNames and PersonNames movies having all of the
+// names, ignoring case and accents.
+type MovieFilters struct {
+	Name          string
+	Kind          string
+	Country       string
+	Language      string
+	DateFrom      *time.Time
+	DateTo        *time.Time
+	RuntimeMin    *int64
+	RuntimeMax    *int64
+	VoteAvgMin    *float64
+	VoteAvgMax    *float64
+	VotesCountMin *int64
+	VotesCountMax *int64
+	BudgetMin     *float64
+	BudgetMax     *float64
+	RevenueMin    *float64
+	RevenueMax    *float64
+	ParentID      *int64
+	SeriesID      *int64
+	CategoryIDs   []int64
+	KeywordIDs    []int64
+	PersonIDs     []int64
+	CategoryNames []string
+	KeywordNames  []string
+	PersonNames   []string
+}
+
+// apply adds the conditions of the filters to the query.
+func (f MovieFilters) apply(q *queryBuilder) {
+	if f.Name != "" {
+		// Match like the movies of /v1/search, so that case, accents, typos
+		// and missing spaces are ignored.
+		q.where(`to_tsvector('simple', movies.name) @@ plainto_tsquery('simple', ?) OR search_key(?) <% search_key(movies.name)
+			OR EXISTS (SELECT 1 FROM movie_aliases WHERE movie_id = movies.id
+				AND (to_tsvector('simple', movie_aliases.name) @@ plainto_tsquery('simple', ?) OR search_key(?) <% search_key(movie_aliases.name)))`, f.Name, f.Name, f.Name, f.Name)
+	}
+	if f.Kind != "" {
+		q.where("movies.kind = ?", f.Kind)
+	}
+	if f.Country != "" {
+		q.where("EXISTS (SELECT 1 FROM movie_countries WHERE movie_id = movies.id AND country_code = ?)", f.Country)
+	}
+	if f.Language != "" {
+		q.where("EXISTS (SELECT 1 FROM movie_languages WHERE movie_id = movies.id AND language_code = ?)", f.Language)
+	}
+
+	whereRange(q, "movies.date", f.DateFrom, f.DateTo)
+	whereRange(q, "movies.runtime", f.RuntimeMin, f.RuntimeMax)
+	whereRange(q, "movies.vote_average", f.VoteAvgMin, f.VoteAvgMax)
+	whereRange(q, "movies.votes_count", f.VotesCountMin, f.VotesCountMax)
+	whereRange(q, "movies.budget", f.BudgetMin, f.BudgetMax)
+	whereRange(q, "movies.revenue", f.RevenueMin, f.RevenueMax)
+
+	if f.ParentID != nil {
+		q.where("movies.parent_id = ?", *f.ParentID)
+	}
+	if f.SeriesID != nil {
+		q.where("movies.series_id = ?", *f.SeriesID)
+	}
+	if len(f.CategoryIDs) > 0 {
+		q.where("(SELECT count(DISTINCT category_id) FROM movie_categories WHERE movie_id = movies.id AND category_id = ANY(?)) = ?", pq.Array(f.CategoryIDs), len(f.CategoryIDs))
+	}
+	if len(f.KeywordIDs) > 0 {
+		q.where("(SELECT count(DISTINCT category_id) FROM movie_keywords WHERE movie_id = movies.id AND category_id = ANY(?)) = ?", pq.Array(f.KeywordIDs), len(f.KeywordIDs))
+	}
+	if len(f.PersonIDs) > 0 {
+		q.where("(SELECT count(DISTINCT person_id) FROM casts WHERE movie_id = movies.id AND person_id = ANY(?)) = ?", pq.Array(f.PersonIDs), len(f.PersonIDs))
+	}
+	for _, name := range f.CategoryNames {
+		q.where("movies.id IN (SELECT movie_id FROM movie_categories JOIN categories ON categories.id = movie_categories.category_id WHERE search_key(categories.name) = search_key(?))", name)
+	}
+	for _, name := range f.KeywordNames {
+		q.where("movies.id IN (SELECT movie_id FROM movie_keywords JOIN categories ON categories.id = movie_keywords.category_id WHERE search_key(categories.name) = search_key(?))", name)
+	}
+	for _, name := range f.PersonNames {
+		q.where("movies.id IN (SELECT movie_id FROM casts JOIN people ON people.id = casts.person_id WHERE search_key(people.name) = search_key(?))", name)
+	}
+}
+
+func ValidateMovieFilters(v *validator.Validator, f MovieFilters) {
+	if f.Kind != "" {
+		ValidateKind(v, &f.Kind)
+	}
+	if f.Country != "" {
+		ValidateCountryCode(v, "country", f.Country)
+	}
+	if f.Language != "" {
+		ValidateLanguageCode(v, "language", f.Language)
+	}
+
+	if f.DateFrom != nil && f.DateTo != nil {
+		v.Check(!f.DateFrom.After(*f.DateTo), "date_from", "must not be after date_to")
+	}
+	if f.RuntimeMin != nil && f.RuntimeMax != nil {
+		v.Check(*f.RuntimeMin <= *f.RuntimeMax, "runtime_min", "must not be greater than runtime_max")
+	}
+	if f.VoteAvgMin != nil && f.VoteAvgMax != nil {
+		v.Check(*f.VoteAvgMin <= *f.VoteAvgMax, "vote_average_min", "must not be greater than vote_average_max")
+	}
+	if f.VotesCountMin != nil && f.VotesCountMax != nil {
+		v.Check(*f.VotesCountMin <= *f.VotesCountMax, "votes_count_min", "must not be greater than votes_count_max")
+	}
+	if f.BudgetMin != nil && f.BudgetMax != nil {
+		v.Check(*f.BudgetMin <= *f.BudgetMax, "budget_min", "must not be greater than budget_max")
+	}
+	if f.RevenueMin != nil && f.RevenueMax != nil {
+		v.Check(*f.RevenueMin <= *f.RevenueMax, "revenue_min", "must not be greater than revenue_max")
+	}
+
+	for key, ids := range map[string][]int64{"category_ids": f.CategoryIDs, "keyword_ids": f.KeywordIDs, "person_ids": f.PersonIDs} {
+		v.Check(len(ids) <= 20, key, "must not contain more than 20 ids")
+		v.Check(validator.Unique(ids), key, "must not contain duplicate values")
+		for _, id := range ids {
+			v.Check(id > 0, key, "must only contain positive ids")
+		}
+	}
+	for key, names := range map[string][]string{"genre": f.CategoryNames, "keyword": f.KeywordNames, "cast": f.PersonNames} {
+		v.Check(len(names) <= 20, "q."+key, "must not be given more than 20 times")
+		v.Check(validator.Unique(names), "q."+key, "must not contain duplicate values")
+	}
+}
+
+func (m MovieModel) GetAll(movieFilters MovieFilters, filters Filters, fields Fields) ([]*Movie, Metadata, error) {
+	var q queryBuilder
+	movieFilters.apply(&q)
 
 	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
 	defer cancel()
 
-	args := []any{name}
-	args = append(args, filters.limit(), filters.offset())
-	if kind != "" {
-		args = append(args, kind)
+	totalRecords, err := countRecords(ctx, m.DB, filters, "movies", q)
+	if err != nil {
+		return nil, Metadata{}, err
 	}
 
-	rows, err := m.DB.QueryContext(ctx, query, args...)
+	orderBy := filters.keyset(&q, "movies")
+	columns, dests := selectColumns(movieColumns, fields)
+
+	query := fmt.Sprintf(`
+		SELECT movies.%s::text, %s
+		FROM movies
+		%s
+		ORDER BY %s
+		LIMIT %s OFFSET %s
+	`, filters.getSortColumn(), columns, q.whereClause(), orderBy, q.arg(filters.limit()+1), q.arg(filters.offset()))
+
+	rows, err := m.DB.QueryContext(ctx, query, q.args...)
 	if err != nil {
 		return nil, Metadata{}, err
 	}
 	defer rows.Close()
 
-	totalRecords := 0
 	movies := []*Movie{}
+	keys := []cursor{}
 
 	for rows.Next() {
 		var movie Movie
+		var key cursor
 
-		err := rows.Scan(
-			&totalRecords,
-			&movie.ID,
-			&movie.Name,
-			&movie.ParentID,
-			&movie.Date,
-			&movie.SeriesID,
-			&movie.Kind,
-			&movie.Runtime,
-			&movie.Budget,
-			&movie.Revenue,
-			&movie.Homepage,
-			&movie.VoteAvarage,
-			&movie.VoteCount,
-			&movie.Abstract,
-			&movie.CreatedAt,
-			&movie.ModifiedAt,
-			&movie.Version,
-		)
+		err := rows.Scan(append([]any{&key.Value}, dests(&movie)...)...)
 		if err != nil {
 			return nil, Metadata{}, err
 		}
+		movie.Title = movie.Name
+		movie.AbstractLanguage = DefaultAbstractLanguage
+		key.ID = movie.ID
 		movies = append(movies, &movie)
+		keys = append(keys, key)
 	}
 
 	err = rows.Err()
@@ -208,7 +377,7 @@ func (m MovieModel) GetAll(name string, kind string, filters Filters) ([]*Movie,
 		return nil, Metadata{}, err
 	}
 
-	metadata := calculateMetadata(filters.Page, filters.PageSize, totalRecords)
+	movies, metadata := paginate(filters, movies, keys, totalRecords)
 
 	return movies, metadata, nil
 }
@@ -266,6 +435,27 @@ func (m MovieModel) Update(movie *Movie) error {
 	return nil
 }
 
+// touchMovies bumps the version of the movies in the movie_id column of the
+// CTE named from. The abstracts, aliases, countries and languages of a movie
+// are part of its representation, so edits to them change its ETag.
+func touchMovies(from string) string {
+	return fmt.Sprintf(`
+	UPDATE movies SET version = version + 1, modified_at = NOW()
+	WHERE id IN (SELECT movie_id FROM %s)`, from)
+}
+
+// touchSyncedMovies bumps the synced version of the movies touched by
+// touchMovies, as long as they were at their synced version, so that cmd/sync
+// does not take edits to the abstracts, aliases, countries and languages for
+// edits to the movie. The sync leaves those alone. It must be part of the same
+// statement as touchMovies, as both read the version before the bump.
+func touchSyncedMovies(from string) string {
+	return fmt.Sprintf(`
+	UPDATE sync_movie_versions s SET version = s.version + 1
+	FROM movies m
+	WHERE m.id = s.movie_id AND m.version = s.version AND m.id IN (SELECT movie_id FROM %s)`, from)
+}
+
 func (m MovieModel) Delete(id int64) error {
 	if id < 0 {
 		return ErrRecordNotFound
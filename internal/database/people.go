@@ -58,41 +58,44 @@ func (m PeopleModel) Insert(person *Person) error {
 	)
 }
 
+// personColumns are the columns of a person that ?fields= can leave out, see
+// movieColumns.
+var personColumns = []column[Person]{
+	{"id", nil, func(p *Person) any { return &p.ID }},
+	{"name", []string{"name"}, func(p *Person) any { return &p.Name }},
+	{"birthday", []string{"birthday"}, func(p *Person) any { return &p.Birthday }},
+	{"deathday", []string{"deathday"}, func(p *Person) any { return &p.Deathday }},
+	{"gender", []string{"gender"}, func(p *Person) any { return &p.Gender }},
+	{"aliases", []string{"aliases"}, func(p *Person) any { return pq.Array(&p.Aliases) }},
+	{"created_at", nil, func(p *Person) any { return &p.CreatedAt }},
+	{"modified_at", nil, func(p *Person) any { return &p.ModifiedAt }},
+	{"version", nil, func(p *Person) any { return &p.Version }},
+}
+
 func (m PeopleModel) Get(id int64) (*Person, error) {
+	return m.GetFields(id, nil)
+}
+
+// GetFields returns the person with only the columns needed for fields read
+// from the database.
+func (m PeopleModel) GetFields(id int64, fields Fields) (*Person, error) {
 	if id < 0 {
 		return nil, ErrRecordNotFound
 	}
 
 	var person Person
 
-	query := `
-	SELECT 
-		id,
-		name, 
-		birthday,
-		deathday, 
-		gender, 
-		aliases, 
-		created_at,
-		modified_at,
-		version
+	columns, dests := selectColumns(personColumns, fields)
+
+	query := fmt.Sprintf(`
+	SELECT %s
 	FROM people
-	WHERE id = $1;`
+	WHERE id = $1;`, columns)
 
 	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
 	defer cancel()
 
-	err := m.DB.QueryRowContext(ctx, query, id).Scan(
-		&person.ID,
-		&person.Name,
-		&person.Birthday,
-		&person.Deathday,
-		&person.Gender,
-		pq.Array(&person.Aliases),
-		&person.CreatedAt,
-		&person.ModifiedAt,
-		&person.Version,
-	)
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(dests(&person)...)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, ErrRecordNotFound
@@ -104,53 +107,93 @@ func (m PeopleModel) Get(id int64) (*Person, error) {
 	return &person, nil
 }
 
-func (m PeopleModel) GetAll(name string, filter Filters) ([]*Person, Metadata, error) {
-	sortColumn := filter.getSortColumn()
-	sortDirection := filter.getSortDirection()
+// GetByIDs returns the people with the ids in the order of ids, and the ids
+// that were not found.
+func (m PeopleModel) GetByIDs(ids []int64, fields Fields) ([]*Person, []int64, error) {
+	columns, dests := selectColumns(personColumns, fields)
 
 	query := fmt.Sprintf(`
-		SELECT count(*) OVER(), id, name, birthday, deathday, gender, aliases, created_at, modified_at, version
-		FROM people
-		WHERE  (to_tsvector('simple', name) @@ plainto_tsquery('simple', $1) OR $1 = '')
-		ORDER BY %s %s, id ASC
-		LIMIT $2 OFFSET $3
-	`, sortColumn, sortDirection)
+	SELECT %s
+	FROM people
+	WHERE id = ANY($1)`, columns)
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
 
-	args := []any{name, filter.limit(), filter.offset()}
+	rows, err := m.DB.QueryContext(ctx, query, pq.Array(ids))
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	people := []*Person{}
+
+	for rows.Next() {
+		var person Person
+
+		err := rows.Scan(dests(&person)...)
+		if err != nil {
+			return nil, nil, err
+		}
+		people = append(people, &person)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	people, missing := inRequestOrder(ids, people, func(person *Person) int64 { return person.ID })
+
+	return people, missing, nil
+}
+
+func (m PeopleModel) GetAll(name string, filter Filters, fields Fields) ([]*Person, Metadata, error) {
+	var q queryBuilder
+	if name != "" {
+		q.where("to_tsvector('simple', people.name) @@ plainto_tsquery('simple', ?)", name)
+	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
 	defer cancel()
 
-	rows, err := m.DB.QueryContext(ctx, query, args...)
+	totalRecords, err := countRecords(ctx, m.DB, filter, "people", q)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	orderBy := filter.keyset(&q, "people")
+	columns, dests := selectColumns(personColumns, fields)
+
+	query := fmt.Sprintf(`
+		SELECT people.%s::text, %s
+		FROM people
+		%s
+		ORDER BY %s
+		LIMIT %s OFFSET %s
+	`, filter.getSortColumn(), columns, q.whereClause(), orderBy, q.arg(filter.limit()+1), q.arg(filter.offset()))
+
+	rows, err := m.DB.QueryContext(ctx, query, q.args...)
 	if err != nil {
 		return nil, Metadata{}, err
 	}
 	defer rows.Close()
 
-	totalRecords := 0
 	people := []*Person{}
+	keys := []cursor{}
 
 	for rows.Next() {
 		var person Person
+		var key cursor
 
-		err = rows.Scan(
-			&totalRecords,
-			&person.ID,
-			&person.Name,
-			&person.Birthday,
-			&person.Deathday,
-			&person.Gender,
-			pq.Array(&person.Aliases),
-			&person.CreatedAt,
-			&person.ModifiedAt,
-			&person.Version,
-		)
+		err = rows.Scan(append([]any{&key.Value}, dests(&person)...)...)
 		if err != nil {
 			return nil, Metadata{}, err
 		}
 
+		key.ID = person.ID
 		people = append(people, &person)
-
+		keys = append(keys, key)
 	}
 
 	err = rows.Err()
@@ -158,7 +201,7 @@ func (m PeopleModel) GetAll(name string, filter Filters) ([]*Person, Metadata, e
 		return nil, Metadata{}, err
 	}
 
-	metadata := calculateMetadata(filter.Page, filter.PageSize, totalRecords)
+	people, metadata := paginate(filter, people, keys, totalRecords)
 
 	return people, metadata, nil
 }
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/Torkel-Aannestad/OMDB-api/internal/validator"
+	"github.com/lib/pq"
 )
 
 type PeopleLink struct {
@@ -132,3 +133,52 @@ func ValidatePeopleLink(v *validator.Validator, PeopleLink *PeopleLink) {
 	v.Check(PeopleLink.Source != "", "source", "must be provided")
 	v.Check(len(PeopleLink.Source) <= 500, "source", "must not be more than 500 bytes long")
 }
+
+// GetForPeople returns the links of each of the people in one query.
+func (m PeopleLinkModel) GetForPeople(personIDs []int64) (map[int64][]*PeopleLink, error) {
+	query := `
+		SELECT 
+			id,
+			source,  
+			key,
+			person_id,
+			language
+		FROM people_links
+		WHERE person_id = ANY($1)
+		ORDER BY id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, pq.Array(personIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	peopleLinks := make(map[int64][]*PeopleLink)
+
+	for rows.Next() {
+		var peopleLink PeopleLink
+
+		err := rows.Scan(
+			&peopleLink.ID,
+			&peopleLink.Source,
+			&peopleLink.Key,
+			&peopleLink.PersonID,
+			&peopleLink.Language,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		peopleLinks[peopleLink.PersonID] = append(peopleLinks[peopleLink.PersonID], &peopleLink)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return peopleLinks, nil
+}
@@ -0,0 +1,62 @@
+package database
+
+import (
+	"strconv"
+	"strings"
+)
+
+// queryBuilder collects the conditions of a WHERE clause together with their
+// arguments and numbers the placeholders. Conditions are written with ? for
+// each argument, which is replaced with the next $n placeholder. Only the
+// arguments are taken from the client, conditions and column names must be
+// constants.
+type queryBuilder struct {
+	conditions []string
+	args       []any
+}
+
+// arg adds an argument and returns its placeholder.
+func (q *queryBuilder) arg(value any) string {
+	q.args = append(q.args, value)
+	return "$" + strconv.Itoa(len(q.args))
+}
+
+// where adds a condition, replacing each ? with a placeholder for the next
+// value.
+func (q *queryBuilder) where(condition string, values ...any) {
+	var b strings.Builder
+	for _, value := range values {
+		i := strings.IndexByte(condition, '?')
+		if i < 0 {
+			panic("query condition has fewer placeholders than values: " + condition)
+		}
+		b.WriteString(condition[:i])
+		b.WriteString(q.arg(value))
+		condition = condition[i+1:]
+	}
+	if strings.IndexByte(condition, '?') >= 0 {
+		panic("query condition has more placeholders than values")
+	}
+	b.WriteString(condition)
+
+	q.conditions = append(q.conditions, b.String())
+}
+
+// whereClause returns the conditions joined with AND, or an empty string when
+// there are no conditions.
+func (q *queryBuilder) whereClause() string {
+	if len(q.conditions) == 0 {
+		return ""
+	}
+	return "WHERE (" + strings.Join(q.conditions, ") AND (") + ")"
+}
+
+// whereRange adds a condition for each bound of a range that is set.
+func whereRange[T any](q *queryBuilder, column string, min, max *T) {
+	if min != nil {
+		q.where(column+" >= ?", *min)
+	}
+	if max != nil {
+		q.where(column+" <= ?", *max)
+	}
+}
@@ -0,0 +1,320 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Torkel-Aannestad/OMDB-api/internal/validator"
+)
+
+// SearchTypes are the types of resources returned by Search.
+var SearchTypes = []string{"movie", "person", "category", "keyword"}
+
+type SearchResult struct {
+	Type    string  `json:"type"`
+	ID      int64   `json:"id"`
+	Name    string  `json:"name"`
+	Score   float64 `json:"score"`
+	Snippet string  `json:"snippet"`
+}
+
+type SearchModel struct {
+	DB *sql.DB
+}
+
+// searchQuery is the tsquery of the search terms.
+const searchQuery = `plainto_tsquery('simple', $1)`
+
+// searchKey is the search terms in lower case and without accents, which is
+// matched against the trigram indexes on search_key(name).
+const searchKey = `search_key($1)`
+
+// searchHeadline marks the matched words of name in a snippet. Names are
+// short, so the whole name is returned. Accents are ignored so that "Amelie"
+// marks "Amélie".
+func searchHeadline(name string) string {
+	return `ts_headline('simple_unaccent', ` + name + `, plainto_tsquery('simple_unaccent', $1), 'StartSel=<mark>, StopSel=</mark>, HighlightAll=true')`
+}
+
+// searchMatch matches the full text of name, or the search terms as a fuzzy
+// part of the key, which catches typos, missing accents and missing spaces.
+func searchMatch(name, key string) string {
+	return `(to_tsvector('simple', ` + name + `) @@ ` + searchQuery + ` OR ` + searchKey + ` <% ` + key + `)`
+}
+
+// searchScore is a score from 0 to 1 of how well the key matches the search
+// terms. Half of it is how well the terms match a part of the key, and half
+// how similar the whole key is, so that "Star Wars" scores above "Star Wars:
+// Episode I".
+func searchScore(key string) string {
+	return `(word_similarity(` + searchKey + `, ` + key + `) + similarity(` + searchKey + `, ` + key + `)) / 2`
+}
+
+// searchQueries holds the query of each search type. Every query selects the
+// type, id, name, score and snippet of the matches. Movies match on the title
+// and the aliases, and people on the name and the aliases, where alias matches
+// score a bit below name matches. Categories and keywords are both rows in
+// categories, told apart by being used in movie_categories or movie_keywords.
+var searchQueries = map[string]string{
+	"movie": `
+		SELECT 'movie', movies.id, movies.name, best.score, ` + searchHeadline("best.name") + `
+		FROM (
+			SELECT DISTINCT ON (movie_id) movie_id, name, score
+			FROM (
+				SELECT id AS movie_id, name, ` + searchScore("search_key(name)") + ` AS score, true AS original
+				FROM movies
+				WHERE ` + searchMatch("name", "search_key(name)") + `
+				UNION ALL
+				SELECT movie_id, name, 0.9 * ` + searchScore("search_key(name)") + `, false
+				FROM movie_aliases
+				WHERE ` + searchMatch("name", "search_key(name)") + `
+			) matches
+			ORDER BY movie_id, score DESC, original DESC
+		) best
+		JOIN movies ON movies.id = best.movie_id`,
+	"person": `
+		SELECT 'person', id, name,
+			greatest(` + searchScore("search_key(name)") + `, 0.9 * word_similarity(` + searchKey + `, people_search_key(name, aliases))),
+			` + searchHeadline(`CASE
+				WHEN `+searchKey+` <% search_key(name) OR to_tsvector('simple', name) @@ `+searchQuery+` THEN name
+				ELSE name || ' (' || array_to_string(aliases, ', ') || ')'
+			END`) + `
+		FROM people
+		WHERE (people_search_document(name, aliases) @@ ` + searchQuery + ` OR ` + searchKey + ` <% people_search_key(name, aliases))`,
+	"category": `
+		SELECT 'category', id, name, ` + searchScore("search_key(name)") + `, ` + searchHeadline("name") + `
+		FROM categories
+		WHERE ` + searchMatch("name", "search_key(name)") + `
+		AND (EXISTS (SELECT 1 FROM movie_categories WHERE category_id = categories.id)
+			OR NOT EXISTS (SELECT 1 FROM movie_keywords WHERE category_id = categories.id))`,
+	"keyword": `
+		SELECT 'keyword', id, name, ` + searchScore("search_key(name)") + `, ` + searchHeadline("name") + `
+		FROM categories
+		WHERE ` + searchMatch("name", "search_key(name)") + `
+		AND EXISTS (SELECT 1 FROM movie_keywords WHERE category_id = categories.id)`,
+}
+
+// Search returns the movies, people, categories and keywords matching q,
+// ranked by relevance. types limits the search to some of the SearchTypes.
+func (m SearchModel) Search(q string, types []string, filters Filters) ([]*SearchResult, Metadata, error) {
+	queries := []string{}
+	for _, searchType := range SearchTypes {
+		if slices.Contains(types, searchType) {
+			queries = append(queries, searchQueries[searchType])
+		}
+	}
+
+	query := `
+		SELECT count(*) OVER(), type, id, name, score, snippet
+		FROM (` + strings.Join(queries, "\n\t\tUNION ALL") + `
+		) AS results (type, id, name, score, snippet)
+		ORDER BY score DESC, name ASC, type ASC, id ASC
+		LIMIT $2 OFFSET $3`
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, q, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	results := []*SearchResult{}
+
+	for rows.Next() {
+		var result SearchResult
+
+		err := rows.Scan(
+			&totalRecords,
+			&result.Type,
+			&result.ID,
+			&result.Name,
+			&result.Score,
+			&result.Snippet,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		results = append(results, &result)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(filters.Page, filters.PageSize, totalRecords)
+
+	return results, metadata, nil
+}
+
+// AutocompleteTypes are the types of resources returned by Autocomplete.
+var AutocompleteTypes = []string{"movie", "person"}
+
+type Suggestion struct {
+	Type string `json:"type"`
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+// autocompleteQueries holds the query of each autocomplete type. Every query
+// selects the type, id, name, whether the name starts with the search terms,
+// the similarity and the popularity of the names starting with the terms, or
+// having a word starting with them. $2 is the search terms escaped for LIKE.
+var autocompleteQueries = map[string]string{
+	"movie": `
+		(SELECT 'movie', id, name, search_key(name) LIKE search_key($2) || '%', similarity(search_key(name), ` + searchKey + `), votes_count
+		FROM movies
+		WHERE search_key(name) LIKE search_key($2) || '%' OR search_key(name) LIKE '% ' || search_key($2) || '%'
+		ORDER BY 4 DESC, votes_count DESC, 5 DESC
+		LIMIT $3)`,
+	"person": `
+		(SELECT 'person', id, name, search_key(name) LIKE search_key($2) || '%', similarity(search_key(name), ` + searchKey + `), NULL::bigint
+		FROM people
+		WHERE search_key(name) LIKE search_key($2) || '%' OR search_key(name) LIKE '% ' || search_key($2) || '%'
+		ORDER BY 4 DESC, 5 DESC
+		LIMIT $3)`,
+}
+
+// Autocomplete returns up to limit movies and people with a name or a word in
+// the name starting with q, ignoring case and accents. Names starting with q
+// come first, and then the most popular and most similar names.
+func (m SearchModel) Autocomplete(q string, types []string, limit int) ([]*Suggestion, error) {
+	queries := []string{}
+	for _, autocompleteType := range AutocompleteTypes {
+		if slices.Contains(types, autocompleteType) {
+			queries = append(queries, autocompleteQueries[autocompleteType])
+		}
+	}
+
+	query := `
+		SELECT type, id, name
+		FROM (` + strings.Join(queries, "\n\t\tUNION ALL") + `
+		) AS suggestions (type, id, name, prefix, score, popularity)
+		ORDER BY prefix DESC, popularity DESC NULLS LAST, score DESC, name ASC, id ASC
+		LIMIT $3`
+
+	escaped := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(q)
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, q, escaped, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	suggestions := []*Suggestion{}
+	for rows.Next() {
+		var suggestion Suggestion
+
+		err := rows.Scan(&suggestion.Type, &suggestion.ID, &suggestion.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		suggestions = append(suggestions, &suggestion)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return suggestions, nil
+}
+
+// DidYouMean returns up to five names similar to q from the tables of the
+// search types. It is meant for searches without results, as it also finds
+// names too different from q to be search results.
+func (m SearchModel) DidYouMean(q string, types []string) ([]string, error) {
+	tables := []string{}
+	for _, searchType := range types {
+		table := "categories"
+		switch searchType {
+		case "movie":
+			table = "movies"
+		case "person":
+			table = "people"
+		}
+		if !slices.Contains(tables, table) {
+			tables = append(tables, table)
+		}
+	}
+
+	queries := []string{}
+	for _, table := range tables {
+		queries = append(queries, `
+			SELECT name, similarity(search_key(name), `+searchKey+`)
+			FROM `+table+`
+			WHERE search_key(name) % `+searchKey)
+	}
+
+	query := `
+		SELECT name
+		FROM (` + strings.Join(queries, "\n\t\tUNION ALL") + `
+		) AS suggestions (name, score)
+		GROUP BY name
+		ORDER BY max(score) DESC, name ASC
+		LIMIT 5`
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, q)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	names := []string{}
+	for rows.Next() {
+		var name string
+
+		err := rows.Scan(&name)
+		if err != nil {
+			return nil, err
+		}
+
+		names = append(names, name)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return names, nil
+}
+
+func ValidateSearch(v *validator.Validator, q string, types []string) {
+	v.Check(strings.TrimSpace(q) != "", "q", "must be provided")
+	v.Check(len(q) <= 200, "q", "must not be more than 200 bytes long")
+
+	v.Check(len(types) > 0, "types", "must contain at least one type")
+	for _, t := range types {
+		v.Check(validator.PermittedValue(t, SearchTypes...), "types", "must be one of the following values: "+strings.Join(SearchTypes, ", "))
+	}
+	v.Check(validator.Unique(types), "types", "must not contain duplicate values")
+}
+
+func ValidateAutocomplete(v *validator.Validator, q string, types []string, limit int) {
+	v.Check(utf8.RuneCountInString(strings.TrimSpace(q)) >= 2, "q", "must be at least 2 characters long")
+	v.Check(len(q) <= 100, "q", "must not be more than 100 bytes long")
+
+	v.Check(limit > 0, "limit", "must be greater than zero")
+	v.Check(limit <= 20, "limit", "must be a maximum of 20")
+
+	v.Check(len(types) > 0, "types", "must contain at least one type")
+	for _, t := range types {
+		v.Check(validator.PermittedValue(t, AutocompleteTypes...), "types", "must be one of the following values: "+strings.Join(AutocompleteTypes, ", "))
+	}
+	v.Check(validator.Unique(types), "types", "must not contain duplicate values")
+}
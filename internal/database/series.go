@@ -0,0 +1,354 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Season is a movie of kind season. SeasonNumber is read from the name, like
+// "Season 2" or "Season Two", and falls back to the position of the season
+// when ordered by date. Specials are season 0.
+type Season struct {
+	ID           int64     `json:"id"`
+	SeriesID     int64     `json:"series_id"`
+	Name         string    `json:"name"`
+	Date         time.Time `json:"date"`
+	SeasonNumber int       `json:"season_number"`
+	EpisodeCount int       `json:"episode_count"`
+	Version      int32     `json:"version"`
+}
+
+// Episode is a movie of kind episode. EpisodeNumber is the position of the
+// episode in its season when ordered by date.
+type Episode struct {
+	ID            int64     `json:"id"`
+	SeasonID      NullInt64 `json:"season_id"`
+	SeriesID      NullInt64 `json:"series_id"`
+	Name          string    `json:"name"`
+	Date          time.Time `json:"date"`
+	Runtime       int64     `json:"runtime"`
+	VoteAvarage   float64   `json:"vote_average"`
+	VoteCount     int64     `json:"vote_count"`
+	Abstract      string    `json:"abstract"`
+	SeasonNumber  int       `json:"season_number"`
+	EpisodeNumber int       `json:"episode_number"`
+	Version       int32     `json:"version"`
+}
+
+type EpisodeGuideSeason struct {
+	Season
+	Episodes []*Episode `json:"episodes"`
+}
+
+// EpisodeGuide is a series with its seasons and episodes. Episodes that are
+// not in any of the seasons are listed in Episodes.
+type EpisodeGuide struct {
+	Series   *Movie                `json:"series"`
+	Seasons  []*EpisodeGuideSeason `json:"seasons"`
+	Episodes []*Episode            `json:"episodes"`
+}
+
+type SeriesModel struct {
+	DB *sql.DB
+}
+
+var seasonNumberWords = map[string]int{
+	"one": 1, "two": 2, "three": 3, "four": 4, "five": 5, "six": 6, "seven": 7, "eight": 8, "nine": 9, "ten": 10,
+	"eleven": 11, "twelve": 12, "thirteen": 13, "fourteen": 14, "fifteen": 15, "sixteen": 16, "seventeen": 17,
+	"eighteen": 18, "nineteen": 19, "twenty": 20,
+}
+
+// parseSeasonNumber reads the season number from names like "Season 2",
+// "Season Two" or "Staffel 2". It returns false when the name has no number.
+func parseSeasonNumber(name string) (int, bool) {
+	fields := strings.Fields(strings.ToLower(name))
+	if len(fields) == 0 {
+		return 0, false
+	}
+	if strings.Contains(fields[0], "special") {
+		return 0, true
+	}
+
+	last := fields[len(fields)-1]
+	if n, err := strconv.Atoi(last); err == nil && n >= 0 {
+		return n, true
+	}
+	if n, found := seasonNumberWords[last]; found {
+		return n, true
+	}
+	return 0, false
+}
+
+// checkKind returns ErrRecordNotFound unless the movie exists and is of the
+// given kind.
+func (m SeriesModel) checkKind(ctx context.Context, id int64, kind string) error {
+	if id < 0 {
+		return ErrRecordNotFound
+	}
+
+	var found string
+	err := m.DB.QueryRowContext(ctx, `SELECT kind FROM movies WHERE id = $1`, id).Scan(&found)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrRecordNotFound
+		}
+		return err
+	}
+	if found != kind {
+		return ErrRecordNotFound
+	}
+	return nil
+}
+
+// GetSeasons returns the seasons of a series ordered by season number.
+func (m SeriesModel) GetSeasons(seriesID int64) ([]*Season, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	err := m.checkKind(ctx, seriesID, "series")
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+	SELECT
+		s.id,
+		s.parent_id,
+		s.name,
+		s.date,
+		(SELECT count(*) FROM movies e WHERE e.parent_id = s.id AND e.kind = 'episode'),
+		s.version
+	FROM movies s
+	WHERE s.parent_id = $1 AND s.kind = 'season'
+	ORDER BY s.date, s.id`
+
+	rows, err := m.DB.QueryContext(ctx, query, seriesID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	seasons := []*Season{}
+
+	for rows.Next() {
+		var season Season
+
+		err := rows.Scan(
+			&season.ID,
+			&season.SeriesID,
+			&season.Name,
+			&season.Date,
+			&season.EpisodeCount,
+			&season.Version,
+		)
+		if err != nil {
+			return nil, err
+		}
+		seasons = append(seasons, &season)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	numberSeasons(seasons)
+
+	return seasons, nil
+}
+
+// numberSeasons sets the season number from the name, or from the position of
+// the season when the name has no number, and sorts the seasons by number.
+func numberSeasons(seasons []*Season) {
+	position := 0
+	for _, season := range seasons {
+		if n, ok := parseSeasonNumber(season.Name); ok {
+			season.SeasonNumber = n
+			if n == 0 {
+				continue
+			}
+			position = n
+		} else {
+			position++
+			season.SeasonNumber = position
+		}
+	}
+
+	sort.SliceStable(seasons, func(i, j int) bool {
+		return seasons[i].SeasonNumber < seasons[j].SeasonNumber
+	})
+}
+
+// GetEpisodes returns the episodes of a season ordered by date.
+func (m SeriesModel) GetEpisodes(seasonID int64) (*Season, []*Episode, error) {
+	if seasonID < 0 {
+		return nil, nil, ErrRecordNotFound
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	var season Season
+	var seriesID sql.NullInt64
+
+	query := `
+	SELECT id, parent_id, name, date, version
+	FROM movies
+	WHERE id = $1 AND kind = 'season'`
+
+	err := m.DB.QueryRowContext(ctx, query, seasonID).Scan(
+		&season.ID,
+		&seriesID,
+		&season.Name,
+		&season.Date,
+		&season.Version,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil, ErrRecordNotFound
+		}
+		return nil, nil, err
+	}
+	season.SeriesID = seriesID.Int64
+
+	// The season number depends on the other seasons of the series.
+	if seriesID.Valid {
+		seasons, err := m.GetSeasons(seriesID.Int64)
+		if err != nil && !errors.Is(err, ErrRecordNotFound) {
+			return nil, nil, err
+		}
+		for _, s := range seasons {
+			if s.ID == season.ID {
+				season.SeasonNumber = s.SeasonNumber
+			}
+		}
+	} else if n, ok := parseSeasonNumber(season.Name); ok {
+		season.SeasonNumber = n
+	}
+
+	episodes, err := m.getEpisodes(ctx, `e.parent_id = $1`, seasonID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for i, episode := range episodes {
+		episode.SeasonNumber = season.SeasonNumber
+		episode.EpisodeNumber = i + 1
+	}
+	season.EpisodeCount = len(episodes)
+
+	return &season, episodes, nil
+}
+
+func (m SeriesModel) getEpisodes(ctx context.Context, where string, id int64) ([]*Episode, error) {
+	query := `
+	SELECT
+		e.id,
+		e.parent_id,
+		e.series_id,
+		e.name,
+		e.date,
+		e.runtime,
+		e.vote_average,
+		e.votes_count,
+		e.abstract,
+		e.version
+	FROM movies e
+	WHERE ` + where + ` AND e.kind = 'episode'
+	ORDER BY e.date, e.id`
+
+	rows, err := m.DB.QueryContext(ctx, query, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	episodes := []*Episode{}
+
+	for rows.Next() {
+		var episode Episode
+
+		err := rows.Scan(
+			&episode.ID,
+			&episode.SeasonID,
+			&episode.SeriesID,
+			&episode.Name,
+			&episode.Date,
+			&episode.Runtime,
+			&episode.VoteAvarage,
+			&episode.VoteCount,
+			&episode.Abstract,
+			&episode.Version,
+		)
+		if err != nil {
+			return nil, err
+		}
+		episodes = append(episodes, &episode)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return episodes, nil
+}
+
+// GetEpisodeGuide returns the series with all seasons and episodes, loading
+// the episodes of every season in a single query.
+func (m SeriesModel) GetEpisodeGuide(series *Movie) (*EpisodeGuide, error) {
+	if series.Kind != "series" {
+		return nil, ErrRecordNotFound
+	}
+
+	seasons, err := m.GetSeasons(series.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	// Episodes reference the series through series_id and the season through
+	// parent_id. Some episodes only have one of them.
+	episodes, err := m.getEpisodes(ctx, `(e.series_id = $1 OR e.parent_id = $1 OR e.parent_id IN (SELECT id FROM movies WHERE parent_id = $1 AND kind = 'season'))`, series.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	guide := EpisodeGuide{
+		Series:   series,
+		Seasons:  make([]*EpisodeGuideSeason, 0, len(seasons)),
+		Episodes: []*Episode{},
+	}
+
+	bySeason := make(map[int64]*EpisodeGuideSeason, len(seasons))
+	for _, season := range seasons {
+		guideSeason := &EpisodeGuideSeason{Season: *season, Episodes: []*Episode{}}
+		guide.Seasons = append(guide.Seasons, guideSeason)
+		bySeason[season.ID] = guideSeason
+	}
+
+	for _, episode := range episodes {
+		guideSeason, found := bySeason[episode.SeasonID.Int64]
+		if !episode.SeasonID.Valid || !found {
+			episode.EpisodeNumber = len(guide.Episodes) + 1
+			guide.Episodes = append(guide.Episodes, episode)
+			continue
+		}
+		episode.SeasonNumber = guideSeason.SeasonNumber
+		episode.EpisodeNumber = len(guideSeason.Episodes) + 1
+		guideSeason.Episodes = append(guideSeason.Episodes, episode)
+	}
+
+	for _, guideSeason := range guide.Seasons {
+		guideSeason.EpisodeCount = len(guideSeason.Episodes)
+	}
+
+	return &guide, nil
+}
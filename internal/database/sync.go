@@ -0,0 +1,539 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// ErrSyncConflict is returned when a movie was created or edited through the
+// api while the sync ran.
+var ErrSyncConflict = errors.New("sync conflict")
+
+// syncTimeout is used for the statements that touch every movie, which take
+// longer than the queries used by the api.
+var syncTimeout = 2 * time.Minute
+
+// SyncRun is one run of the incremental sync. Movies updated in OMDB after
+// Since and up to Watermark are synced by the run.
+type SyncRun struct {
+	ID           int64
+	StartedAt    time.Time
+	Since        time.Time
+	Watermark    time.Time
+	Status       string
+	MoviesSynced int
+	Conflicts    int
+}
+
+// SyncMovie holds a movie and the rows belonging to it as read from the OMDB
+// dataset. People referenced by the casts are included so that new people can
+// be created before the casts are inserted.
+type SyncMovie struct {
+	Movie      Movie
+	LastUpdate time.Time
+	Casts      []Cast
+	Categories []int64
+	Keywords   []int64
+	Links      []MovieLink
+	People     []SyncPerson
+}
+
+type SyncPerson struct {
+	ID       int64
+	Name     string
+	Birthday time.Time
+	Deathday time.Time
+	Gender   int
+}
+
+type SyncModel struct {
+	DB *sql.DB
+}
+
+// GetWatermark returns the watermark of the last completed sync. When no sync
+// has completed the time of the full import is used, which is when the movies
+// were created.
+func (m SyncModel) GetWatermark() (time.Time, error) {
+	query := `
+	SELECT COALESCE(
+		(SELECT max(watermark) FROM sync_runs WHERE status = 'completed'),
+		(SELECT min(created_at) FROM movies)
+	)`
+
+	ctx, cancel := context.WithTimeout(context.Background(), syncTimeout)
+	defer cancel()
+
+	var watermark sql.NullTime
+	err := m.DB.QueryRowContext(ctx, query).Scan(&watermark)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if !watermark.Valid {
+		return time.Time{}, ErrRecordNotFound
+	}
+
+	return watermark.Time, nil
+}
+
+func (m SyncModel) InsertRun(run *SyncRun) error {
+	query := `
+	INSERT INTO sync_runs (since, watermark)
+	VALUES ($1, $2)
+	RETURNING id, started_at, status`
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	return m.DB.QueryRowContext(ctx, query, run.Since, run.Watermark).Scan(
+		&run.ID,
+		&run.StartedAt,
+		&run.Status,
+	)
+}
+
+func (m SyncModel) FinishRun(run *SyncRun) error {
+	query := `
+	UPDATE sync_runs
+	SET
+		status = $2,
+		movies_synced = $3,
+		conflicts = $4,
+		finished_at = NOW()
+	WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, run.ID, run.Status, run.MoviesSynced, run.Conflicts)
+	return err
+}
+
+// GetEditedMovies returns the movies among movieIDs that the sync must leave
+// alone, with the reason. Those are movies edited through the api since they
+// were imported or last synced, and movies created through the api with an id
+// that OMDB has since given to another movie. Casts, categories, keywords and
+// links are diffed against the last synced snapshot instead, see UpsertMovie.
+func (m SyncModel) GetEditedMovies(movieIDs []int64) (map[int64]string, error) {
+	query := `
+	SELECT m.id,
+		CASE
+			WHEN s.movie_id IS NULL THEN 'movie created through the api'
+			WHEN m.version > s.version THEN 'movie edited'
+		END AS reason
+	FROM movies m
+	LEFT JOIN sync_movie_versions s ON s.movie_id = m.id
+	WHERE m.id = ANY($1)`
+
+	ctx, cancel := context.WithTimeout(context.Background(), syncTimeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, pq.Array(movieIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	edited := make(map[int64]string)
+
+	for rows.Next() {
+		var movieID int64
+		var reason sql.NullString
+
+		err := rows.Scan(&movieID, &reason)
+		if err != nil {
+			return nil, err
+		}
+		if reason.Valid {
+			edited[movieID] = reason.String
+		}
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return edited, nil
+}
+
+func (m SyncModel) InsertConflict(runID int64, movieID int64, lastUpdate time.Time, reason string) error {
+	query := `
+	INSERT INTO sync_conflicts (sync_run_id, movie_id, last_update, reason)
+	VALUES ($1, $2, $3, $4)`
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, runID, movieID, lastUpdate, reason)
+	return err
+}
+
+// UpsertMovie writes the movie and applies the changes to its casts,
+// categories, keywords and links in one transaction. Rows are diffed against
+// the snapshot of the last import or sync: rows new in the dataset are added,
+// rows gone from the dataset are deleted, and rows added or deleted through the
+// api are left alone. A parent or series that does not exist is set to null.
+// Casts referencing unknown jobs or people not from OMDB, and categories or
+// keywords referencing unknown categories are skipped, the number of skipped
+// rows is returned. ErrSyncConflict is returned when the movie was created or
+// edited through the api.
+func (m SyncModel) UpsertMovie(syncMovie *SyncMovie) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	movie := &syncMovie.Movie
+
+	// Move the sequence past the id first, so that the api can not create a
+	// movie with it while the transaction runs.
+	err = advanceSequence(ctx, tx, "movies", movie.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	// Only movies from the import or an earlier sync, at the version that was
+	// synced, are updated.
+	query := `
+	INSERT INTO movies (id, name, parent_id, date, series_id, kind, runtime, budget, revenue, homepage, vote_average, votes_count, abstract)
+	VALUES ($1, $2, (SELECT id FROM movies WHERE id = $3), $4, (SELECT id FROM movies WHERE id = $5), $6, $7, $8, $9, $10, $11, $12, $13)
+	ON CONFLICT (id) DO UPDATE SET
+		name = EXCLUDED.name,
+		parent_id = EXCLUDED.parent_id,
+		date = EXCLUDED.date,
+		series_id = EXCLUDED.series_id,
+		kind = EXCLUDED.kind,
+		runtime = EXCLUDED.runtime,
+		budget = EXCLUDED.budget,
+		revenue = EXCLUDED.revenue,
+		homepage = EXCLUDED.homepage,
+		vote_average = EXCLUDED.vote_average,
+		votes_count = EXCLUDED.votes_count,
+		abstract = EXCLUDED.abstract,
+		modified_at = NOW(),
+		version = movies.version + 1
+	WHERE movies.version <= (SELECT s.version FROM sync_movie_versions s WHERE s.movie_id = movies.id)
+	RETURNING version`
+
+	args := []any{
+		movie.ID,
+		movie.Name,
+		movie.ParentID.NullInt64,
+		movie.Date,
+		movie.SeriesID.NullInt64,
+		movie.Kind,
+		movie.Runtime,
+		movie.Budget,
+		movie.Revenue,
+		movie.Homepage,
+		movie.VoteAvarage,
+		movie.VoteCount,
+		movie.Abstract,
+	}
+
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&movie.Version)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, ErrSyncConflict
+		}
+		return 0, err
+	}
+
+	var maxPersonID int64
+	for _, person := range syncMovie.People {
+		maxPersonID = max(maxPersonID, person.ID)
+	}
+	if maxPersonID > 0 {
+		err = advanceSequence(ctx, tx, "people", maxPersonID)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	for _, person := range syncMovie.People {
+		_, err = tx.ExecContext(ctx, `
+		WITH inserted AS (
+			INSERT INTO people (id, name, birthday, deathday, gender)
+			VALUES ($1, $2, $3, $4, $5)
+			ON CONFLICT (id) DO NOTHING
+			RETURNING id
+		)
+		INSERT INTO sync_people (person_id)
+		SELECT id FROM inserted
+		ON CONFLICT (person_id) DO NOTHING`,
+			person.ID, person.Name, person.Birthday, person.Deathday, person.Gender)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	snapshot, err := getSnapshot(ctx, tx, movie.ID)
+	if err != nil {
+		return 0, err
+	}
+	synced := make(map[string][]string)
+	var skipped int
+
+	// casts
+	casts := make(map[string]Cast)
+	for _, cast := range syncMovie.Casts {
+		casts[castKey(cast)] = cast
+	}
+	kept, added, removed := diffKeys(snapshot["cast"], keysOf(casts))
+	_, err = tx.ExecContext(ctx, `
+	DELETE FROM casts
+	WHERE movie_id = $1 AND concat_ws('|', person_id, job_id, position, role) = ANY($2)`,
+		movie.ID, pq.Array(removed))
+	if err != nil {
+		return 0, err
+	}
+	for _, key := range added {
+		cast := casts[key]
+		var ok bool
+		err = tx.QueryRowContext(ctx, `
+		WITH valid AS (
+			SELECT EXISTS (SELECT 1 FROM jobs WHERE id = $3) AND EXISTS (SELECT 1 FROM sync_people WHERE person_id = $2) AS ok
+		), inserted AS (
+			INSERT INTO casts (movie_id, person_id, job_id, role, position)
+			SELECT $1::bigint, $2::bigint, $3::bigint, $4::text, $5::integer
+			FROM valid
+			WHERE valid.ok AND NOT EXISTS (
+				SELECT 1 FROM casts
+				WHERE movie_id = $1 AND person_id = $2 AND job_id = $3 AND role = $4 AND position = $5
+			)
+		)
+		SELECT ok FROM valid`,
+			movie.ID, cast.PersonID, cast.JobID, cast.Role, cast.Position).Scan(&ok)
+		if err != nil {
+			return 0, err
+		}
+		if ok {
+			kept = append(kept, key)
+		} else {
+			skipped++
+		}
+	}
+	synced["cast"] = kept
+
+	// categories and keywords
+	for kind, table := range map[string]string{"category": "movie_categories", "keyword": "movie_keywords"} {
+		categoryIDs := syncMovie.Categories
+		if kind == "keyword" {
+			categoryIDs = syncMovie.Keywords
+		}
+		categories := make(map[string]int64)
+		for _, id := range categoryIDs {
+			categories[strconv.FormatInt(id, 10)] = id
+		}
+
+		kept, added, removed := diffKeys(snapshot[kind], keysOf(categories))
+		_, err = tx.ExecContext(ctx, `DELETE FROM `+table+` WHERE movie_id = $1 AND category_id::text = ANY($2)`,
+			movie.ID, pq.Array(removed))
+		if err != nil {
+			return 0, err
+		}
+
+		addedIDs := make([]int64, len(added))
+		for i, key := range added {
+			addedIDs[i] = categories[key]
+		}
+		rows, err := tx.QueryContext(ctx, `
+		WITH existing AS (
+			SELECT id FROM categories WHERE id = ANY($2)
+		), inserted AS (
+			INSERT INTO `+table+` (movie_id, category_id)
+			SELECT $1::bigint, id FROM existing
+			ON CONFLICT (movie_id, category_id) DO NOTHING
+		)
+		SELECT id::text FROM existing`,
+			movie.ID, pq.Array(addedIDs))
+		if err != nil {
+			return 0, err
+		}
+		applied, err := scanStrings(rows)
+		if err != nil {
+			return 0, err
+		}
+		skipped += len(added) - len(applied)
+		synced[kind] = append(kept, applied...)
+	}
+
+	// links
+	links := make(map[string]MovieLink)
+	for _, link := range syncMovie.Links {
+		links[linkKey(link)] = link
+	}
+	kept, added, removed = diffKeys(snapshot["link"], keysOf(links))
+	_, err = tx.ExecContext(ctx, `
+	DELETE FROM movie_links
+	WHERE movie_id = $1 AND concat_ws('|', source, key, language) = ANY($2)`,
+		movie.ID, pq.Array(removed))
+	if err != nil {
+		return 0, err
+	}
+	for _, key := range added {
+		link := links[key]
+		_, err = tx.ExecContext(ctx, `
+		INSERT INTO movie_links (source, key, movie_id, language)
+		SELECT $1::text, $2::text, $3::bigint, $4::text
+		WHERE NOT EXISTS (
+			SELECT 1 FROM movie_links
+			WHERE source = $1 AND key = $2 AND movie_id = $3 AND language = $4
+		)`,
+			link.Source, link.Key, movie.ID, link.Language)
+		if err != nil {
+			return 0, err
+		}
+	}
+	synced["link"] = append(kept, added...)
+
+	err = saveSnapshot(ctx, tx, movie.ID, synced)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+	INSERT INTO sync_movie_versions (movie_id, version, synced_at)
+	VALUES ($1, $2, NOW())
+	ON CONFLICT (movie_id) DO UPDATE SET
+		version = EXCLUDED.version,
+		synced_at = EXCLUDED.synced_at`,
+		movie.ID, movie.Version)
+	if err != nil {
+		return 0, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, err
+	}
+
+	return skipped, nil
+}
+
+// advanceSequence moves the identity sequence of table past id. The sync
+// writes the OMDB ids explicitly, and rows created through the api must not
+// get them later.
+func advanceSequence(ctx context.Context, tx *sql.Tx, table string, id int64) error {
+	query := fmt.Sprintf(`
+	SELECT setval(seq::regclass, GREATEST($1, (SELECT max(id) FROM %[1]s), COALESCE(pg_sequence_last_value(seq::regclass), 0)))
+	FROM pg_get_serial_sequence('%[1]s', 'id') AS seq`, table)
+
+	_, err := tx.ExecContext(ctx, query, id)
+	return err
+}
+
+// The keys of the rows in sync_snapshots. They are built the same way with
+// concat_ws in SQL, and in sql/data-import/050_sync_baseline.sql.
+func castKey(cast Cast) string {
+	return fmt.Sprintf("%d|%d|%d|%s", cast.PersonID, cast.JobID, cast.Position, cast.Role)
+}
+
+func linkKey(link MovieLink) string {
+	return link.Source + "|" + link.Key + "|" + link.Language
+}
+
+// getSnapshot returns the keys of the rows of the movie as last imported or
+// synced, by kind.
+func getSnapshot(ctx context.Context, tx *sql.Tx, movieID int64) (map[string]map[string]bool, error) {
+	rows, err := tx.QueryContext(ctx, `SELECT kind, row_key FROM sync_snapshots WHERE movie_id = $1`, movieID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	snapshot := make(map[string]map[string]bool)
+	for rows.Next() {
+		var kind, key string
+		err = rows.Scan(&kind, &key)
+		if err != nil {
+			return nil, err
+		}
+		if snapshot[kind] == nil {
+			snapshot[kind] = make(map[string]bool)
+		}
+		snapshot[kind][key] = true
+	}
+
+	return snapshot, rows.Err()
+}
+
+// saveSnapshot replaces the snapshot of the movie with the synced keys.
+func saveSnapshot(ctx context.Context, tx *sql.Tx, movieID int64, synced map[string][]string) error {
+	_, err := tx.ExecContext(ctx, `DELETE FROM sync_snapshots WHERE movie_id = $1`, movieID)
+	if err != nil {
+		return err
+	}
+
+	var kinds, keys []string
+	for kind, kindKeys := range synced {
+		for _, key := range kindKeys {
+			kinds = append(kinds, kind)
+			keys = append(keys, key)
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, `
+	INSERT INTO sync_snapshots (movie_id, kind, row_key)
+	SELECT $1, kind, row_key FROM unnest($2::text[], $3::text[]) AS t(kind, row_key)
+	ON CONFLICT DO NOTHING`,
+		movieID, pq.Array(kinds), pq.Array(keys))
+	return err
+}
+
+// diffKeys compares the keys of the last snapshot with the keys in the
+// dataset. Kept keys are in both, added keys are new in the dataset and removed
+// keys are gone from it. Kept rows that were deleted through the api stay
+// deleted.
+func diffKeys(snapshot map[string]bool, dataset []string) (kept, added, removed []string) {
+	inDataset := make(map[string]bool, len(dataset))
+	for _, key := range dataset {
+		inDataset[key] = true
+		if snapshot[key] {
+			kept = append(kept, key)
+		} else {
+			added = append(added, key)
+		}
+	}
+	for key := range snapshot {
+		if !inDataset[key] {
+			removed = append(removed, key)
+		}
+	}
+	sort.Strings(added)
+	return kept, added, removed
+}
+
+func keysOf[T any](rows map[string]T) []string {
+	keys := make([]string, 0, len(rows))
+	for key := range rows {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+func scanStrings(rows *sql.Rows) ([]string, error) {
+	defer rows.Close()
+
+	var values []string
+	for rows.Next() {
+		var value string
+		err := rows.Scan(&value)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, rows.Err()
+}
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/Torkel-Aannestad/OMDB-api/internal/validator"
+	"github.com/lib/pq"
 )
 
 type Trailer struct {
@@ -135,3 +136,52 @@ func ValidateTrailer(v *validator.Validator, Trailer *Trailer) {
 	v.Check(len(Trailer.Source) <= 500, "source", "must not be more than 500 bytes long")
 	v.Check(validator.PermittedValue(Trailer.Source, "youtube", "vimeo"), "source", "must not be of the following values 'youtube' or 'vimeo'")
 }
+
+// GetForMovies returns the trailers of each of the movies in one query.
+func (m TrailersModel) GetForMovies(movieIDs []int64) (map[int64][]*Trailer, error) {
+	query := `
+		SELECT 
+			id,
+			source,  
+			key,
+			movie_id,
+			language
+		FROM trailers
+		WHERE movie_id = ANY($1)
+		ORDER BY id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, pq.Array(movieIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	trailers := make(map[int64][]*Trailer)
+
+	for rows.Next() {
+		var trailer Trailer
+
+		err := rows.Scan(
+			&trailer.ID,
+			&trailer.Source,
+			&trailer.Key,
+			&trailer.MovieID,
+			&trailer.Language,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		trailers[trailer.MovieID] = append(trailers[trailer.MovieID], &trailer)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return trailers, nil
+}
@@ -0,0 +1,175 @@
+package patch
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// equalJSON reports whether two JSON documents hold the same value, ignoring
+// the order of object members and whitespace.
+func equalJSON(t *testing.T, a, b []byte) bool {
+	t.Helper()
+
+	var x, y any
+	if err := json.Unmarshal(a, &x); err != nil {
+		t.Fatalf("invalid JSON %s: %v", a, err)
+	}
+	if err := json.Unmarshal(b, &y); err != nil {
+		t.Fatalf("invalid JSON %s: %v", b, err)
+	}
+	return reflect.DeepEqual(x, y)
+}
+
+func TestMerge(t *testing.T) {
+	// The examples of appendix A of RFC 7396 come first.
+	tests := []struct {
+		name    string
+		doc     string
+		patch   string
+		want    string
+		wantErr bool
+	}{
+		{name: "replace member", doc: `{"a":"b"}`, patch: `{"a":"c"}`, want: `{"a":"c"}`},
+		{name: "add member", doc: `{"a":"b"}`, patch: `{"b":"c"}`, want: `{"a":"b","b":"c"}`},
+		{name: "remove member", doc: `{"a":"b"}`, patch: `{"a":null}`, want: `{}`},
+		{name: "remove one of two members", doc: `{"a":"b","b":"c"}`, patch: `{"a":null}`, want: `{"b":"c"}`},
+		{name: "array replaces value", doc: `{"a":["b"]}`, patch: `{"a":"c"}`, want: `{"a":"c"}`},
+		{name: "value replaces array", doc: `{"a":"c"}`, patch: `{"a":["b"]}`, want: `{"a":["b"]}`},
+		{
+			name:  "nested objects",
+			doc:   `{"a":{"b":"c"}}`,
+			patch: `{"a":{"b":"d","c":null}}`,
+			want:  `{"a":{"b":"d"}}`,
+		},
+		{name: "arrays are not merged", doc: `{"a":[{"b":"c"}]}`, patch: `{"a":[1]}`, want: `{"a":[1]}`},
+		{name: "array document", doc: `["a","b"]`, patch: `["c","d"]`, want: `["c","d"]`},
+		{name: "object replaces array document", doc: `["a"]`, patch: `{"a":"b"}`, want: `{"a":"b"}`},
+		{name: "value replaces document", doc: `{"a":"foo"}`, patch: `"bar"`, want: `"bar"`},
+		{name: "null patch", doc: `{"a":"foo"}`, patch: `null`, want: `null`},
+		{name: "null member of document is kept", doc: `{"e":null}`, patch: `{"a":1}`, want: `{"e":null,"a":1}`},
+		{name: "object replaces value", doc: `{"a":"b"}`, patch: `{"a":{"bb":{"ccc":null}}}`, want: `{"a":{"bb":{}}}`},
+		{name: "empty patch", doc: `{"a":"b"}`, patch: `{}`, want: `{"a":"b"}`},
+		{name: "remove missing member", doc: `{"a":"b"}`, patch: `{"c":null}`, want: `{"a":"b"}`},
+		{name: "empty document", doc: `{}`, patch: `{"a":{"b":1}}`, want: `{"a":{"b":1}}`},
+		{name: "malformed patch", doc: `{"a":"b"}`, patch: `{"a":`, wantErr: true},
+		{name: "empty body", doc: `{"a":"b"}`, patch: ``, wantErr: true},
+		{name: "malformed document", doc: `{"a"`, patch: `{}`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Merge([]byte(tt.doc), []byte(tt.patch))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("got %s, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !equalJSON(t, got, []byte(tt.want)) {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApply(t *testing.T) {
+	// Most cases follow the examples of appendix A of RFC 6902.
+	tests := []struct {
+		name    string
+		doc     string
+		patch   string
+		want    string
+		wantErr error
+	}{
+		{name: "add member", doc: `{"foo":"bar"}`, patch: `[{"op":"add","path":"/baz","value":"qux"}]`, want: `{"baz":"qux","foo":"bar"}`},
+		{name: "add array element", doc: `{"foo":["bar","baz"]}`, patch: `[{"op":"add","path":"/foo/1","value":"qux"}]`, want: `{"foo":["bar","qux","baz"]}`},
+		{name: "add to end of array", doc: `{"foo":["bar"]}`, patch: `[{"op":"add","path":"/foo/-","value":["abc","def"]}]`, want: `{"foo":["bar",["abc","def"]]}`},
+		{name: "add after last element", doc: `{"foo":["bar"]}`, patch: `[{"op":"add","path":"/foo/1","value":"baz"}]`, want: `{"foo":["bar","baz"]}`},
+		{name: "add replaces member", doc: `{"foo":"bar"}`, patch: `[{"op":"add","path":"/foo","value":1}]`, want: `{"foo":1}`},
+		{name: "add null", doc: `{"foo":"bar"}`, patch: `[{"op":"add","path":"/baz","value":null}]`, want: `{"foo":"bar","baz":null}`},
+		{name: "add nested member", doc: `{"foo":{"bar":{}}}`, patch: `[{"op":"add","path":"/foo/bar/baz","value":1}]`, want: `{"foo":{"bar":{"baz":1}}}`},
+		{name: "add whole document", doc: `{"foo":"bar"}`, patch: `[{"op":"add","path":"","value":[1]}]`, want: `[1]`},
+		{name: "remove member", doc: `{"baz":"qux","foo":"bar"}`, patch: `[{"op":"remove","path":"/baz"}]`, want: `{"foo":"bar"}`},
+		{name: "remove array element", doc: `{"foo":["bar","qux","baz"]}`, patch: `[{"op":"remove","path":"/foo/1"}]`, want: `{"foo":["bar","baz"]}`},
+		{name: "replace member", doc: `{"baz":"qux","foo":"bar"}`, patch: `[{"op":"replace","path":"/baz","value":"boo"}]`, want: `{"baz":"boo","foo":"bar"}`},
+		{name: "replace array element", doc: `{"foo":[1,2,3]}`, patch: `[{"op":"replace","path":"/foo/2","value":4}]`, want: `{"foo":[1,2,4]}`},
+		{name: "replace whole document", doc: `{"foo":"bar"}`, patch: `[{"op":"replace","path":"","value":{"a":1}}]`, want: `{"a":1}`},
+		{
+			name:  "move member",
+			doc:   `{"foo":{"bar":"baz","waldo":"fred"},"qux":{"corge":"grault"}}`,
+			patch: `[{"op":"move","from":"/foo/waldo","path":"/qux/thud"}]`,
+			want:  `{"foo":{"bar":"baz"},"qux":{"corge":"grault","thud":"fred"}}`,
+		},
+		{name: "move array element", doc: `{"foo":["all","grass","cows","eat"]}`, patch: `[{"op":"move","from":"/foo/1","path":"/foo/3"}]`, want: `{"foo":["all","cows","eat","grass"]}`},
+		{name: "move to same path", doc: `{"foo":1}`, patch: `[{"op":"move","from":"/foo","path":"/foo"}]`, want: `{"foo":1}`},
+		{name: "copy member", doc: `{"foo":{"a":1}}`, patch: `[{"op":"copy","from":"/foo","path":"/bar"}]`, want: `{"foo":{"a":1},"bar":{"a":1}}`},
+		{
+			name:  "copy is deep",
+			doc:   `{"foo":{"a":1}}`,
+			patch: `[{"op":"copy","from":"/foo","path":"/bar"},{"op":"replace","path":"/bar/a","value":2}]`,
+			want:  `{"foo":{"a":1},"bar":{"a":2}}`,
+		},
+		{name: "test", doc: `{"baz":"qux","foo":["a",2,"c"]}`, patch: `[{"op":"test","path":"/baz","value":"qux"},{"op":"test","path":"/foo/1","value":2}]`, want: `{"baz":"qux","foo":["a",2,"c"]}`},
+		{name: "test object", doc: `{"foo":{"a":[1],"b":null}}`, patch: `[{"op":"test","path":"/foo","value":{"b":null,"a":[1]}}]`, want: `{"foo":{"a":[1],"b":null}}`},
+		{name: "escaped pointer", doc: `{"a/b":0,"m~n":1}`, patch: `[{"op":"replace","path":"/a~1b","value":2},{"op":"test","path":"/m~0n","value":1}]`, want: `{"a/b":2,"m~n":1}`},
+		{name: "empty member name", doc: `{"":0}`, patch: `[{"op":"replace","path":"/","value":1}]`, want: `{"":1}`},
+		{name: "operations apply in order", doc: `{"a":1}`, patch: `[{"op":"add","path":"/b","value":2},{"op":"remove","path":"/a"},{"op":"test","path":"/b","value":2}]`, want: `{"b":2}`},
+		{name: "no operations", doc: `{"a":1}`, patch: `[]`, want: `{"a":1}`},
+
+		{name: "test fails", doc: `{"baz":"qux"}`, patch: `[{"op":"test","path":"/baz","value":"bar"}]`, wantErr: ErrTestFailed},
+		{name: "test compares types", doc: `{"foo":"1"}`, patch: `[{"op":"test","path":"/foo","value":1}]`, wantErr: ErrTestFailed},
+		{name: "test missing member", doc: `{"foo":1}`, patch: `[{"op":"test","path":"/bar","value":1}]`, wantErr: errAny},
+		{name: "add to missing parent", doc: `{"foo":"bar"}`, patch: `[{"op":"add","path":"/baz/bat","value":"qux"}]`, wantErr: errAny},
+		{name: "add past end of array", doc: `{"foo":[1]}`, patch: `[{"op":"add","path":"/foo/2","value":2}]`, wantErr: errAny},
+		{name: "negative index", doc: `{"foo":[1]}`, patch: `[{"op":"remove","path":"/foo/-1"}]`, wantErr: errAny},
+		{name: "leading zero index", doc: `{"foo":[1,2]}`, patch: `[{"op":"remove","path":"/foo/01"}]`, wantErr: errAny},
+		{name: "index not a number", doc: `{"foo":[1]}`, patch: `[{"op":"replace","path":"/foo/a","value":2}]`, wantErr: errAny},
+		{name: "remove end of array", doc: `{"foo":[1]}`, patch: `[{"op":"remove","path":"/foo/-"}]`, wantErr: errAny},
+		{name: "remove missing member", doc: `{"foo":1}`, patch: `[{"op":"remove","path":"/bar"}]`, wantErr: errAny},
+		{name: "remove whole document", doc: `{"foo":1}`, patch: `[{"op":"remove","path":""}]`, wantErr: errAny},
+		{name: "replace missing member", doc: `{"foo":1}`, patch: `[{"op":"replace","path":"/bar","value":1}]`, wantErr: errAny},
+		{name: "member of a value", doc: `{"foo":1}`, patch: `[{"op":"add","path":"/foo/bar","value":1}]`, wantErr: errAny},
+		{name: "move into child", doc: `{"foo":{"bar":1}}`, patch: `[{"op":"move","from":"/foo","path":"/foo/bar/baz"}]`, wantErr: errAny},
+		{name: "copy missing member", doc: `{"foo":1}`, patch: `[{"op":"copy","from":"/bar","path":"/baz"}]`, wantErr: errAny},
+		{name: "missing path", doc: `{"foo":1}`, patch: `[{"op":"remove"}]`, wantErr: errAny},
+		{name: "missing value", doc: `{"foo":1}`, patch: `[{"op":"add","path":"/bar"}]`, wantErr: errAny},
+		{name: "missing from", doc: `{"foo":1}`, patch: `[{"op":"move","path":"/bar"}]`, wantErr: errAny},
+		{name: "path without slash", doc: `{"foo":1}`, patch: `[{"op":"remove","path":"foo"}]`, wantErr: errAny},
+		{name: "unknown op", doc: `{"foo":1}`, patch: `[{"op":"delete","path":"/foo"}]`, wantErr: errAny},
+		{name: "missing op", doc: `{"foo":1}`, patch: `[{"path":"/foo"}]`, wantErr: errAny},
+		{name: "later operation fails", doc: `{"foo":1}`, patch: `[{"op":"remove","path":"/foo"},{"op":"remove","path":"/foo"}]`, wantErr: errAny},
+		{name: "not an array", doc: `{"foo":1}`, patch: `{"op":"remove","path":"/foo"}`, wantErr: errAny},
+		{name: "malformed patch", doc: `{"foo":1}`, patch: `[{"op":`, wantErr: errAny},
+		{name: "empty body", doc: `{"foo":1}`, patch: ``, wantErr: errAny},
+		{name: "malformed document", doc: `{"foo"`, patch: `[]`, wantErr: errAny},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Apply([]byte(tt.doc), []byte(tt.patch))
+			if tt.wantErr != nil {
+				if err == nil {
+					t.Fatalf("got %s, want an error", got)
+				}
+				if tt.wantErr != errAny && !errors.Is(err, tt.wantErr) {
+					t.Errorf("got error %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !equalJSON(t, got, []byte(tt.want)) {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// errAny marks a test case that must fail with any error.
+var errAny = errors.New("any error")
@@ -21,13 +21,28 @@ DROP TABLE IF EXISTS image_ids CASCADE;
 DROP TABLE IF EXISTS images CASCADE;
 DROP TABLE IF EXISTS image_licenses CASCADE;
 DROP TABLE IF EXISTS movie_aliases_iso CASCADE;
+DROP TABLE IF EXISTS movie_aliases CASCADE;
 DROP TABLE IF EXISTS movie_languages CASCADE;
 DROP TABLE IF EXISTS movie_countries CASCADE;
+DROP TABLE IF EXISTS countries CASCADE;
+DROP TABLE IF EXISTS languages CASCADE;
 DROP TABLE IF EXISTS movie_references CASCADE;
+DROP TABLE IF EXISTS movie_abstracts CASCADE;
 DROP TABLE IF EXISTS movie_abstracts_de CASCADE;
 DROP TABLE IF EXISTS movie_abstracts_en CASCADE;
 DROP TABLE IF EXISTS movie_abstracts_fr CASCADE;
 DROP TABLE IF EXISTS movie_abstracts_es CASCADE;
 
+-- a full import replaces every movie, so the incremental sync starts over
+DO $$
+BEGIN
+    IF to_regclass('sync_runs') IS NOT NULL THEN
+        TRUNCATE sync_runs, sync_movie_versions, sync_conflicts;
+    END IF;
+    IF to_regclass('sync_snapshots') IS NOT NULL THEN
+        TRUNCATE sync_snapshots, sync_people;
+    END IF;
+END $$;
+
 DROP TYPE IF EXISTS kind;
 
@@ -20,6 +20,12 @@ COMMENT ON TABLE trailers is 'Youtube/Vimeo Trailer';
 CREATE TABLE IF NOT EXISTS movie_links (source text, key text, movie_id bigint, language text);
 CREATE TABLE IF NOT EXISTS image_ids (id bigint, object_id bigint, object_type text, image_version int);
 CREATE TABLE IF NOT EXISTS image_licenses (image_id bigint, source text, license_id bigint, author text);
--- CREATE TABLE IF NOT EXISTS movie_references (movie_id bigint, referenced_id bigint, type text);
+CREATE TABLE IF NOT EXISTS movie_countries (movie_id bigint, country_code text);
+CREATE TABLE IF NOT EXISTS movie_languages (movie_id bigint, language_code text);
+CREATE TABLE IF NOT EXISTS movie_abstracts (movie_id bigint, language text, abstract text);
+COMMENT ON TABLE movie_abstracts is 'Translated abstracts, the english abstract is stored in movies.abstract';
+CREATE TABLE IF NOT EXISTS movie_references (movie_id bigint, referenced_id bigint, type text);
+CREATE TABLE IF NOT EXISTS movie_aliases (movie_id bigint, name text, language text, country_code text);
+COMMENT ON TABLE movie_aliases is 'Alternate and localized titles from all_movie_aliases_iso';
 
 COMMIT;
\ No newline at end of file
@@ -9,11 +9,12 @@ CREATE TEMP TABLE IF NOT EXISTS all_episodes    (id bigint primary key, name tex
 CREATE TEMP TABLE IF NOT EXISTS all_movieseries (id bigint primary key, name text, parent_id bigint, date date);
 CREATE TEMP TABLE IF NOT EXISTS movie_details (movie_id bigint primary key, runtime int, budget numeric, revenue numeric, homepage text);
 CREATE TEMP TABLE IF NOT EXISTS votes (movie_id bigint primary key, vote_average numeric, votes_count bigint);
-CREATE TEMP TABLE IF NOT EXISTS movie_abstracts (movie_id bigint, abstract text);
+CREATE TEMP TABLE IF NOT EXISTS movie_abstracts_en (movie_id bigint, abstract text);
 CREATE TEMP TABLE IF NOT EXISTS job_names (job_id bigint, name text, language text);
 CREATE TEMP TABLE IF NOT EXISTS category_names (category_id bigint, name text, language text);
 CREATE TEMP TABLE IF NOT EXISTS all_people (id bigint, name text, birthday date, deathday date, gender int);
 CREATE TEMP TABLE IF NOT EXISTS all_people_aliases (person_id bigint, name text);
+CREATE TEMP TABLE IF NOT EXISTS all_movie_aliases_iso (movie_id bigint, name text, language text, official_translation int);
 
 \copy all_movies            FROM 'sql/data-import/data/all_movies.csv'            WITH (FORMAT CSV, HEADER TRUE, NULL '\N', ESCAPE '\')
 \copy all_series            FROM 'sql/data-import/data/all_series.csv'            WITH (FORMAT CSV, HEADER TRUE, NULL '\N', ESCAPE '\')
@@ -22,12 +23,13 @@ CREATE TEMP TABLE IF NOT EXISTS all_people_aliases (person_id bigint, name text)
 \copy all_movieseries       FROM 'sql/data-import/data/all_movieseries.csv'       WITH (FORMAT CSV, HEADER TRUE, NULL '\N', ESCAPE '\')
 \copy movie_details         FROM 'sql/data-import/data/movie_details.csv'         WITH (FORMAT CSV, HEADER TRUE, NULL '\N', ESCAPE '\')
 \copy votes                 FROM 'sql/data-import/data/all_votes.csv'             WITH (FORMAT CSV, HEADER TRUE, NULL '\N', ESCAPE '\')
-\copy movie_abstracts 		FROM 'sql/data-import/data/movie_abstracts_en.csv'    WITH (FORMAT CSV, HEADER TRUE, NULL '\N', ESCAPE '\')
+\copy movie_abstracts_en 	FROM 'sql/data-import/data/movie_abstracts_en.csv'    WITH (FORMAT CSV, HEADER TRUE, NULL '\N', ESCAPE '\')
 \copy job_names				FROM 'sql/data-import/data/job_names.csv'    		  WITH (FORMAT CSV, HEADER TRUE, NULL '\N', ESCAPE '\')
 \copy category_names		FROM 'sql/data-import/data/category_names.csv'		  WITH (FORMAT CSV, HEADER TRUE, NULL '\N', ESCAPE '\')
 \copy category_names		FROM 'sql/data-import/data/category_names.csv'		  WITH (FORMAT CSV, HEADER TRUE, NULL '\N', ESCAPE '\')
 \copy all_people FROM 'sql/data-import/data/all_people.csv' WITH (FORMAT CSV, HEADER TRUE, NULL '\N', ESCAPE '\')
 \copy all_people_aliases	FROM 'sql/data-import/data/all_people_aliases.csv'    WITH (FORMAT CSV, HEADER TRUE, NULL '\N', ESCAPE '\')
+\copy all_movie_aliases_iso	FROM 'sql/data-import/data/all_movie_aliases_iso.csv' WITH (FORMAT CSV, HEADER TRUE, NULL '\N', ESCAPE '\')
 
 --movies
 WITH import_movies AS (
@@ -44,12 +46,12 @@ WITH import_movies AS (
 INSERT INTO movies
 (SELECT id, name, parent_id, date, series_id, kind, -- from import_movies temp table
 	runtime, budget, revenue, homepage, -- from movie_details temp table
-	vote_average, votes_count -- from votes temp table
-	abstract -- from movie_abstracts temp table
+	vote_average, votes_count, -- from votes temp table
+	abstract -- from movie_abstracts_en temp table
 FROM import_movies m
 	LEFT JOIN movie_details d ON m.id = d.movie_id
 	LEFT JOIN votes v ON m.id = v.movie_id
-	LEFT JOIN movie_abstracts a ON m.id = a.movie_id);
+	LEFT JOIN movie_abstracts_en a ON m.id = a.movie_id);
 
 -- jobs
 INSERT INTO jobs SELECT job_id, name FROM job_names WHERE language = 'en'; -- job_names temp table
@@ -80,6 +82,25 @@ LEFT JOIN (
 \copy movie_links           FROM 'sql/data-import/data/movie_links.csv'           WITH (FORMAT CSV, HEADER TRUE, NULL '\N', ESCAPE '\')
 \copy image_ids             FROM 'sql/data-import/data/image_ids.csv'             WITH (FORMAT CSV, HEADER TRUE, NULL '\N', ESCAPE '\')
 \copy image_licenses        FROM 'sql/data-import/data/image_licenses.csv'        WITH (FORMAT CSV, HEADER TRUE, NULL '\N', ESCAPE '\')
--- \copy movie_references      FROM 'sql/data-import/data/movie_references.csv'      WITH (FORMAT CSV, HEADER TRUE, NULL '\N', ESCAPE '\')
+\copy movie_countries       FROM 'sql/data-import/data/movie_countries.csv'       WITH (FORMAT CSV, HEADER TRUE, NULL '\N', ESCAPE '\')
+\copy movie_languages       FROM 'sql/data-import/data/movie_languages.csv'       WITH (FORMAT CSV, HEADER TRUE, NULL '\N', ESCAPE '\')
+-- translated abstracts
+\copy movie_abstracts (movie_id, abstract) FROM 'sql/data-import/data/movie_abstracts_de.csv' WITH (FORMAT CSV, HEADER TRUE, NULL '\N', ESCAPE '\')
+UPDATE movie_abstracts SET language = 'de' WHERE language IS NULL;
+\copy movie_abstracts (movie_id, abstract) FROM 'sql/data-import/data/movie_abstracts_fr.csv' WITH (FORMAT CSV, HEADER TRUE, NULL '\N', ESCAPE '\')
+UPDATE movie_abstracts SET language = 'fr' WHERE language IS NULL;
+\copy movie_abstracts (movie_id, abstract) FROM 'sql/data-import/data/movie_abstracts_es.csv' WITH (FORMAT CSV, HEADER TRUE, NULL '\N', ESCAPE '\')
+UPDATE movie_abstracts SET language = 'es' WHERE language IS NULL;
+
+-- movie aliases, the language is a locale such as "de" or "de_AT" where the second part is the country
+INSERT INTO movie_aliases (movie_id, name, language, country_code)
+SELECT
+	movie_id,
+	trim(name),
+	lower(split_part(replace(language, '-', '_'), '_', 1)),
+	upper(split_part(replace(language, '-', '_'), '_', 2))
+FROM all_movie_aliases_iso;
+
+\copy movie_references      FROM 'sql/data-import/data/movie_references.csv'      WITH (FORMAT CSV, HEADER TRUE, NULL '\N', ESCAPE '\')
 
 COMMIT;
\ No newline at end of file
@@ -18,11 +18,34 @@ WITH t as (
   )
 DELETE from movie_links where ctid in (select ctid FROM t WHERE row_number > 1);
 
--- DELETE FROM movie_references WHERE type IS NULL;
--- WITH t as (
---   select ctid, row_number() over (partition by movie_id, referenced_id, type), * from movie_references
---   )
--- DELETE from movie_references where ctid in (select ctid FROM t WHERE row_number > 1);
+DELETE FROM movie_countries WHERE country_code IS NULL;
+WITH t as (
+  select ctid, row_number() over (partition by movie_id, country_code), * from movie_countries
+  )
+DELETE from movie_countries where ctid in (select ctid FROM t WHERE row_number > 1);
+
+DELETE FROM movie_languages WHERE language_code IS NULL;
+WITH t as (
+  select ctid, row_number() over (partition by movie_id, language_code), * from movie_languages
+  )
+DELETE from movie_languages where ctid in (select ctid FROM t WHERE row_number > 1);
+
+DELETE FROM movie_abstracts WHERE abstract IS NULL OR trim(abstract) = '';
+WITH t as (
+  select ctid, row_number() over (partition by movie_id, language), * from movie_abstracts
+  )
+DELETE from movie_abstracts where ctid in (select ctid FROM t WHERE row_number > 1);
+
+DELETE FROM movie_aliases WHERE name IS NULL OR name = '';
+WITH t as (
+  select ctid, row_number() over (partition by movie_id, name, language, country_code), * from movie_aliases
+  )
+DELETE from movie_aliases where ctid in (select ctid FROM t WHERE row_number > 1);
+
+WITH t as (
+  select ctid, row_number() over (partition by movie_id, referenced_id, type), * from movie_references
+  )
+DELETE from movie_references where ctid in (select ctid FROM t WHERE row_number > 1);
 
 WITH t as (
   select ctid, row_number() over (partition by movie_id, person_id, job_id, role, "position"), * from casts
@@ -14,4 +14,10 @@ ALTER TABLE trailers
 ALTER TABLE casts
     ADD id bigint GENERATED BY DEFAULT AS IDENTITY;
 
+ALTER TABLE movie_references
+    ADD id bigint GENERATED BY DEFAULT AS IDENTITY;
+
+ALTER TABLE movie_aliases
+    ADD id bigint GENERATED BY DEFAULT AS IDENTITY;
+
 COMMIT;
\ No newline at end of file
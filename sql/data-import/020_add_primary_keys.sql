@@ -14,6 +14,10 @@ ALTER TABLE image_licenses     ADD PRIMARY KEY (image_id);
 ALTER TABLE movie_links        ADD PRIMARY KEY (id); --(movie_id, language, key)
 ALTER TABLE people_links       ADD PRIMARY KEY (id); --(person_id, language, key)
 ALTER TABLE trailers           ADD PRIMARY KEY (id);
--- ALTER TABLE movie_references   ADD PRIMARY KEY (movie_id, referenced_id, type);
+ALTER TABLE movie_countries    ADD PRIMARY KEY (movie_id, country_code);
+ALTER TABLE movie_languages    ADD PRIMARY KEY (movie_id, language_code);
+ALTER TABLE movie_abstracts    ADD PRIMARY KEY (movie_id, language);
+ALTER TABLE movie_references   ADD PRIMARY KEY (id); --(movie_id, referenced_id, type)
+ALTER TABLE movie_aliases      ADD PRIMARY KEY (id); --(movie_id, name, language, country_code)
 
 COMMIT;
\ No newline at end of file
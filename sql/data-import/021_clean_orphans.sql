@@ -12,9 +12,16 @@ DELETE FROM movie_keywords     c WHERE NOT EXISTS (SELECT * FROM movies p     WH
 DELETE FROM movie_keywords     c WHERE NOT EXISTS (SELECT * FROM categories p WHERE p.id = c.category_id);
 DELETE FROM trailers           c WHERE NOT EXISTS (SELECT * FROM movies p     WHERE p.id = c.movie_id);
 DELETE FROM movie_links        c WHERE NOT EXISTS (SELECT * FROM movies p     WHERE p.id = c.movie_id);
--- DELETE FROM movie_references   c WHERE NOT EXISTS (SELECT * FROM movies p     WHERE p.id = c.movie_id);
--- DELETE FROM movie_references   c WHERE NOT EXISTS (SELECT * FROM movies p     WHERE p.id = c.movie_id);
--- DELETE FROM movie_references   c WHERE NOT EXISTS (SELECT * FROM movies p     WHERE p.id = c.referenced_id);
+DELETE FROM movie_countries    c WHERE NOT EXISTS (SELECT * FROM movies p     WHERE p.id = c.movie_id);
+DELETE FROM movie_countries    c WHERE NOT EXISTS (SELECT * FROM countries p  WHERE p.code = c.country_code);
+DELETE FROM movie_languages    c WHERE NOT EXISTS (SELECT * FROM movies p     WHERE p.id = c.movie_id);
+DELETE FROM movie_languages    c WHERE NOT EXISTS (SELECT * FROM languages p  WHERE p.code = c.language_code);
+DELETE FROM movie_abstracts    c WHERE NOT EXISTS (SELECT * FROM movies p     WHERE p.id = c.movie_id);
+DELETE FROM movie_references   c WHERE NOT EXISTS (SELECT * FROM movies p     WHERE p.id = c.movie_id);
+DELETE FROM movie_references   c WHERE NOT EXISTS (SELECT * FROM movies p     WHERE p.id = c.referenced_id);
+DELETE FROM movie_aliases      c WHERE NOT EXISTS (SELECT * FROM movies p     WHERE p.id = c.movie_id);
+UPDATE movie_aliases         c SET language = 'xx' WHERE NOT EXISTS (SELECT * FROM languages p  WHERE p.code = c.language);
+UPDATE movie_aliases         c SET country_code = '' WHERE NOT EXISTS (SELECT * FROM countries p  WHERE p.code = c.country_code);
 
 DELETE FROM image_ids WHERE object_id IS NULL;
 DELETE FROM image_licenses c WHERE NOT EXISTS (SELECT * FROM image_ids p     WHERE p.id = c.image_id);
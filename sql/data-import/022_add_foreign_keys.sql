@@ -16,12 +16,19 @@ ALTER TABLE movie_keywords     ADD FOREIGN KEY (movie_id) REFERENCES movies (id)
                                ADD FOREIGN KEY (category_id) REFERENCES categories (id) ON DELETE cascade;
 ALTER TABLE trailers           ADD FOREIGN KEY (movie_id) REFERENCES movies (id) ON DELETE cascade;
 ALTER TABLE movie_links        ADD FOREIGN KEY (movie_id) REFERENCES movies (id) ON DELETE cascade;
+ALTER TABLE movie_countries    ADD FOREIGN KEY (movie_id) REFERENCES movies (id) ON DELETE cascade,
+                               ADD FOREIGN KEY (country_code) REFERENCES countries (code) ON DELETE cascade;
+ALTER TABLE movie_languages    ADD FOREIGN KEY (movie_id) REFERENCES movies (id) ON DELETE cascade,
+                               ADD FOREIGN KEY (language_code) REFERENCES languages (code) ON DELETE cascade;
 
 -- other references
 ALTER TABLE image_licenses     ADD FOREIGN KEY (image_id) REFERENCES image_ids (id) ON DELETE cascade;
 ALTER TABLE categories         ADD FOREIGN KEY (parent_id) REFERENCES categories (id) ON DELETE cascade,
                                ADD FOREIGN KEY (root_id) REFERENCES categories (id) ON DELETE cascade;
 
--- ALTER TABLE movie_references   ADD FOREIGN KEY (movie_id) REFERENCES movies (id) ON DELETE cascade,
---                                ADD FOREIGN KEY (referenced_id) REFERENCES movies (id) ON DELETE cascade;
+ALTER TABLE movie_abstracts    ADD FOREIGN KEY (movie_id) REFERENCES movies (id) ON DELETE cascade;
+ALTER TABLE movie_references   ADD FOREIGN KEY (movie_id) REFERENCES movies (id) ON DELETE cascade,
+                               ADD FOREIGN KEY (referenced_id) REFERENCES movies (id) ON DELETE cascade;
+ALTER TABLE movie_aliases      ADD FOREIGN KEY (movie_id) REFERENCES movies (id) ON DELETE cascade,
+                               ADD FOREIGN KEY (language) REFERENCES languages (code) ON DELETE cascade;
 COMMIT;
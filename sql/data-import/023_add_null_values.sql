@@ -57,6 +57,20 @@ WHERE
     license_id IS NULL OR
     author IS NULL;
 
+-- references without a type in OMDB are kept as a generic reference
+UPDATE movie_references
+SET
+    type = COALESCE(type, 'Reference')
+WHERE
+    type IS NULL;
+
+-- aliases without a known country apply to every country with the language
+UPDATE movie_aliases
+SET
+    country_code = COALESCE(country_code, '')
+WHERE
+    country_code IS NULL;
+
 UPDATE image_ids
 SET
     image_version = COALESCE(image_version, 0)
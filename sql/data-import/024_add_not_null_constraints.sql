@@ -74,9 +74,29 @@ ALTER TABLE image_licenses
     ALTER COLUMN license_id SET NOT NULL,
     ALTER COLUMN author SET NOT NULL;
 
--- ALTER TABLE movie_references
---     ALTER COLUMN movie_id SET NOT NULL,
---     ALTER COLUMN referenced_id SET NOT NULL,
---     ALTER COLUMN type SET NOT NULL;
+ALTER TABLE movie_countries
+    ALTER COLUMN movie_id SET NOT NULL,
+    ALTER COLUMN country_code SET NOT NULL;
+
+ALTER TABLE movie_languages
+    ALTER COLUMN movie_id SET NOT NULL,
+    ALTER COLUMN language_code SET NOT NULL;
+
+ALTER TABLE movie_abstracts
+    ALTER COLUMN movie_id SET NOT NULL,
+    ALTER COLUMN language SET NOT NULL,
+    ALTER COLUMN abstract SET NOT NULL;
+
+ALTER TABLE movie_references
+    ALTER COLUMN movie_id SET NOT NULL,
+    ALTER COLUMN referenced_id SET NOT NULL,
+    ALTER COLUMN type SET NOT NULL;
+
+ALTER TABLE movie_aliases
+    ALTER COLUMN movie_id SET NOT NULL,
+    ALTER COLUMN name SET NOT NULL,
+    ALTER COLUMN language SET NOT NULL,
+    ALTER COLUMN country_code SET NOT NULL,
+    ALTER COLUMN country_code SET DEFAULT '';
 
 COMMIT;
\ No newline at end of file
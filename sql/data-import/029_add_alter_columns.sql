@@ -29,6 +29,7 @@ UPDATE images
     WHERE version = '0';   
 
 ALTER TABLE image_licenses    
+    ADD version integer NOT NULL DEFAULT 1,
     ADD created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
     ADD modified_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
 
@@ -62,7 +63,26 @@ ALTER TABLE casts
     ADD created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
     ADD modified_at TIMESTAMPTZ NOT NULL DEFAULT NOW();          
 
--- ALTER TABLE movie_references   
---     ADD created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
---     ADD modified_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
+ALTER TABLE movie_countries
+    ADD created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    ADD modified_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
+
+ALTER TABLE movie_languages
+    ADD created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    ADD modified_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
+
+ALTER TABLE movie_abstracts
+    ADD version integer NOT NULL DEFAULT 1,
+    ADD created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    ADD modified_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
+
+ALTER TABLE movie_references
+    ADD version integer NOT NULL DEFAULT 1,
+    ADD created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    ADD modified_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
+
+ALTER TABLE movie_aliases
+    ADD version integer NOT NULL DEFAULT 1,
+    ADD created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    ADD modified_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
 COMMIT;
\ No newline at end of file
@@ -0,0 +1,53 @@
+BEGIN;
+\echo ''
+\echo '032_job_departments'
+
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS department_id bigint REFERENCES jobs (id) ON DELETE SET NULL;
+
+-- The departments are jobs in OMDB, like Writing Department and Actors. The
+-- dataset does not link jobs to their department, so the department is set
+-- from the job name. The patterns are tried in order and jobs not matching any
+-- of them are put in Crew. The departments are looked up by name, so that the
+-- mapping does not depend on the ids of the dataset.
+CREATE TEMP TABLE department_patterns (priority int, department text, pattern text);
+INSERT INTO department_patterns VALUES
+    (1, 'Actors', '^(actor|special guest|cameo|voice|motion actor|stand in|narrator|archive footage|shown person|adr voice cast|voice actor|dubbing actor|host|presenter)$'),
+    (2, 'Sound Department', 'sound|foley|\madr\M|dubbing|boom operator|mix|mischung|music|composer|song|orchestr|score|conductor|vocals|musician|piano|violin|cello|drums|guitar|\mbass|theremin|dialogue editor|recordist|recording|dolby|^ton|musik'),
+    (3, 'Camera Department', 'camera|photograph|cinematograph|steadicam|grip|focus puller|loader|dolly|crane|drone|gopro|video assist|24 frame|digital imaging|data wrangler|data management|kamera'),
+    (4, 'Lighting Department', 'light|gaffer|electric|best boy|generator|balloon'),
+    (5, 'Costume & Make-Up Department', 'costum|make-?up|hair|wig|wardrobe|tailor|seamstress|milliner|textile|shoe design|maske|tattoo|prosthetic|silicone'),
+    (6, 'Visual Effects Department', 'visual effects|vfx|\mcg|effects|composit|matte|simulation|modeling|shading|layout|animat|motion capture|flame|3d artist|digital artist|creature|pyrotechn|sequence|i/o|imaging science|digital producer|character (model|technical|development|design)|cloth setup|facial|sets & props|fix|background designer|puppet|software|photoscience|technical director'),
+    (7, 'Art Department', '\mart\M|art direct|design|\mset (design|decorat|dress|director|costumer)|set dressing|on-set dresser|decorat|prop|construction|paint|scenic|carpenter|sculpt|illustrat|drafter|storyboard|greensman|leadman|swing gang|patinizer|doll maker|instrument maker|settings|scenograph|visual development|graphic (design|s)|\mtitle|machinist|buyer|byer|production artist'),
+    (8, 'Production Department', 'casting|director of (production|marketing)|production director|executive in charge'),
+    (9, 'Directing Department', 'script supervisor|continuity|director|directing|direction|stage manager'),
+    (10, 'Writing Department', 'writ|screenplay|novel|story|characters|adaptation|dialogue|play\M|script|author|idea|treatment|libretto|manga|comic|poem|opera\M|diary|creator|screenstory|scenario|texte|dramaturg|translator|subtitle'),
+    (11, 'Editing Department', 'edit|colorist|color timer|color assist|conform|digital intermediate|telecine|online|dailies|projection|mastering|schnitt|post-production|post production|post producer'),
+    (12, 'Production Department', 'produc|manager|coordinator|assistant|account|finance|legal|location|publicist|public relations|press|insurance|payroll|executive|secretary|runner|intern|administrat|herstellung|clearances|unit|transport|driver|trainee|showrunner|consult|research');
+
+CREATE TEMP TABLE departments AS
+    SELECT DISTINCT ON (name) id, name FROM jobs
+    WHERE name IN (SELECT department FROM department_patterns UNION SELECT 'Crew')
+    ORDER BY name, id;
+
+UPDATE jobs j SET department_id = d.id
+FROM departments d
+WHERE d.id = j.id;
+
+UPDATE jobs j SET department_id = m.department_id
+FROM (
+    SELECT DISTINCT ON (j.id) j.id, d.id AS department_id
+    FROM jobs j
+    JOIN department_patterns p ON j.name ~* p.pattern
+    JOIN departments d ON d.name = p.department
+    WHERE j.department_id IS NULL
+    ORDER BY j.id, p.priority
+) m
+WHERE m.id = j.id;
+
+UPDATE jobs SET department_id = (SELECT id FROM departments WHERE name = 'Crew')
+WHERE department_id IS NULL;
+
+DROP TABLE department_patterns;
+DROP TABLE departments;
+
+COMMIT;
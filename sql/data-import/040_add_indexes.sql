@@ -11,8 +11,54 @@ CREATE INDEX IF NOT EXISTS movie_keywords_movie_id_idx ON movie_keywords (movie_
 CREATE INDEX IF NOT EXISTS movie_keywords_category_id_idx ON movie_keywords (category_id);
 CREATE INDEX IF NOT EXISTS movie_categories_movie_id_idx ON movie_categories (movie_id);
 CREATE INDEX IF NOT EXISTS movie_categories_category_id_idx ON movie_categories (category_id);
+CREATE INDEX IF NOT EXISTS categories_parent_id_idx ON categories (parent_id);
 CREATE INDEX IF NOT EXISTS trailers_movie_id_idx ON trailers (movie_id);
 CREATE INDEX IF NOT EXISTS images_object_id_idx ON images (object_id);
 CREATE INDEX IF NOT EXISTS images_object_type_idx ON images (object_type);
+CREATE INDEX IF NOT EXISTS movie_countries_country_code_idx ON movie_countries (country_code);
+CREATE INDEX IF NOT EXISTS movie_languages_language_code_idx ON movie_languages (language_code);
+CREATE UNIQUE INDEX IF NOT EXISTS movie_references_movie_id_referenced_id_type_idx ON movie_references (movie_id, referenced_id, type);
+CREATE INDEX IF NOT EXISTS movie_references_referenced_id_idx ON movie_references (referenced_id);
+CREATE INDEX IF NOT EXISTS movie_aliases_movie_id_idx ON movie_aliases (movie_id);
+CREATE INDEX IF NOT EXISTS movie_aliases_name_idx ON movie_aliases USING GIN (to_tsvector('simple', name));
 
-COMMIT;
\ No newline at end of file
+-- The search indexes of the migration 0012_search_indexes. The function is
+-- not dropped with the tables, but is created here as well for a first import,
+-- which runs before the migrations.
+CREATE OR REPLACE FUNCTION people_search_document(name text, aliases text[]) RETURNS tsvector
+LANGUAGE sql IMMUTABLE PARALLEL SAFE AS $$
+    SELECT setweight(to_tsvector('simple', coalesce(name, '')), 'A')
+        || setweight(to_tsvector('simple', coalesce(array_to_string(aliases, ' '), '')), 'B')
+$$;
+
+CREATE INDEX IF NOT EXISTS people_search_idx ON people USING GIN (people_search_document(name, aliases));
+CREATE INDEX IF NOT EXISTS categories_name_idx ON categories USING GIN (to_tsvector('simple', name));
+
+-- The trigram indexes of the migration 0013_fuzzy_search, with the extensions
+-- and functions they are built on.
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+CREATE EXTENSION IF NOT EXISTS unaccent;
+
+CREATE OR REPLACE FUNCTION search_key(value text) RETURNS text
+LANGUAGE sql IMMUTABLE PARALLEL SAFE STRICT AS $$
+    SELECT lower(public.unaccent('public.unaccent', value))
+$$;
+
+CREATE OR REPLACE FUNCTION people_search_key(name text, aliases text[]) RETURNS text
+LANGUAGE sql IMMUTABLE PARALLEL SAFE AS $$
+    SELECT search_key(concat_ws(' ', name, array_to_string(aliases, ' ')))
+$$;
+
+CREATE INDEX IF NOT EXISTS movies_name_trgm_idx ON movies USING GIN (search_key(name) gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS movie_aliases_name_trgm_idx ON movie_aliases USING GIN (search_key(name) gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS people_name_trgm_idx ON people USING GIN (search_key(name) gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS people_search_trgm_idx ON people USING GIN (people_search_key(name, aliases) gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS categories_name_trgm_idx ON categories USING GIN (search_key(name) gin_trgm_ops);
+
+-- The abstract search indexes of the migration 0014_abstract_search.
+CREATE INDEX IF NOT EXISTS movies_abstract_search_idx ON movies USING GIN (to_tsvector('english', abstract));
+CREATE INDEX IF NOT EXISTS movie_abstracts_de_search_idx ON movie_abstracts USING GIN (to_tsvector('german', abstract)) WHERE language = 'de';
+CREATE INDEX IF NOT EXISTS movie_abstracts_fr_search_idx ON movie_abstracts USING GIN (to_tsvector('french', abstract)) WHERE language = 'fr';
+CREATE INDEX IF NOT EXISTS movie_abstracts_es_search_idx ON movie_abstracts USING GIN (to_tsvector('spanish', abstract)) WHERE language = 'es';
+
+COMMIT;
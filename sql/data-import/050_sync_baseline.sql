@@ -0,0 +1,28 @@
+BEGIN;
+\echo ''
+\echo '050_sync_baseline'
+
+-- Every imported movie, person and row is the baseline of the incremental
+-- sync, which only touches rows that come from OMDB. The tables are created by
+-- the migrations, which seed them instead when they run after the import.
+DO $$
+BEGIN
+    IF to_regclass('sync_snapshots') IS NOT NULL THEN
+        INSERT INTO sync_movie_versions (movie_id, version, synced_at)
+        SELECT id, version, NOW() FROM movies;
+
+        INSERT INTO sync_people (person_id)
+        SELECT id FROM people;
+
+        INSERT INTO sync_snapshots (movie_id, kind, row_key)
+        SELECT movie_id, 'cast', concat_ws('|', person_id, job_id, position, role) FROM casts
+        UNION
+        SELECT movie_id, 'category', category_id::text FROM movie_categories
+        UNION
+        SELECT movie_id, 'keyword', category_id::text FROM movie_keywords
+        UNION
+        SELECT movie_id, 'link', concat_ws('|', source, key, language) FROM movie_links;
+    END IF;
+END $$;
+
+COMMIT;
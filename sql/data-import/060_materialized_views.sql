@@ -0,0 +1,39 @@
+BEGIN;
+\echo ''
+\echo '060_materialized_views'
+
+-- The materialized views of the migrations are dropped with the tables they
+-- are built on, so they are created again from the imported data. cmd/sync
+-- keeps them fresh after the import.
+
+-- abstract_terms, see the migration 0014_abstract_search.
+CREATE MATERIALIZED VIEW IF NOT EXISTS abstract_terms AS
+    SELECT word AS term, ndoc FROM ts_stat($$SELECT to_tsvector('english', abstract) FROM movies WHERE abstract <> ''$$)
+    UNION ALL
+    SELECT '', count(*) FROM movies WHERE abstract <> '';
+
+CREATE UNIQUE INDEX IF NOT EXISTS abstract_terms_term_idx ON abstract_terms (term);
+
+-- movie_facet_counts, see the migration 0015_movie_facets.
+CREATE MATERIALIZED VIEW IF NOT EXISTS movie_facet_counts AS
+    SELECT 'kind' AS facet, kind::text AS value, '' AS name, count(*) AS count
+    FROM movies
+    GROUP BY kind
+    UNION ALL
+    SELECT 'decade', (floor(extract(year FROM date) / 10) * 10)::int::text, '', count(*)
+    FROM movies
+    GROUP BY 2
+    UNION ALL
+    SELECT 'category', categories.id::text, categories.name, count(*)
+    FROM movie_categories
+    JOIN categories ON categories.id = movie_categories.category_id
+    GROUP BY categories.id
+    UNION ALL
+    SELECT 'language', movie_languages.language_code, coalesce(languages.name, ''), count(*)
+    FROM movie_languages
+    LEFT JOIN languages ON languages.code = movie_languages.language_code
+    GROUP BY movie_languages.language_code, languages.name;
+
+CREATE UNIQUE INDEX IF NOT EXISTS movie_facet_counts_facet_value_idx ON movie_facet_counts (facet, value);
+
+COMMIT;
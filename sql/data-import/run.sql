@@ -3,6 +3,7 @@
 \i :base_path/000_drop-tables.sql
 \i :base_path/001_kind_enum.sql
 \i :base_path/002_schema_omdb.sql
+\i :base_path/003_iso_reference_tables.sql
 \i :base_path/010_import_data.sql
 \i :base_path/011_remove_duplicates.sql
 VACUUM;
@@ -18,5 +19,8 @@ VACUUM;
 \i :base_path/030_add_identity_for_id.sql
 ANALYZE;
 \i :base_path/031_purge_dirty_categories.sql
+\i :base_path/032_job_departments.sql
 
 \i :base_path/040_add_indexes.sql
+\i :base_path/050_sync_baseline.sql
+\i :base_path/060_materialized_views.sql
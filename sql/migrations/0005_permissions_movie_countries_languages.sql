@@ -0,0 +1,11 @@
+-- +goose Up
+INSERT INTO permissions (code)
+VALUES 
+    ('movie-countries:read'),
+    ('movie-countries:write'),
+    ('movie-languages:read'),
+    ('movie-languages:write')
+ON CONFLICT (code) DO NOTHING;
+
+-- +goose Down
+DELETE FROM permissions WHERE code IN ('movie-countries:read', 'movie-countries:write', 'movie-languages:read', 'movie-languages:write');
@@ -0,0 +1,9 @@
+-- +goose Up
+INSERT INTO permissions (code)
+VALUES 
+    ('movie-references:read'),
+    ('movie-references:write')
+ON CONFLICT (code) DO NOTHING;
+
+-- +goose Down
+DELETE FROM permissions WHERE code IN ('movie-references:read', 'movie-references:write');
@@ -0,0 +1,9 @@
+-- +goose Up
+INSERT INTO permissions (code)
+VALUES 
+    ('movie-abstracts:read'),
+    ('movie-abstracts:write')
+ON CONFLICT (code) DO NOTHING;
+
+-- +goose Down
+DELETE FROM permissions WHERE code IN ('movie-abstracts:read', 'movie-abstracts:write');
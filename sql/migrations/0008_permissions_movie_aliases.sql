@@ -0,0 +1,9 @@
+-- +goose Up
+INSERT INTO permissions (code)
+VALUES 
+    ('movie-aliases:read'),
+    ('movie-aliases:write')
+ON CONFLICT (code) DO NOTHING;
+
+-- +goose Down
+DELETE FROM permissions WHERE code IN ('movie-aliases:read', 'movie-aliases:write');
@@ -0,0 +1,31 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS sync_runs (
+    id bigint PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+    started_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    finished_at timestamp(0) with time zone,
+    since timestamp(0) with time zone NOT NULL,
+    watermark timestamp(0) with time zone NOT NULL,
+    status text NOT NULL DEFAULT 'running',
+    movies_synced integer NOT NULL DEFAULT 0,
+    conflicts integer NOT NULL DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS sync_movie_versions (
+    movie_id bigint PRIMARY KEY,
+    version integer NOT NULL,
+    synced_at timestamp with time zone NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS sync_conflicts (
+    id bigint PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+    sync_run_id bigint NOT NULL REFERENCES sync_runs ON DELETE CASCADE,
+    movie_id bigint NOT NULL,
+    last_update timestamp(0) with time zone NOT NULL,
+    reason text NOT NULL,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW()
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS sync_conflicts;
+DROP TABLE IF EXISTS sync_movie_versions;
+DROP TABLE IF EXISTS sync_runs;
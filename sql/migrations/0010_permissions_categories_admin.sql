@@ -0,0 +1,8 @@
+-- +goose Up
+INSERT INTO permissions (code)
+VALUES 
+    ('categories:admin')
+ON CONFLICT (code) DO NOTHING;
+
+-- +goose Down
+DELETE FROM permissions WHERE code IN ('categories:admin');
@@ -0,0 +1,8 @@
+-- +goose Up
+-- The departments of the jobs are set by the data import, see
+-- sql/data-import/032_job_departments.sql. The column is added here for
+-- databases imported before the departments were added to the import.
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS department_id bigint REFERENCES jobs (id) ON DELETE SET NULL;
+
+-- +goose Down
+ALTER TABLE jobs DROP COLUMN IF EXISTS department_id;
@@ -0,0 +1,19 @@
+-- +goose Up
+-- array_to_string is not immutable, so the search document of a person is
+-- wrapped in an immutable function to be usable in an index. Matches on the
+-- name are weighted above matches on the aliases.
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION people_search_document(name text, aliases text[]) RETURNS tsvector
+LANGUAGE sql IMMUTABLE PARALLEL SAFE AS $$
+    SELECT setweight(to_tsvector('simple', coalesce(name, '')), 'A')
+        || setweight(to_tsvector('simple', coalesce(array_to_string(aliases, ' '), '')), 'B')
+$$;
+-- +goose StatementEnd
+
+CREATE INDEX IF NOT EXISTS people_search_idx ON people USING GIN (people_search_document(name, aliases));
+CREATE INDEX IF NOT EXISTS categories_name_idx ON categories USING GIN (to_tsvector('simple', name));
+
+-- +goose Down
+DROP INDEX IF EXISTS categories_name_idx;
+DROP INDEX IF EXISTS people_search_idx;
+DROP FUNCTION IF EXISTS people_search_document(text, text[]);
@@ -0,0 +1,43 @@
+-- +goose Up
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+CREATE EXTENSION IF NOT EXISTS unaccent;
+
+-- search_key is the lower case name without accents, which the trigram
+-- indexes are built on. unaccent is not immutable, so it is wrapped in an
+-- immutable function to be usable in an index.
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION search_key(value text) RETURNS text
+LANGUAGE sql IMMUTABLE PARALLEL SAFE STRICT AS $$
+    SELECT lower(public.unaccent('public.unaccent', value))
+$$;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION people_search_key(name text, aliases text[]) RETURNS text
+LANGUAGE sql IMMUTABLE PARALLEL SAFE AS $$
+    SELECT search_key(concat_ws(' ', name, array_to_string(aliases, ' ')))
+$$;
+-- +goose StatementEnd
+
+-- simple_unaccent is used to mark the matched words in search snippets when
+-- the accents of the search terms and the name differ.
+CREATE TEXT SEARCH CONFIGURATION simple_unaccent (COPY = simple);
+ALTER TEXT SEARCH CONFIGURATION simple_unaccent ALTER MAPPING FOR hword, hword_part, word WITH unaccent, simple;
+
+CREATE INDEX IF NOT EXISTS movies_name_trgm_idx ON movies USING GIN (search_key(name) gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS movie_aliases_name_trgm_idx ON movie_aliases USING GIN (search_key(name) gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS people_name_trgm_idx ON people USING GIN (search_key(name) gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS people_search_trgm_idx ON people USING GIN (people_search_key(name, aliases) gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS categories_name_trgm_idx ON categories USING GIN (search_key(name) gin_trgm_ops);
+
+-- +goose Down
+DROP INDEX IF EXISTS categories_name_trgm_idx;
+DROP INDEX IF EXISTS people_search_trgm_idx;
+DROP INDEX IF EXISTS people_name_trgm_idx;
+DROP INDEX IF EXISTS movie_aliases_name_trgm_idx;
+DROP INDEX IF EXISTS movies_name_trgm_idx;
+DROP TEXT SEARCH CONFIGURATION IF EXISTS simple_unaccent;
+DROP FUNCTION IF EXISTS people_search_key(text, text[]);
+DROP FUNCTION IF EXISTS search_key(text);
+DROP EXTENSION IF EXISTS unaccent;
+DROP EXTENSION IF EXISTS pg_trgm;
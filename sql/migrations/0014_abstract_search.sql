@@ -0,0 +1,27 @@
+-- +goose Up
+-- The english abstracts are in movies.abstract and the other languages in
+-- movie_abstracts. Each language is indexed with its text search config to
+-- get stemming and stop words. Languages without an index are searched with
+-- the simple config.
+CREATE INDEX IF NOT EXISTS movies_abstract_search_idx ON movies USING GIN (to_tsvector('english', abstract));
+CREATE INDEX IF NOT EXISTS movie_abstracts_de_search_idx ON movie_abstracts USING GIN (to_tsvector('german', abstract)) WHERE language = 'de';
+CREATE INDEX IF NOT EXISTS movie_abstracts_fr_search_idx ON movie_abstracts USING GIN (to_tsvector('french', abstract)) WHERE language = 'fr';
+CREATE INDEX IF NOT EXISTS movie_abstracts_es_search_idx ON movie_abstracts USING GIN (to_tsvector('spanish', abstract)) WHERE language = 'es';
+
+-- abstract_terms holds the number of english abstracts each term is found in,
+-- which is the document frequency of the TF-IDF vectors used to find similar
+-- movies. The row with the empty term holds the number of abstracts. It is
+-- refreshed by cmd/sync.
+CREATE MATERIALIZED VIEW IF NOT EXISTS abstract_terms AS
+    SELECT word AS term, ndoc FROM ts_stat($$SELECT to_tsvector('english', abstract) FROM movies WHERE abstract <> ''$$)
+    UNION ALL
+    SELECT '', count(*) FROM movies WHERE abstract <> '';
+
+CREATE UNIQUE INDEX IF NOT EXISTS abstract_terms_term_idx ON abstract_terms (term);
+
+-- +goose Down
+DROP MATERIALIZED VIEW IF EXISTS abstract_terms;
+DROP INDEX IF EXISTS movie_abstracts_es_search_idx;
+DROP INDEX IF EXISTS movie_abstracts_fr_search_idx;
+DROP INDEX IF EXISTS movie_abstracts_de_search_idx;
+DROP INDEX IF EXISTS movies_abstract_search_idx;
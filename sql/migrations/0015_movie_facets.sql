@@ -0,0 +1,27 @@
+-- +goose Up
+-- movie_facet_counts holds the facet counts of all movies, which is the
+-- common case of the movie list without filters. Filtered lists count their
+-- facets when queried. It is refreshed by cmd/sync.
+CREATE MATERIALIZED VIEW IF NOT EXISTS movie_facet_counts AS
+    SELECT 'kind' AS facet, kind::text AS value, '' AS name, count(*) AS count
+    FROM movies
+    GROUP BY kind
+    UNION ALL
+    SELECT 'decade', (floor(extract(year FROM date) / 10) * 10)::int::text, '', count(*)
+    FROM movies
+    GROUP BY 2
+    UNION ALL
+    SELECT 'category', categories.id::text, categories.name, count(*)
+    FROM movie_categories
+    JOIN categories ON categories.id = movie_categories.category_id
+    GROUP BY categories.id
+    UNION ALL
+    SELECT 'language', movie_languages.language_code, coalesce(languages.name, ''), count(*)
+    FROM movie_languages
+    LEFT JOIN languages ON languages.code = movie_languages.language_code
+    GROUP BY movie_languages.language_code, languages.name;
+
+CREATE UNIQUE INDEX IF NOT EXISTS movie_facet_counts_facet_value_idx ON movie_facet_counts (facet, value);
+
+-- +goose Down
+DROP MATERIALIZED VIEW IF EXISTS movie_facet_counts;
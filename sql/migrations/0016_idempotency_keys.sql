@@ -0,0 +1,18 @@
+-- +goose Up
+-- idempotency_keys holds the Idempotency-Key of POST requests with the
+-- fingerprint of the request and the stored response, so that a retry gets
+-- the same response. status is null while the request is in progress.
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    user_id bigint NOT NULL REFERENCES users ON DELETE CASCADE,
+    key text NOT NULL,
+    fingerprint bytea NOT NULL,
+    status integer,
+    header jsonb,
+    body bytea,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    expires_at timestamp(0) with time zone NOT NULL,
+    PRIMARY KEY (user_id, key)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS idempotency_keys;
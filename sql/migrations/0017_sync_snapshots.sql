@@ -0,0 +1,60 @@
+-- +goose Up
+-- sync_snapshots holds the casts, categories, keywords and links of each
+-- movie as they were last imported or synced, keyed by their content. The
+-- sync diffs the dataset against the snapshot, so that rows added or deleted
+-- through the api are left alone.
+CREATE TABLE IF NOT EXISTS sync_snapshots (
+    movie_id bigint NOT NULL,
+    kind text NOT NULL,
+    row_key text NOT NULL,
+    PRIMARY KEY (movie_id, kind, row_key)
+);
+
+-- sync_people holds the people that come from OMDB. The sync only links casts
+-- to these, never to a person created through the api with the same id.
+CREATE TABLE IF NOT EXISTS sync_people (
+    person_id bigint PRIMARY KEY
+);
+
+-- Movies and people created by the full import share the created_at of the
+-- import transaction, while rows created by the api or the sync are newer.
+-- Databases imported before this migration are seeded from their current
+-- rows, so api deletions made before it can not be told apart. Later imports
+-- seed the tables in sql/data-import/050_sync_baseline.sql.
+INSERT INTO sync_movie_versions (movie_id, version, synced_at)
+SELECT id, 1, created_at
+FROM movies
+WHERE created_at <= (SELECT min(created_at) FROM movies)
+ON CONFLICT (movie_id) DO NOTHING;
+
+INSERT INTO sync_people (person_id)
+SELECT p.id
+FROM people p
+WHERE p.created_at <= (SELECT min(created_at) FROM people)
+    OR EXISTS (
+        SELECT 1 FROM sync_runs r
+        WHERE p.created_at BETWEEN r.started_at AND COALESCE(r.finished_at, NOW())
+    )
+ON CONFLICT (person_id) DO NOTHING;
+
+INSERT INTO sync_snapshots (movie_id, kind, row_key)
+SELECT c.movie_id, 'cast', concat_ws('|', c.person_id, c.job_id, c.position, c.role)
+FROM casts c
+JOIN sync_movie_versions s ON s.movie_id = c.movie_id AND c.created_at <= s.synced_at
+UNION
+SELECT c.movie_id, 'category', c.category_id::text
+FROM movie_categories c
+JOIN sync_movie_versions s ON s.movie_id = c.movie_id AND c.created_at <= s.synced_at
+UNION
+SELECT c.movie_id, 'keyword', c.category_id::text
+FROM movie_keywords c
+JOIN sync_movie_versions s ON s.movie_id = c.movie_id AND c.created_at <= s.synced_at
+UNION
+SELECT c.movie_id, 'link', concat_ws('|', c.source, c.key, c.language)
+FROM movie_links c
+JOIN sync_movie_versions s ON s.movie_id = c.movie_id AND c.created_at <= s.synced_at
+ON CONFLICT DO NOTHING;
+
+-- +goose Down
+DROP TABLE IF EXISTS sync_people;
+DROP TABLE IF EXISTS sync_snapshots;
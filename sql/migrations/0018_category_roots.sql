@@ -0,0 +1,15 @@
+-- +goose Up
+-- A root category has its own id as root_id, like the imported categories.
+-- Categories created through the api without a root_id are given the root of
+-- the tree they are in.
+WITH RECURSIVE tree AS (
+    SELECT id, id AS root_id FROM categories WHERE parent_id IS NULL
+    UNION
+    SELECT c.id, t.root_id FROM categories c JOIN tree t ON c.parent_id = t.id
+)
+UPDATE categories c SET root_id = t.root_id
+FROM tree t
+WHERE c.id = t.id AND c.root_id IS NULL;
+
+-- +goose Down
+-- The root ids are kept, they are valid for the old code as well.